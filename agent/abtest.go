@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// abJudgePrompt 模型评判 A/B 测试时使用的提示词
+const abJudgePrompt = `你是一个公正的评审员。下面是同一任务的两种提示词以及模型对它们的回答，请判断哪个回答的质量更高（准确性、完整性、清晰度）。
+只回复 A、B 或 TIE，不要输出其他内容。
+
+提示词 A：%s
+
+回答 A：%s
+
+提示词 B：%s
+
+回答 B：%s`
+
+// ABTestResult 一轮 A/B 测试的结果
+type ABTestResult struct {
+	WinnerQuery  string           `json:"winner_query"`  // 胜出的查询，平局时为空
+	WinnerResult *QueryResult     `json:"winner_result"` // 胜出查询的结果，平局时为空
+	JudgeScore   int              `json:"judge_score"`   // 评判结果: -1 表示 A 更好，1 表示 B 更好，0 表示平局
+	Latencies    [2]time.Duration `json:"latencies"`     // 查询 A 和查询 B 的延迟
+}
+
+// ABTest 依次发送 queryA 和 queryB，由 judge 评判两个回复的优劣
+// 参数:
+//   - ctx: 上下文
+//   - engine: Engine 实例
+//   - queryA: 查询 A
+//   - queryB: 查询 B
+//   - judge: 评判函数，A 更好时返回负数，B 更好时返回正数，平局返回 0；为空时使用 ModelJudge(ctx, engine)
+// 返回:
+//   - *ABTestResult: 测试结果
+//   - error: 任一查询失败时返回错误
+func ABTest(ctx context.Context, engine *Engine, queryA, queryB string, judge func(a, b *QueryResult) int) (*ABTestResult, error) {
+	if judge == nil {
+		judge = ModelJudge(ctx, engine)
+	}
+
+	var (
+		results [2]*QueryResult
+		result  ABTestResult
+	)
+	for i, query := range [2]string{queryA, queryB} {
+		start := time.Now()
+		r, err := engine.Query(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("查询 %c 失败: %w", 'A'+i, err)
+		}
+		result.Latencies[i] = time.Since(start)
+		results[i] = r
+	}
+
+	switch score := judge(results[0], results[1]); {
+	case score < 0:
+		result.JudgeScore = -1
+		result.WinnerQuery, result.WinnerResult = queryA, results[0]
+	case score > 0:
+		result.JudgeScore = 1
+		result.WinnerQuery, result.WinnerResult = queryB, results[1]
+	}
+	return &result, nil
+}
+
+// ModelJudge 返回使用 Engine 当前模型评判两个回复的函数，可作为 ABTest 的 judge
+// 每次评判随机交换两个回复在提示词中的位置以减少位置偏差；评判调用失败或回复无法识别时视为平局
+// 参数:
+//   - ctx: 评判请求使用的上下文
+//   - engine: Engine 实例
+// 返回:
+//   - func(a, b *QueryResult) int: 评判函数
+func ModelJudge(ctx context.Context, engine *Engine) func(a, b *QueryResult) int {
+	return func(a, b *QueryResult) int {
+		swapped := rand.Intn(2) == 1
+		first, second := a, b
+		if swapped {
+			first, second = b, a
+		}
+
+		// 评判只使用当前模型，不进行路由
+		result, err := engine.QueryRequest(ctx, &QueryRequest{
+			Query:         fmt.Sprintf(abJudgePrompt, first.Query, first.Reply, second.Query, second.Reply),
+			OverrideModel: engine.ModelId,
+		})
+		if err != nil {
+			engine.loggerFrom(ctx).Warn("A/B 测试评判失败，视为平局", "model", engine.ModelId, "error", err)
+			return 0
+		}
+
+		score := 0
+		switch verdict := strings.ToUpper(strings.TrimSpace(result.Reply)); {
+		case strings.HasPrefix(verdict, "TIE"):
+		case strings.HasPrefix(verdict, "A"):
+			score = -1
+		case strings.HasPrefix(verdict, "B"):
+			score = 1
+		default:
+			engine.loggerFrom(ctx).Warn("无法识别 A/B 测试评判结果，视为平局", "reply", result.Reply)
+		}
+		if swapped {
+			score = -score
+		}
+		return score
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultBatchConcurrency QueryBatch 默认的最大并发查询数
+const DefaultBatchConcurrency = 4
+
+// ErrTokenBudgetExceeded 批量查询预估的 token 用量超过当前提供商剩余预算时返回的错误
+var ErrTokenBudgetExceeded = errors.New("预估 token 用量超过剩余预算")
+
+// BatchError QueryBatch 中有查询失败时返回的错误
+type BatchError struct {
+	Errors []error // 与查询一一对应，成功的查询为 nil
+}
+
+// Error 实现 error 接口
+func (e *BatchError) Error() string {
+	failed := e.Unwrap()
+	if len(failed) == 0 {
+		return "批量查询失败"
+	}
+	return fmt.Sprintf("批量查询中 %d/%d 个查询失败，第一个错误: %v", len(failed), len(e.Errors), failed[0])
+}
+
+// Unwrap 返回所有失败查询的错误，支持 errors.Is/errors.As
+func (e *BatchError) Unwrap() []error {
+	var failed []error
+	for _, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// SetBatchConcurrency 设置 QueryBatch 的最大并发查询数
+// 参数:
+//   - n: 最大并发数，小于等于 0 时使用 DefaultBatchConcurrency
+func (engine *Engine) SetBatchConcurrency(n int) {
+	engine.batchConcurrency = n
+}
+
+// QueryBatch 并发发送多个查询，结果顺序与查询顺序一致
+// 单个查询失败不影响其他查询；Engine 关闭后尚未开始的查询不再发送
+// 当前提供商配置了每月 token 预算时，开始前按查询内容预估 token 用量，超过剩余预算则不发送任何查询
+// 参数:
+//   - ctx: 上下文
+//   - queries: 查询内容列表
+// 返回:
+//   - []*QueryResult: 与 queries 一一对应的结果，失败的查询为 nil
+//   - error: 有查询失败时返回 *BatchError（通过 errors.As 获取每个查询的错误），预算不足时返回 ErrTokenBudgetExceeded
+func (engine *Engine) QueryBatch(ctx context.Context, queries []string) ([]*QueryResult, error) {
+	if err := engine.checkBatchBudget(queries); err != nil {
+		return nil, err
+	}
+
+	concurrency := engine.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]*QueryResult, len(queries))
+	errs := make([]error, len(queries))
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for i, query := range queries {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				errs[i] = context.Cause(ctx)
+				return nil
+			}
+			// QueryHandler 处理过程中会切换模型，每个查询使用独立的副本
+			results[i], errs[i] = engine.Clone().Query(ctx, query)
+			// Engine 已关闭时后续查询都会失败，直接取消
+			if errors.Is(errs[i], ErrEngineShutdown) {
+				cancel(errs[i])
+			}
+			// 错误记录在 errs 中，不返回给 errgroup，避免影响其他查询
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, &BatchError{Errors: errs}
+		}
+	}
+	return results, nil
+}
+
+// checkBatchBudget 检查当前提供商的剩余预算是否足够批量查询，未配置预算时不检查
+func (engine *Engine) checkBatchBudget(queries []string) error {
+	remaining, err := engine.GetTokenBudgetRemaining(engine.GetCurrentProviderName())
+	if errors.Is(err, ErrNoTokenBudget) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取剩余 token 预算失败: %w", err)
+	}
+
+	estimated := 0
+	for _, q := range queries {
+		estimated += engine.estimateTokens(q)
+	}
+	if estimated > remaining {
+		return fmt.Errorf("%w: 预估 %d，剩余 %d（提供商 %s）", ErrTokenBudgetExceeded, estimated, remaining, engine.GetCurrentProviderName())
+	}
+	return nil
+}
+
+// estimateTokens 按当前提供商的 token_encoding 计算文本的 token 数，未配置编码或加载失败时近似估算
+func (engine *Engine) estimateTokens(text string) int {
+	encoding := ""
+	if engine.config != nil {
+		if provider, err := engine.config.GetProviderByName(engine.providerName); err == nil {
+			encoding = provider.TokenEncoding
+		}
+	}
+	n, err := conf.CountTokens(text, encoding)
+	if err != nil {
+		engine.loggerFrom(engine.baseContext()).Warn("计算 token 数失败，使用近似估算", "encoding", encoding, "error", err)
+		return conf.ApproximateTokens(text)
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ModelBenchmark 单个提供商/模型组合的基准测试结果
+type ModelBenchmark struct {
+	Provider        string        `json:"provider"`          // 提供商名称
+	Model           string        `json:"model"`             // 模型ID
+	Iterations      int           `json:"iterations"`        // 请求次数
+	Errors          int           `json:"errors"`            // 失败次数
+	ErrorRate       float64       `json:"error_rate"`        // 失败率（0~1）
+	P50             time.Duration `json:"p50"`               // 成功请求的 p50 延迟
+	P95             time.Duration `json:"p95"`               // 成功请求的 p95 延迟
+	P99             time.Duration `json:"p99"`               // 成功请求的 p99 延迟
+	TokensPerSecond float64       `json:"tokens_per_second"` // 生成速度（completion tokens / 延迟）
+}
+
+// BenchmarkReport 基准测试报告
+type BenchmarkReport struct {
+	Query      string           `json:"query"`      // 测试使用的查询
+	Iterations int              `json:"iterations"` // 每个组合的请求次数
+	Results    []ModelBenchmark `json:"results"`    // 按 p50 延迟升序排列的结果，全部失败的组合排在最后
+}
+
+// BenchmarkProviders 对所有提供商/模型组合运行基准测试
+// 每个组合依次发送 iterations 次相同的查询，请求不重试、不做模型轮换，以测量单次调用的真实表现
+// 参数:
+//   - ctx: 上下文，取消后停止测试
+//   - engine: Engine 实例，测试在其副本上进行，不修改原 Engine
+//   - query: 查询内容
+//   - iterations: 每个组合的请求次数
+// 返回:
+//   - *BenchmarkReport: 基准测试报告
+//   - error: 参数无效、Engine 已关闭或测试被取消时返回错误
+func BenchmarkProviders(ctx context.Context, engine *Engine, query string, iterations int) (*BenchmarkReport, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	if iterations <= 0 {
+		return nil, fmt.Errorf("迭代次数必须大于 0，当前为 %d", iterations)
+	}
+
+	if engine.lifecycle != nil {
+		if err := engine.lifecycle.acquire(); err != nil {
+			return nil, err
+		}
+		defer engine.lifecycle.release()
+	}
+	ctx, cancel := mergeContext(engine.baseCtx, ctx)
+	defer cancel()
+	ctx = engine.withLogger(ctx)
+	logger := LoggerFromContext(ctx).With("handler", "BenchmarkProviders")
+
+	report := &BenchmarkReport{Query: query, Iterations: iterations}
+	for _, provider := range engine.config.Provider {
+		for _, modelId := range provider.ModelIDs() {
+			clone := engine.Clone()
+			if err := clone.SwitchProvider(provider.Name, modelId); err != nil {
+				return nil, err
+			}
+
+			result, err := clone.benchmarkModel(ctx, query, iterations)
+			if err != nil {
+				return nil, err
+			}
+			logger.Info("基准测试完成", "provider", provider.Name, "model", modelId, "p50", result.P50, "error_rate", result.ErrorRate)
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	// 全部失败的组合没有延迟数据，排在最后
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		aFailed, bFailed := a.Errors == a.Iterations, b.Errors == b.Iterations
+		if aFailed != bFailed {
+			return bFailed
+		}
+		return a.P50 < b.P50
+	})
+	return report, nil
+}
+
+// benchmarkModel 对 Engine 当前的提供商和模型运行基准测试
+func (engine *Engine) benchmarkModel(ctx context.Context, query string, iterations int) (ModelBenchmark, error) {
+	result := ModelBenchmark{Provider: engine.providerName, Model: engine.ModelId, Iterations: iterations}
+	client := engine.newClient()
+
+	var latencies []time.Duration
+	var totalLatency time.Duration
+	var completionTokens int64
+	for i := 1; i <= iterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("基准测试被取消: %w", err)
+		}
+
+		reqCtx := withCorrelation(ctx)
+		opts := append(engine.requestOptions(reqCtx), option.WithMaxRetries(0))
+		start := time.Now()
+		completion, err := client.Chat.Completions.New(reqCtx, openai.ChatCompletionNewParams{
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(query)},
+			Model:    engine.ModelId,
+		}, opts...)
+		latency := time.Since(start)
+		if err != nil {
+			result.Errors++
+			engine.recordError(i, err)
+			LoggerFromContext(reqCtx).Warn("基准测试请求失败", "provider", engine.providerName, "model", engine.ModelId, "iteration", i, "error", err)
+			continue
+		}
+
+		latencies = append(latencies, latency)
+		totalLatency += latency
+		completionTokens += completion.Usage.CompletionTokens
+	}
+
+	result.ErrorRate = float64(result.Errors) / float64(iterations)
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		result.P50 = percentile(latencies, 50)
+		result.P95 = percentile(latencies, 95)
+		result.P99 = percentile(latencies, 99)
+	}
+	if totalLatency > 0 {
+		result.TokensPerSecond = float64(completionTokens) / totalLatency.Seconds()
+	}
+	return result, nil
+}
+
+// percentile 使用最近秩法计算已排序延迟的百分位数
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100 // 向上取整
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
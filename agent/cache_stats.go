@@ -0,0 +1,36 @@
+package agent
+
+import "time"
+
+// CacheStats 缓存统计
+type CacheStats struct {
+	Hits        int64     `json:"hits"`         // 命中次数
+	Misses      int64     `json:"misses"`       // 未命中次数
+	Evictions   int64     `json:"evictions"`    // 因容量不足淘汰的条目数
+	SizeBytes   int64     `json:"size_bytes"`   // 缓存占用的字节数（估算）
+	EntryCount  int       `json:"entry_count"`  // 当前条目数
+	OldestEntry time.Time `json:"oldest_entry"` // 最早写入的条目的写入时间，没有条目时为零值
+	NewestEntry time.Time `json:"newest_entry"` // 最近写入的条目的写入时间，没有条目时为零值
+}
+
+// GetCacheStats 获取嵌入向量缓存（GetEmbedding 使用）的统计，所有副本共享同一缓存
+// 返回:
+//   - CacheStats: 缓存统计
+func (engine *Engine) GetCacheStats() CacheStats {
+	if engine.embeddings == nil {
+		return CacheStats{}
+	}
+	return engine.embeddings.stats()
+}
+
+// InvalidateCacheEntry 清除查询内容对应的缓存条目（所有提供商和模型）
+// 参数:
+//   - query: 查询内容，与调用 GetEmbedding 时的文本一致
+// 返回:
+//   - bool: 是否清除了条目
+func (engine *Engine) InvalidateCacheEntry(query string) bool {
+	if engine.embeddings == nil {
+		return false
+	}
+	return engine.embeddings.remove(query) > 0
+}
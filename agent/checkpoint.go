@@ -0,0 +1,287 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCheckpointNotFound 检查点不存在时 RestoreCheckpoint 返回的错误
+var ErrCheckpointNotFound = errors.New("检查点不存在")
+
+// checkpointData 检查点保存的 Engine 状态，写入统计数据文件（stats_file）
+type checkpointData struct {
+	CreatedAt      time.Time                  `json:"created_at"`       // 创建时间
+	Provider       string                     `json:"provider"`         // 当前提供商
+	Model          string                     `json:"model"`            // 当前模型
+	Sessions       []sessionExport            `json:"sessions"`         // 活跃会话及其历史
+	Latency        map[string][]time.Duration `json:"latency"`          // 延迟统计: provider + "/" + model -> 最近的延迟
+	TokensPerSec   map[string][]float64       `json:"tokens_per_sec"`   // 生成速度统计: provider + "/" + model -> 最近的生成速度
+	Embeddings     []embeddingSnapshot        `json:"embeddings"`       // 嵌入向量缓存，按写入顺序排列
+	PreQueryHooks  int                        `json:"pre_query_hooks"`  // 查询前置钩子数量（钩子本身只保存在进程内）
+	PostQueryHooks int                        `json:"post_query_hooks"` // 查询后置钩子数量（钩子本身只保存在进程内）
+}
+
+// embeddingSnapshot 一条嵌入向量缓存
+type embeddingSnapshot struct {
+	Key     string    `json:"key"`
+	Text    string    `json:"text"`
+	Vector  []float64 `json:"vector"`
+	Created time.Time `json:"created"`
+}
+
+// checkpointHooks 检查点中的查询钩子，函数无法序列化，只能在创建检查点的进程中恢复
+type checkpointHooks struct {
+	pre  []prioritizedHook[PreQueryHook]
+	post []prioritizedHook[PostQueryHook]
+}
+
+// CreateCheckpoint 将当前状态保存为命名检查点，同名检查点会被覆盖
+// 保存的状态包括当前提供商和模型、活跃会话的历史、延迟和生成速度统计、嵌入向量缓存和查询钩子；
+// 除查询钩子外都写入统计数据文件（stats_file），可在之后的运行中恢复；查询钩子只保存在当前进程中
+// 参数:
+//   - name: 检查点名称
+// 返回:
+//   - error: 名称为空或写入文件失败时返回错误
+func (engine *Engine) CreateCheckpoint(name string) error {
+	if name == "" {
+		return fmt.Errorf("检查点名称不能为空")
+	}
+	if engine.statsFile == nil {
+		return fmt.Errorf("统计数据文件未启用")
+	}
+
+	cp := &checkpointData{
+		CreatedAt:      time.Now(),
+		Provider:       engine.GetCurrentProviderName(),
+		Model:          engine.ModelId,
+		PreQueryHooks:  len(engine.preQueryHooks),
+		PostQueryHooks: len(engine.postQueryHooks),
+	}
+	if engine.sessions != nil {
+		engine.sessions.Range(func(_, value any) bool {
+			cp.Sessions = append(cp.Sessions, value.(*ConversationSession).snapshot())
+			return true
+		})
+		slices.SortFunc(cp.Sessions, func(a, b sessionExport) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+	}
+	if engine.stats != nil {
+		cp.Latency, cp.TokensPerSec = engine.stats.snapshot()
+	}
+	if engine.embeddings != nil {
+		cp.Embeddings = engine.embeddings.snapshot()
+	}
+
+	if err := engine.statsFile.saveCheckpoint(name, cp); err != nil {
+		return err
+	}
+	if engine.checkpoints != nil {
+		engine.checkpoints.Store(name, checkpointHooks{pre: engine.preQueryHooks, post: engine.postQueryHooks})
+	}
+	engine.loggerFrom(engine.baseContext()).Info("已创建检查点", "name", name, "provider", cp.Provider, "model", cp.Model, "sessions", len(cp.Sessions))
+	return nil
+}
+
+// RestoreCheckpoint 恢复命名检查点保存的状态
+// 切换到检查点的提供商和模型，恢复检查点中会话的历史（会话已关闭时重新创建，不在检查点中的会话保持不变），
+// 并用检查点中的数据替换延迟和生成速度统计、嵌入向量缓存（这些数据由所有副本共享）；
+// 检查点在当前进程中创建时同时恢复查询钩子，否则保留当前的钩子
+// 参数:
+//   - name: 检查点名称
+// 返回:
+//   - error: 检查点不存在（ErrCheckpointNotFound）、读取文件失败或提供商、模型已不在配置中时返回错误
+func (engine *Engine) RestoreCheckpoint(name string) error {
+	if engine.statsFile == nil {
+		return fmt.Errorf("统计数据文件未启用")
+	}
+	cp, err := engine.statsFile.checkpoint(name)
+	if err != nil {
+		return err
+	}
+	if err := engine.SwitchProvider(cp.Provider, cp.Model); err != nil {
+		return fmt.Errorf("恢复检查点 %s 失败: %w", name, err)
+	}
+
+	for _, snap := range cp.Sessions {
+		engine.restoreSession(snap)
+	}
+	if engine.stats != nil {
+		engine.stats.restore(cp.Latency, cp.TokensPerSec)
+	}
+	if engine.embeddings != nil {
+		engine.embeddings.restore(cp.Embeddings)
+	}
+	logger := engine.loggerFrom(engine.baseContext())
+	if hooks, ok := engine.loadCheckpointHooks(name); ok {
+		engine.preQueryHooks, engine.postQueryHooks = hooks.pre, hooks.post
+	} else if cp.PreQueryHooks > 0 || cp.PostQueryHooks > 0 {
+		logger.Warn("检查点的查询钩子不在当前进程中，保留当前的钩子", "name", name)
+	}
+	logger.Info("已恢复检查点", "name", name, "provider", cp.Provider, "model", cp.Model, "sessions", len(cp.Sessions))
+	return nil
+}
+
+// ListCheckpoints 列出统计数据文件中的检查点
+// 返回:
+//   - []string: 检查点名称，按字典序排列
+//   - error: 读取文件失败时返回错误
+func (engine *Engine) ListCheckpoints() ([]string, error) {
+	if engine.statsFile == nil {
+		return nil, fmt.Errorf("统计数据文件未启用")
+	}
+	engine.statsFile.mu.Lock()
+	defer engine.statsFile.mu.Unlock()
+	stats, err := engine.statsFile.load()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(stats.Checkpoints)), nil
+}
+
+// loadCheckpointHooks 获取当前进程中保存的检查点查询钩子
+func (engine *Engine) loadCheckpointHooks(name string) (checkpointHooks, bool) {
+	if engine.checkpoints == nil {
+		return checkpointHooks{}, false
+	}
+	v, ok := engine.checkpoints.Load(name)
+	if !ok {
+		return checkpointHooks{}, false
+	}
+	return v.(checkpointHooks), true
+}
+
+// restoreSession 恢复会话的历史，会话已关闭时以检查点中的ID重新创建
+func (engine *Engine) restoreSession(snap sessionExport) {
+	if engine.sessions == nil {
+		return
+	}
+	if v, ok := engine.sessions.Load(snap.ID); ok {
+		s := v.(*ConversationSession)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.systemPrompt, s.history, s.summary = snap.SystemPrompt, slices.Clone(snap.Messages), snap.Summary
+		return
+	}
+	clone := engine.Clone()
+	if err := clone.SwitchProvider(snap.Provider, snap.Model); err != nil {
+		engine.loggerFrom(engine.baseContext()).Warn("会话的提供商或模型已不在配置中，使用当前模型", "session", snap.ID, "error", err)
+		clone = engine.Clone()
+	}
+	clone.conversation = nil // 会话自行维护历史
+	engine.sessions.Store(snap.ID, &ConversationSession{
+		id:           snap.ID,
+		engine:       clone,
+		systemPrompt: snap.SystemPrompt,
+		history:      slices.Clone(snap.Messages),
+		summary:      snap.Summary,
+		maxTurns:     engine.conversationMaxTurns(),
+	})
+}
+
+// snapshot 返回会话的状态
+func (s *ConversationSession) snapshot() sessionExport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sessionExport{
+		ID:           s.id,
+		SystemPrompt: s.systemPrompt,
+		Provider:     s.engine.GetCurrentProviderName(),
+		Model:        s.engine.ModelId,
+		Summary:      s.summary,
+		Messages:     slices.Clone(s.history),
+	}
+}
+
+// snapshot 返回延迟和生成速度统计的副本
+func (s *latencyStats) snapshot() (map[string][]time.Duration, map[string][]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latency := make(map[string][]time.Duration, len(s.samples))
+	for k, v := range s.samples {
+		latency[k] = slices.Clone(v)
+	}
+	tps := make(map[string][]float64, len(s.tps))
+	for k, v := range s.tps {
+		tps[k] = slices.Clone(v)
+	}
+	return latency, tps
+}
+
+// restore 用检查点中的数据替换延迟和生成速度统计
+func (s *latencyStats) restore(latency map[string][]time.Duration, tps map[string][]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = make(map[string][]time.Duration, len(latency))
+	for k, v := range latency {
+		s.samples[k] = slices.Clone(v)
+	}
+	s.tps = make(map[string][]float64, len(tps))
+	for k, v := range tps {
+		s.tps[k] = slices.Clone(v)
+	}
+}
+
+// snapshot 按写入顺序返回缓存的嵌入向量
+func (c *embeddingCache) snapshot() []embeddingSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]embeddingSnapshot, 0, len(c.order))
+	for _, key := range c.order {
+		e := c.entries[key]
+		entries = append(entries, embeddingSnapshot{Key: key, Text: e.text, Vector: slices.Clone(e.vector), Created: e.created})
+	}
+	return entries
+}
+
+// restore 用检查点中的数据替换缓存内容，命中统计保持不变
+func (c *embeddingCache) restore(entries []embeddingSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*embeddingEntry, len(entries))
+	c.order = c.order[:0]
+	c.sizeBytes = 0
+	for _, s := range entries {
+		if _, ok := c.entries[s.Key]; ok || len(c.order) >= embeddingCacheSize {
+			continue
+		}
+		e := &embeddingEntry{text: s.Text, vector: slices.Clone(s.Vector), created: s.Created}
+		c.entries[s.Key] = e
+		c.order = append(c.order, s.Key)
+		c.sizeBytes += entrySize(s.Key, e)
+	}
+}
+
+// saveCheckpoint 将检查点写入统计数据文件
+func (s *statsStore) saveCheckpoint(name string, cp *checkpointData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, err := s.load()
+	if err != nil {
+		return err
+	}
+	if stats.Checkpoints == nil {
+		stats.Checkpoints = make(map[string]*checkpointData)
+	}
+	stats.Checkpoints[name] = cp
+	return s.save(stats)
+}
+
+// checkpoint 读取命名检查点
+func (s *statsStore) checkpoint(name string) (*checkpointData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	cp, ok := stats.Checkpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, name)
+	}
+	return cp, nil
+}
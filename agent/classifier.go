@@ -0,0 +1,309 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// QueryType 查询类型枚举
+type QueryType int
+
+const (
+	Unknown           QueryType = iota // 无法确定类型
+	QuestionAnswering                  // 问答
+	CodeGeneration                     // 代码生成
+	Translation                        // 翻译
+	Summarization                      // 摘要总结
+	Creative                           // 创意写作
+)
+
+// queryTypeNames 查询类型与配置文件中名称的对应关系
+var queryTypeNames = map[QueryType]string{
+	Unknown:           "unknown",
+	QuestionAnswering: "question_answering",
+	CodeGeneration:    "code_generation",
+	Translation:       "translation",
+	Summarization:     "summarization",
+	Creative:          "creative",
+}
+
+// String 返回查询类型在配置文件中使用的名称
+func (t QueryType) String() string {
+	if name, ok := queryTypeNames[t]; ok {
+		return name
+	}
+	return queryTypeNames[Unknown]
+}
+
+// queryTypeCapabilities 处理各查询类型所需的模型能力（对应 ModelConfig.Capabilities 的键）
+var queryTypeCapabilities = map[QueryType]string{
+	QuestionAnswering: "chat",
+	CodeGeneration:    "code",
+	Translation:       "translation",
+	Summarization:     "summarization",
+	Creative:          "creative",
+}
+
+// Capability 返回处理该类型查询所需的模型能力，Unknown 返回空字符串
+func (t QueryType) Capability() string {
+	return queryTypeCapabilities[t]
+}
+
+// ParseQueryType 将配置中的名称解析为查询类型
+// 参数:
+//   - name: 类型名称，例如 code_generation（不区分大小写）
+// 返回:
+//   - QueryType: 查询类型
+//   - error: 名称无法识别时返回错误
+func ParseQueryType(name string) (QueryType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for t, n := range queryTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return Unknown, fmt.Errorf("未知的查询类型: %s", name)
+}
+
+// classifierRule 编译后的匹配规则
+type classifierRule struct {
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// defaultClassifierRules 内置的分类规则，可通过配置文件按类型覆盖
+var defaultClassifierRules = map[QueryType]conf.ClassifierRule{
+	CodeGeneration: {
+		Keywords: []string{"代码", "函数", "实现", "编写", "bug", "code", "function", "implement", "golang", "python", "java", "sql"},
+		Patterns: []string{"(?i)\\b(write|generate)\\b.*\\b(code|script|function|class)\\b", "```"},
+	},
+	Translation: {
+		Keywords: []string{"翻译", "译成", "translate", "translation"},
+		Patterns: []string{"(?i)\\bin(to)? (english|chinese|japanese|french|german)\\b", "(翻译|译)(成|为)"},
+	},
+	Summarization: {
+		Keywords: []string{"总结", "摘要", "概括", "归纳", "summarize", "summary", "tl;dr"},
+		Patterns: []string{"(?i)\\bsum(marize|mary)\\b", "(简要|简单)(概括|总结)"},
+	},
+	Creative: {
+		Keywords: []string{"写一首", "诗", "故事", "小说", "歌词", "poem", "story", "lyrics", "创作"},
+		Patterns: []string{"(?i)\\bwrite (a|an) (poem|story|song)\\b"},
+	},
+	QuestionAnswering: {
+		Keywords: []string{"什么", "为什么", "怎么", "如何", "是否", "what", "why", "how", "who", "when", "where"},
+		Patterns: []string{"[?？]\\s*$"},
+	},
+}
+
+// ClassifyFallback 规则无法确定类型时的辅助分类函数
+type ClassifyFallback func(ctx context.Context, query string) (QueryType, error)
+
+// QueryClassifier 查询分类器
+// 首先使用关键词和正则表达式进行规则匹配，无法确定时可调用 Fallback 进行辅助分类
+type QueryClassifier struct {
+	Fallback ClassifyFallback // 辅助分类函数（可选）
+
+	rules map[QueryType]classifierRule
+}
+
+// NewQueryClassifier 根据配置创建查询分类器
+// 参数:
+//   - cfg: 分类器配置，同类型的规则会覆盖内置规则，可为 nil
+// 返回:
+//   - *QueryClassifier: 分类器实例指针
+//   - error: 类型名称或正则表达式无效时返回错误
+func NewQueryClassifier(cfg *conf.ClassifierConfig) (*QueryClassifier, error) {
+	ruleConfigs := make(map[QueryType]conf.ClassifierRule, len(defaultClassifierRules))
+	for t, r := range defaultClassifierRules {
+		ruleConfigs[t] = r
+	}
+	if cfg != nil {
+		for _, r := range cfg.Rules {
+			t, err := ParseQueryType(r.Type)
+			if err != nil {
+				return nil, fmt.Errorf("解析分类规则失败: %w", err)
+			}
+			ruleConfigs[t] = r
+		}
+	}
+
+	classifier := &QueryClassifier{rules: make(map[QueryType]classifierRule, len(ruleConfigs))}
+	for t, r := range ruleConfigs {
+		compiled := classifierRule{keywords: make([]string, 0, len(r.Keywords))}
+		for _, k := range r.Keywords {
+			compiled.keywords = append(compiled.keywords, strings.ToLower(k))
+		}
+		for _, p := range r.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("编译 %s 类型的正则表达式 %q 失败: %w", t, p, err)
+			}
+			compiled.patterns = append(compiled.patterns, re)
+		}
+		classifier.rules[t] = compiled
+	}
+
+	return classifier, nil
+}
+
+// Classify 对查询进行分类
+// 参数:
+//   - ctx: 上下文，传给辅助分类函数，辅助分类失败时使用其中的 logger 记录
+//   - query: 查询内容
+// 返回:
+//   - QueryType: 查询类型，无法确定时返回 Unknown
+func (c *QueryClassifier) Classify(ctx context.Context, query string) QueryType {
+	lower := strings.ToLower(query)
+
+	// 规则匹配：每命中一个关键词或正则计 1 分，取最高分的类型
+	best, bestScore, tie := Unknown, 0, false
+	for t, rule := range c.rules {
+		score := 0
+		for _, k := range rule.keywords {
+			if strings.Contains(lower, k) {
+				score++
+			}
+		}
+		for _, re := range rule.patterns {
+			if re.MatchString(query) {
+				score++
+			}
+		}
+		// 问答是最宽泛的类型，同分时让位于更具体的类型
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = t, score, false
+		case score == bestScore && score > 0:
+			if best == QuestionAnswering {
+				best, tie = t, false
+			} else if t != QuestionAnswering {
+				tie = true
+			}
+		}
+	}
+
+	// 规则能够确定类型时直接返回
+	if bestScore > 0 && !tie {
+		return best
+	}
+
+	// 不确定时使用辅助分类
+	if c.Fallback != nil {
+		t, err := c.Fallback(ctx, query)
+		if err != nil {
+			LoggerFromContext(ctx).Warn("辅助分类失败", "error", err)
+			return Unknown
+		}
+		return t
+	}
+	return Unknown
+}
+
+// NewModelClassifyFallback 创建使用模型进行辅助分类的函数
+// 参数:
+//   - engine: Engine 实例，使用其当前提供商
+//   - modelId: 用于分类的模型ID（建议使用轻量模型）
+// 返回:
+//   - ClassifyFallback: 辅助分类函数
+func NewModelClassifyFallback(engine *Engine, modelId string) ClassifyFallback {
+	return func(ctx context.Context, query string) (QueryType, error) {
+		names := make([]string, 0, len(queryTypeNames))
+		for t := QuestionAnswering; t <= Creative; t++ {
+			names = append(names, t.String())
+		}
+		prompt := fmt.Sprintf("请判断下面的用户请求属于哪一类，只回答类别名称（%s 之一）：\n\n%s", strings.Join(names, ", "), query)
+
+		client := engine.newClient()
+		completion, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(prompt)},
+			Model:    modelId,
+		})
+		if err != nil {
+			return Unknown, fmt.Errorf("调用分类模型 %s 失败: %w", modelId, err)
+		}
+		if len(completion.Choices) == 0 {
+			return Unknown, fmt.Errorf("分类模型 %s 未返回结果", modelId)
+		}
+		return ParseQueryType(completion.Choices[0].Message.Content)
+	}
+}
+
+// QueryRouter 基于查询内容的路由器
+// 使用 QueryClassifier 判断查询类型，再根据路由表切换到对应的提供商和模型
+type QueryRouter struct {
+	Classifier *QueryClassifier
+	Routes     map[QueryType]conf.RouteConfig
+}
+
+// NewQueryRouter 根据 Engine 的配置创建路由器
+// 复用创建 Engine 时编译的分类规则，辅助分类使用传入的 Engine
+// 参数:
+//   - engine: Engine 实例
+// 返回:
+//   - *QueryRouter: 路由器实例指针
+//   - error: 错误信息
+func NewQueryRouter(engine *Engine) (*QueryRouter, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	cfg := &engine.config.Classifier
+
+	compiled := engine.classifier
+	if compiled == nil {
+		var err error
+		if compiled, err = NewQueryClassifier(cfg); err != nil {
+			return nil, err
+		}
+	}
+	classifier := &QueryClassifier{rules: compiled.rules}
+	if cfg.FallbackModel != "" {
+		classifier.Fallback = NewModelClassifyFallback(engine, cfg.FallbackModel)
+	}
+
+	routes := make(map[QueryType]conf.RouteConfig, len(cfg.Routes))
+	for name, route := range cfg.Routes {
+		t, err := ParseQueryType(name)
+		if err != nil {
+			return nil, fmt.Errorf("解析路由配置失败: %w", err)
+		}
+		routes[t] = route
+	}
+
+	return &QueryRouter{Classifier: classifier, Routes: routes}, nil
+}
+
+// Route 对查询进行分类并切换 Engine 到对应的路由目标
+// 参数:
+//   - ctx: 上下文
+//   - engine: 需要切换的 Engine 实例
+//   - query: 查询内容
+// 返回:
+//   - QueryType: 查询类型
+//   - bool: 是否发生了路由切换
+//   - error: 错误信息
+func (r *QueryRouter) Route(ctx context.Context, engine *Engine, query string) (QueryType, bool, error) {
+	t := r.Classifier.Classify(engine.withLogger(ctx), query)
+	route, ok := r.Routes[t]
+	if !ok {
+		return t, false, nil
+	}
+
+	if route.Provider != "" && route.Provider != engine.GetCurrentProviderName() {
+		if err := engine.SwitchProvider(route.Provider, route.Model); err != nil {
+			return t, false, fmt.Errorf("路由到提供商 %s 失败: %w", route.Provider, err)
+		}
+		return t, true, nil
+	}
+	if route.Model != "" && route.Model != engine.ModelId {
+		if err := engine.SwitchModel(route.Model); err != nil {
+			return t, false, fmt.Errorf("路由到模型 %s 失败: %w", route.Model, err)
+		}
+		return t, true, nil
+	}
+	return t, false, nil
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithMaxConcurrency 返回限制同时处理的分发请求数（DispatchAndHandle、DispatchRequest 等）的 Engine 副本
+// 达到上限后新的请求阻塞等待，直到有请求完成或请求上下文结束；信号量由返回的副本及其后续副本共享，原 Engine 不受影响
+// 参数:
+//   - n: 最大并发请求数，小于等于 0 时不限制
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithMaxConcurrency(n int) *Engine {
+	clone := engine.Clone()
+	clone.concurrency = nil
+	if n > 0 {
+		clone.concurrency = make(chan struct{}, n)
+	}
+	return clone
+}
+
+// GetActiveConcurrency 获取正在处理的分发请求数，未通过 WithMaxConcurrency 设置上限时为 0
+// 返回:
+//   - int: 占用并发名额的请求数，不包括等待中的请求
+func (engine *Engine) GetActiveConcurrency() int {
+	return len(engine.concurrency)
+}
+
+// acquireConcurrency 等待并占用一个并发名额，未设置上限时直接返回；返回的函数用于释放名额
+func (engine *Engine) acquireConcurrency(ctx context.Context) (release func(), err error) {
+	if engine.concurrency == nil {
+		return func() {}, nil
+	}
+	select {
+	case engine.concurrency <- struct{}{}:
+		return func() { <-engine.concurrency }, nil
+	default:
+	}
+	engine.debug(ctx, "并发请求数已达上限，等待", "max_concurrency", cap(engine.concurrency))
+	select {
+	case engine.concurrency <- struct{}{}:
+		return func() { <-engine.concurrency }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("等待并发名额时请求被取消: %w", context.Cause(ctx))
+	}
+}
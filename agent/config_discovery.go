@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateConfigFromAPI 调用 GET {baseURL}/v1/models 获取提供商的模型列表，生成只包含该提供商的配置
+// baseURL 已以 /v1 结尾时不再追加；提供商名称取自主机名（如 api.deepseek.com -> deepseek），模型按ID排序
+// 参数:
+//   - ctx: 上下文
+//   - baseURL: 提供商地址，如 https://api.deepseek.com
+//   - apiKey: API 密钥，写入生成的配置
+// 返回:
+//   - *conf.Config: 生成的配置，base_url 为 {baseURL}/v1
+//   - error: 地址无效、请求失败或提供商没有返回任何模型时返回错误
+func (engine *Engine) GenerateConfigFromAPI(ctx context.Context, baseURL, apiKey string) (*conf.Config, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("无效的基础URL: %s", baseURL)
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("API 密钥不能为空")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if !strings.HasSuffix(u.Path, "/v1") {
+		u.Path += "/v1"
+	}
+	apiURL := u.String()
+
+	ctx, cancel := mergeContext(engine.baseCtx, ctx)
+	defer cancel()
+	client := openai.NewClient(option.WithAPIKey(apiKey), option.WithBaseURL(apiURL), option.WithHTTPClient(traceHTTPClient))
+
+	var ids []string
+	iter := client.Models.ListAutoPaging(ctx)
+	for iter.Next() {
+		if id := iter.Current().ID; id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("获取模型列表失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("提供商 %s 没有返回任何模型", apiURL)
+	}
+	slices.Sort(ids)
+	engine.loggerFrom(ctx).Info("已获取模型列表", "base_url", apiURL, "models", len(ids))
+
+	provider := conf.ProviderConfig{
+		Name:    providerNameFromHost(u.Hostname()),
+		ApiKey:  apiKey,
+		BaseUrl: apiURL,
+	}
+	for _, id := range ids {
+		provider.Models = append(provider.Models, conf.ModelConfig{ID: id})
+	}
+	return &conf.Config{Provider: []conf.ProviderConfig{provider}}, nil
+}
+
+// providerNameFromHost 根据主机名生成提供商名称：去掉 api、www 前缀后取第一段，IP 地址原样返回
+func providerNameFromHost(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	for len(labels) > 1 && (labels[0] == "api" || labels[0] == "www") {
+		labels = labels[1:]
+	}
+	return labels[0]
+}
+
+// WriteConfig 将配置以 YAML 格式写入新文件，文件权限为 0600（配置中包含 API 密钥）
+// 参数:
+//   - configPath: 配置文件路径，文件已存在时不覆盖
+//   - config: 配置
+// 返回:
+//   - error: 文件已存在、序列化或写入失败时返回错误
+func WriteConfig(configPath string, config *conf.Config) error {
+	if len(config.Provider) == 0 {
+		return fmt.Errorf("配置中没有提供商")
+	}
+	var (
+		doc  *yaml.Node
+		data []byte
+		err  error
+	)
+	for i := range config.Provider {
+		if data, err = wizardConfigYAML(doc, &config.Provider[i]); err != nil {
+			return err
+		}
+		doc = &yaml.Node{}
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("序列化配置失败: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("创建配置文件失败: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"fmt"
+	"strings"
+)
+
+// GetConfigDrift 重新读取配置文件，与内存中的配置比较并返回两者的差异
+// 用于发现运行期间通过代码修改或配置文件被改动后，内存中的配置与配置文件不一致的情况
+// 返回:
+//   - []conf.ConfigDiff: 差异列表，Old 为内存中的值，New 为配置文件中的值；api_key 的值已脱敏
+//   - error: 配置未加载、没有配置文件路径或读取、解析配置文件失败时返回错误
+func (engine *Engine) GetConfigDrift() ([]conf.ConfigDiff, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	if engine.configPath == "" {
+		return nil, fmt.Errorf("Engine 不是从配置文件创建的，无法比较")
+	}
+	fileConfig, err := conf.LoadConfig(engine.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("重新读取配置文件失败: %w", err)
+	}
+
+	diffs := engine.config.Diff(fileConfig)
+	for i, d := range diffs {
+		isKey := strings.HasSuffix(d.Path, ".api_key")
+		diffs[i].Old, diffs[i].New = redactDiffValue(d.Old, isKey), redactDiffValue(d.New, isKey)
+	}
+	return diffs, nil
+}
+
+// redactDiffValue 脱敏差异中的密钥：isKey 为 true 时 v 为 api_key 的值；增加或删除整个提供商时脱敏其 api_key
+func redactDiffValue(v any, isKey bool) any {
+	switch v := v.(type) {
+	case string:
+		if isKey {
+			return redactAPIKey(v)
+		}
+	case conf.ProviderConfig:
+		v.ApiKey = redactAPIKey(v.ApiKey)
+		return v
+	}
+	return v
+}
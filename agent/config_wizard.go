@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"agent_engine/conf"
+
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrWizardCanceled 用户取消了配置向导
+var ErrWizardCanceled = errors.New("已取消配置向导")
+
+// configWizard 交互式配置向导的输入输出
+type configWizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	fd  int // 输入对应的终端文件描述符，-1 表示输入不是终端（此时 API 密钥无法隐藏）
+}
+
+// RunConfigWizard 交互式生成配置文件
+// 依次询问提供商名称、基础URL、API 密钥（终端中不回显）和模型ID（输入空行结束），然后写入配置文件
+// 配置文件已存在时询问是添加提供商还是覆盖；添加时保留原文件中的其他配置和注释
+// 参数:
+//   - configPath: 配置文件路径，不支持 github:// 地址
+//   - in: 用户输入，通常为 os.Stdin
+//   - out: 提示信息的输出目标
+// 返回:
+//   - error: 用户取消时返回 ErrWizardCanceled，读写配置失败时返回错误
+func RunConfigWizard(configPath string, in *os.File, out io.Writer) error {
+	if conf.IsGitHubURI(configPath) {
+		return fmt.Errorf("配置向导不支持 GitHub 配置: %s", configPath)
+	}
+
+	w := &configWizard{in: bufio.NewReader(in), out: out, fd: -1}
+	if fd := int(in.Fd()); term.IsTerminal(fd) {
+		w.fd = fd
+	}
+
+	// 配置文件已存在时，添加提供商需要保留原内容
+	var doc *yaml.Node
+	var existingNames []string
+	existing, err := os.ReadFile(configPath)
+	switch {
+	case err == nil:
+		choice, err := w.choose(fmt.Sprintf("配置文件 %s 已存在，添加提供商(a) / 覆盖(o) / 取消(q)", configPath), "a", "o", "q")
+		if err != nil {
+			return err
+		}
+		switch choice {
+		case "q":
+			return ErrWizardCanceled
+		case "a":
+			doc = &yaml.Node{}
+			if err := yaml.Unmarshal(existing, doc); err != nil {
+				return fmt.Errorf("解析配置文件失败: %w", err)
+			}
+			var config conf.Config
+			if err := yaml.Unmarshal(existing, &config); err != nil {
+				return fmt.Errorf("解析配置文件失败: %w", err)
+			}
+			for _, p := range config.Provider {
+				existingNames = append(existingNames, p.Name)
+			}
+		}
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	provider, err := w.promptProvider(existingNames)
+	if err != nil {
+		return err
+	}
+
+	data, err := wizardConfigYAML(doc, provider)
+	if err != nil {
+		return err
+	}
+	// 配置中包含 API 密钥，只允许当前用户读写
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	fmt.Fprintf(w.out, "配置已写入 %s（提供商 %s，%d 个模型）\n", configPath, provider.Name, len(provider.Models))
+	return nil
+}
+
+// promptProvider 询问单个提供商的配置
+func (w *configWizard) promptProvider(existingNames []string) (*conf.ProviderConfig, error) {
+	provider := &conf.ProviderConfig{}
+
+	for provider.Name == "" {
+		name, err := w.prompt("提供商名称（如 deepseek）: ")
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(existingNames, name) {
+			fmt.Fprintf(w.out, "提供商 %s 已存在，请使用其他名称\n", name)
+			continue
+		}
+		provider.Name = name
+	}
+
+	for provider.BaseUrl == "" {
+		baseUrl, err := w.prompt("基础URL（如 https://api.deepseek.com/v1）: ")
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(baseUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fmt.Fprintf(w.out, "无效的基础URL: %s\n", baseUrl)
+			continue
+		}
+		provider.BaseUrl = baseUrl
+	}
+
+	for provider.ApiKey == "" {
+		apiKey, err := w.promptHidden("API 密钥（也可填写 env://变量名 等密钥引用）: ")
+		if err != nil {
+			return nil, err
+		}
+		provider.ApiKey = apiKey
+	}
+
+	fmt.Fprintln(w.out, "依次输入模型ID，输入空行结束（第一个模型为默认模型）")
+	for {
+		modelId, err := w.readLine(fmt.Sprintf("模型ID #%d: ", len(provider.Models)+1))
+		if err != nil {
+			return nil, err
+		}
+		if modelId == "" {
+			if len(provider.Models) == 0 {
+				fmt.Fprintln(w.out, "至少需要一个模型")
+				continue
+			}
+			return provider, nil
+		}
+		if provider.HasModel(modelId) {
+			fmt.Fprintf(w.out, "模型 %s 已添加\n", modelId)
+			continue
+		}
+		provider.Models = append(provider.Models, conf.ModelConfig{ID: modelId})
+	}
+}
+
+// choose 询问用户从多个选项中选择一个，不区分大小写
+func (w *configWizard) choose(question string, options ...string) (string, error) {
+	for {
+		answer, err := w.prompt(question + ": ")
+		if err != nil {
+			return "", err
+		}
+		answer = strings.ToLower(answer)
+		if slices.Contains(options, answer) {
+			return answer, nil
+		}
+		fmt.Fprintf(w.out, "请输入 %s 之一\n", strings.Join(options, "、"))
+	}
+}
+
+// prompt 读取一行非空输入
+func (w *configWizard) prompt(question string) (string, error) {
+	for {
+		line, err := w.readLine(question)
+		if err != nil || line != "" {
+			return line, err
+		}
+	}
+}
+
+// readLine 输出提示并读取一行输入（去除首尾空白），输入结束时返回 ErrWizardCanceled
+func (w *configWizard) readLine(question string) (string, error) {
+	fmt.Fprint(w.out, question)
+	line, err := w.in.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		fmt.Fprintln(w.out)
+		return "", ErrWizardCanceled
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("读取输入失败: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptHidden 读取一行非空输入，输入是终端时不回显
+func (w *configWizard) promptHidden(question string) (string, error) {
+	if w.fd < 0 {
+		return w.prompt(question)
+	}
+	for {
+		fmt.Fprint(w.out, question)
+		line, err := w.readHidden()
+		fmt.Fprintln(w.out)
+		if err != nil {
+			return "", err
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
+		}
+	}
+}
+
+// readHidden 将终端切换到原始模式后逐字节读取一行，不回显输入内容
+// 支持退格键删除，Ctrl+C 或 Ctrl+D 取消
+func (w *configWizard) readHidden() (string, error) {
+	oldState, err := term.MakeRaw(w.fd)
+	if err != nil {
+		return "", fmt.Errorf("切换终端模式失败: %w", err)
+	}
+	defer term.Restore(w.fd, oldState)
+
+	var buf []byte
+	for {
+		b, err := w.in.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("读取输入失败: %w", err)
+		}
+		switch b {
+		case '\r', '\n':
+			return string(buf), nil
+		case 3, 4: // Ctrl+C、Ctrl+D
+			return "", ErrWizardCanceled
+		case 8, 127: // 退格
+			if len(buf) > 0 {
+				// 按 UTF-8 删除最后一个完整字符
+				_, size := utf8.DecodeLastRune(buf)
+				buf = buf[:len(buf)-size]
+			}
+		default:
+			buf = append(buf, b)
+		}
+	}
+}
+
+// wizardConfigYAML 生成写入的配置内容
+// doc 为空时生成只包含该提供商的新配置，否则将提供商追加到 doc 的 provider 列表末尾
+func wizardConfigYAML(doc *yaml.Node, provider *conf.ProviderConfig) ([]byte, error) {
+	if doc == nil || len(doc.Content) == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("配置文件格式错误: 顶层不是映射")
+	}
+
+	var providerNode yaml.Node
+	if err := providerNode.Encode(provider); err != nil {
+		return nil, fmt.Errorf("序列化提供商配置失败: %w", err)
+	}
+
+	var list *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "provider" {
+			list = root.Content[i+1]
+			break
+		}
+	}
+	switch {
+	case list == nil:
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "provider"}, list}, root.Content...)
+	case list.Kind == yaml.ScalarNode && list.Tag == "!!null":
+		// provider: 未填写内容时替换为列表
+		*list = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", LineComment: list.LineComment}
+	case list.Kind != yaml.SequenceNode:
+		return nil, fmt.Errorf("配置文件格式错误: provider 不是列表")
+	}
+	list.Content = append(list.Content, &providerNode)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("序列化配置失败: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	// 确认生成的配置可以被正常解析
+	var config conf.Config
+	if err := yaml.Unmarshal(buf.Bytes(), &config); err != nil {
+		return nil, fmt.Errorf("生成的配置无效: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+)
+
+// contextKey 本包在 context 中存储数据使用的键类型，避免与其他包冲突
+type contextKey int
+
+const (
+	loggerContextKey        contextKey = iota // *slog.Logger
+	correlationIDContextKey                   // string，请求关联ID
+	traceContextKey                           // traceContext，W3C Trace Context
+	requestContextKey                         // *QueryRequest，适配器序列化前的原始请求
+	requestIDContextKey                       // string，上游服务传入的请求ID，见 Engine.WithRequestID
+	queryLabelContextKey                      // string，MultiQuery 中查询的标签
+	engineContextKey                          // *Engine，查询钩子中处理查询的 Engine
+	streamWriterContextKey                    // *streamWriter，流式查询写入回复分片的目标
+	toolCallContextKey                        // *ToolCallHandler，查询中声明的工具及其执行器
+)
+
+// ContextWithLogger 返回携带 logger 的上下文
+// 参数:
+//   - ctx: 父上下文
+//   - logger: 日志记录器
+// 返回:
+//   - context.Context: 新的上下文
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// LoggerFromContext 从上下文中获取 logger，未设置时返回 slog.Default()
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *slog.Logger: 日志记录器
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 多轮对话的默认设置
+const (
+	DefaultMaxTurns   = 20                             // 未配置 max_turns 时会话保留的最多轮数
+	DefaultSessionDir = "./agent_engine_logs/sessions" // 未配置 session_dir 时保存对话的目录
+)
+
+// ErrNoConversation Engine 没有进行中的对话时返回的错误
+var ErrNoConversation = errors.New("没有进行中的对话")
+
+// conversationFile 对话文件的 JSON 结构
+type conversationFile struct {
+	sessionExport
+	UpdatedAt time.Time `json:"updated_at"` // 最后保存时间
+}
+
+// StartConversation 创建新的会话并将其附加到 Engine，之前的对话不再参与查询
+// Engine 带有对话时，每次查询都会在请求的历史消息之前发送会话的系统提示和历史，查询成功后将本轮的查询和回复追加到会话中；
+// 之后创建的副本共享同一会话。会话计入活跃会话，可以通过 CompressConversation 等方法按ID管理
+// 返回:
+//   - *ConversationSession: 会话指针
+func (engine *Engine) StartConversation() *ConversationSession {
+	engine.conversation = engine.NewSession("")
+	return engine.conversation
+}
+
+// ResumeConversation 恢复会话并将其附加到 Engine
+// 会话ID已在活跃会话中时直接使用该会话，否则从 session_dir 中的对话文件加载，文件不存在时以该ID创建新的会话
+// 参数:
+//   - sessionId: 会话ID，对应文件 <session_dir>/<sessionId>.json
+// 返回:
+//   - *ConversationSession: 会话指针
+//   - error: 会话ID无效或读取、解析对话文件失败时返回错误
+func (engine *Engine) ResumeConversation(sessionId string) (*ConversationSession, error) {
+	if engine.sessions != nil {
+		if v, ok := engine.sessions.Load(sessionId); ok {
+			engine.conversation = v.(*ConversationSession)
+			return engine.conversation, nil
+		}
+	}
+	path, err := engine.conversationPath(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	var file conversationFile
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("读取对话文件失败: %w", err)
+	default:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析对话文件失败: %w", err)
+		}
+	}
+
+	s := engine.newSession(sessionId, file.SystemPrompt)
+	s.summary = file.Summary
+	s.history = file.Messages
+	s.trim()
+	engine.conversation = s
+	return s, nil
+}
+
+// SaveConversation 将进行中的对话保存到 session_dir 中以会话ID命名的文件
+// 返回:
+//   - error: 没有进行中的对话（ErrNoConversation）或写入失败时返回错误
+func (engine *Engine) SaveConversation() error {
+	s := engine.conversation
+	if s == nil {
+		return ErrNoConversation
+	}
+	path, err := engine.conversationPath(s.id)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(conversationFile{sessionExport: s.snapshot(), UpdatedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化对话失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建对话目录失败: %w", err)
+	}
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入对话文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入对话文件失败: %w", err)
+	}
+	return nil
+}
+
+// GetConversation 获取进行中的对话
+// 返回:
+//   - *ConversationSession: 会话指针，没有进行中的对话时为 nil
+func (engine *Engine) GetConversation() *ConversationSession {
+	return engine.conversation
+}
+
+// EndConversation 结束进行中的对话，之后的查询不再携带对话历史；会话仍在活跃会话中，不再使用时应调用 Close
+// 已创建的副本不受影响
+func (engine *Engine) EndConversation() {
+	engine.conversation = nil
+}
+
+// conversationMaxTurns 返回会话保留的最多轮数
+func (engine *Engine) conversationMaxTurns() int {
+	if engine.config != nil && engine.config.MaxTurns > 0 {
+		return engine.config.MaxTurns
+	}
+	return DefaultMaxTurns
+}
+
+// conversationPath 返回会话ID对应的对话文件路径，会话ID不能包含路径
+func (engine *Engine) conversationPath(sessionId string) (string, error) {
+	if sessionId == "" || sessionId == "." || sessionId == ".." || filepath.Base(sessionId) != sessionId {
+		return "", fmt.Errorf("无效的会话ID: %q", sessionId)
+	}
+	dir := DefaultSessionDir
+	if engine.config != nil && engine.config.SessionDir != "" {
+		dir = engine.config.SessionDir
+	}
+	return filepath.Join(dir, sessionId+".json"), nil
+}
+
+// queryHistory 返回 Engine 带有会话时在请求前发送的历史消息：系统提示加全部历史轮次
+func (s *ConversationSession) queryHistory() []ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fullHistory()
+}
+
+// appendTurn 追加一轮查询和回复，超过最多轮数时丢弃最早的轮次
+func (s *ConversationSession) appendTurn(query string, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history,
+		ChatMessage{Role: RoleUser, Content: query},
+		ChatMessage{Role: RoleAssistant, Content: reply},
+	)
+	s.trim()
+}
+
+// trim 丢弃超过最多轮数的最早轮次，配置了 prune_after 时历史超过该条数后删除较早的轮次（见 Prune）
+// 历史开头的摘要消息（见 Compress）不计入轮数且始终保留，调用方需持有锁
+func (s *ConversationSession) trim() {
+	if limit := s.maxTurns * 2; s.maxTurns > 0 {
+		s.prune(limit)
+	}
+	if config := s.engine.config; config != nil && config.PruneAfter > 0 {
+		s.prune(config.PruneAfter)
+	}
+}
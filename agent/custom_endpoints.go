@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// defaultEndpointPaths 可通过 custom_endpoints 覆盖的接口及其相对于基础URL的默认路径
+var defaultEndpointPaths = map[string]string{
+	conf.EndpointCompletions: "chat/completions",
+	conf.EndpointModels:      "models",
+	conf.EndpointEmbeddings:  "embeddings",
+}
+
+// endpointMiddleware 返回将默认接口路径改写为 custom_endpoints 中配置路径的客户端中间件
+// models 接口的子路径（如 models/{model_id}）会保留在配置路径之后
+func endpointMiddleware(baseURL string, endpoints map[string]string) option.Middleware {
+	prefix := ""
+	if u, err := url.Parse(baseURL); err == nil {
+		prefix = strings.TrimSuffix(u.Path, "/")
+	}
+
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		rel, ok := strings.CutPrefix(req.URL.Path, prefix+"/")
+		if !ok {
+			return next(req)
+		}
+		for name, custom := range endpoints {
+			def, known := defaultEndpointPaths[name]
+			if !known || custom == "" {
+				continue
+			}
+			rest, matched := strings.CutPrefix(rel, def)
+			if !matched || rest != "" && !strings.HasPrefix(rest, "/") {
+				continue
+			}
+			path := strings.TrimSuffix(custom, "/")
+			if !strings.HasPrefix(custom, "/") {
+				path = prefix + "/" + path
+			}
+			req.URL.Path = path + rest
+			req.URL.RawPath = ""
+			break
+		}
+		return next(req)
+	}
+}
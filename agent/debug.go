@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// WithDebugMode 返回开启调试模式的 Engine 副本
+// 调试模式下，配置、模型选择依据、缓存命中情况和重试决策会以 debug 级别记录日志，同时记录发送给模型的完整请求 JSON
+// 请求内容可能包含个人信息等敏感数据，只应在排查问题时开启；日志需要 logger 的级别为 Debug 才会输出
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithDebugMode() *Engine {
+	clone := engine.Clone()
+	clone.debugMode = true
+
+	ctx := clone.baseContext()
+	providers := 0
+	if clone.config != nil {
+		providers = len(clone.config.Provider)
+	}
+	clone.debug(ctx, "已加载配置",
+		"config_path", clone.configPath,
+		"providers", providers,
+		"provider", clone.GetCurrentProviderName(),
+		"model", clone.ModelId,
+		"base_url", clone.BaseUrl,
+		"smart_fallback", clone.smartFallback,
+		"global_timeout", clone.globalTimeout)
+	return clone
+}
+
+// IsDebugMode 是否开启了调试模式，见 WithDebugMode
+func (engine *Engine) IsDebugMode() bool {
+	return engine.debugMode
+}
+
+// debug 调试模式下以 debug 级别记录日志
+func (engine *Engine) debug(ctx context.Context, msg string, args ...any) {
+	if !engine.debugMode {
+		return
+	}
+	engine.loggerFrom(ctx).Debug(msg, args...)
+}
+
+// debugJSON 调试模式下以 debug 级别记录 v 序列化后的 JSON
+func (engine *Engine) debugJSON(ctx context.Context, msg string, key string, v any, args ...any) {
+	if !engine.debugMode {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		engine.loggerFrom(ctx).Debug(msg, append(args, "error", err)...)
+		return
+	}
+	engine.loggerFrom(ctx).Debug(msg, append(args, key, string(data))...)
+}
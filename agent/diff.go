@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxDiffCells 逐词比较时 LCS 表的最大单元数，超过时改为逐行比较以限制内存占用
+const maxDiffCells = 4_000_000
+
+// diffOp 差异片段的类型
+type diffOp int
+
+const (
+	diffEqual  diffOp = iota // 两边相同
+	diffDelete               // 只在 a 中出现
+	diffInsert               // 只在 b 中出现
+)
+
+// diffChunk 连续的同类型差异片段
+type diffChunk struct {
+	op   diffOp
+	text string
+}
+
+// DiffResponses 比较两个查询结果的回复，生成逐词差异
+// 输出以 ---/+++ 标明两边的提供商和模型，正文中删除的内容标记为 [-...-]，新增的内容标记为 {+...+}（与 git diff --word-diff 相同）
+// 参数:
+//   - a: 原结果
+//   - b: 对比结果
+// 返回:
+//   - string: 差异文本，两个回复相同时正文为「回复相同」
+func (engine *Engine) DiffResponses(a, b *QueryResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a %s\n+++ b %s\n", diffLabel(a), diffLabel(b))
+
+	replyA, replyB := "", ""
+	if a != nil {
+		replyA = a.Reply
+	}
+	if b != nil {
+		replyB = b.Reply
+	}
+	if replyA == replyB {
+		sb.WriteString("回复相同\n")
+		return sb.String()
+	}
+
+	tokensA, tokensB := diffTokens(replyA), diffTokens(replyB)
+	if len(tokensA)*len(tokensB) > maxDiffCells {
+		tokensA, tokensB = lineTokens(replyA), lineTokens(replyB)
+	}
+	for _, c := range diffSequences(tokensA, tokensB) {
+		switch c.op {
+		case diffEqual:
+			sb.WriteString(c.text)
+		case diffDelete:
+			sb.WriteString("[-" + c.text + "-]")
+		case diffInsert:
+			sb.WriteString("{+" + c.text + "+}")
+		}
+	}
+	if !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// diffLabel 生成差异标题中的结果说明
+func diffLabel(r *QueryResult) string {
+	if r == nil {
+		return "(空)"
+	}
+	return r.ProviderUsed + "/" + r.ModelUsed
+}
+
+// diffTokens 将文本拆分为单词和空白片段，拼接后与原文相同
+func diffTokens(s string) []string {
+	var tokens []string
+	start := 0
+	var prev rune
+	for i, r := range s {
+		if i > start && (unicode.IsSpace(prev) != unicode.IsSpace(r) || isCJK(r) || isCJK(prev)) {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		prev = r
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+// isCJK 中日韩文字没有空格分词，按单字比较
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// lineTokens 将文本拆分为行（保留换行符）
+func lineTokens(s string) []string {
+	return strings.SplitAfter(s, "\n")
+}
+
+// diffSequences 基于最长公共子序列计算两个片段序列的差异，并合并相邻的同类型片段
+func diffSequences(a, b []string) []diffChunk {
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var chunks []diffChunk
+	add := func(op diffOp, text string) {
+		if n := len(chunks); n > 0 && chunks[n-1].op == op {
+			chunks[n-1].text += text
+			return
+		}
+		chunks = append(chunks, diffChunk{op: op, text: text})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(diffEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(diffDelete, a[i])
+			i++
+		default:
+			add(diffInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add(diffDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		add(diffInsert, b[j])
+	}
+	return chunks
+}
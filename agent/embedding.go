@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// embeddingCacheSize 嵌入向量缓存的最大条目数，超过时淘汰最早写入的条目
+const embeddingCacheSize = 1000
+
+// ErrNoEmbeddingModel 配置文件未设置 embedding_model 时返回的错误
+var ErrNoEmbeddingModel = errors.New("配置文件未设置 embedding_model")
+
+// embeddingCache 嵌入向量的内存缓存，所有副本共享
+type embeddingCache struct {
+	mu        sync.Mutex
+	entries   map[string]*embeddingEntry
+	order     []string // 写入顺序，用于淘汰
+	hits      int64
+	misses    int64
+	evictions int64
+	sizeBytes int64 // 缓存键和向量占用的字节数（估算）
+}
+
+// embeddingEntry 一条缓存的嵌入向量
+type embeddingEntry struct {
+	text    string    // 原始文本，用于按查询内容清除缓存
+	vector  []float64 // 嵌入向量
+	created time.Time // 写入时间
+}
+
+// newEmbeddingCache 创建嵌入向量缓存
+func newEmbeddingCache() *embeddingCache {
+	return &embeddingCache{entries: make(map[string]*embeddingEntry)}
+}
+
+// embeddingCacheKey 缓存键包含提供商和模型，切换嵌入模型后不会命中旧向量
+func embeddingCacheKey(provider, model, text string) string {
+	return provider + "\x00" + model + "\x00" + text
+}
+
+// entrySize 估算一条缓存占用的字节数
+func entrySize(key string, e *embeddingEntry) int64 {
+	return int64(len(key) + 8*len(e.vector))
+}
+
+// get 获取缓存的嵌入向量
+func (c *embeddingCache) get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return e.vector, true
+}
+
+// put 写入嵌入向量
+func (c *embeddingCache) put(key, text string, v []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	if len(c.order) >= embeddingCacheSize {
+		oldest := c.order[0]
+		c.sizeBytes -= entrySize(oldest, c.entries[oldest])
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+		c.evictions++
+	}
+	e := &embeddingEntry{text: text, vector: v, created: time.Now()}
+	c.entries[key] = e
+	c.order = append(c.order, key)
+	c.sizeBytes += entrySize(key, e)
+}
+
+// remove 删除文本对应的所有缓存（不区分提供商和模型），返回删除的条目数
+func (c *embeddingCache) remove(text string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	order := c.order[:0]
+	for _, key := range c.order {
+		e := c.entries[key]
+		if e.text != text {
+			order = append(order, key)
+			continue
+		}
+		c.sizeBytes -= entrySize(key, e)
+		delete(c.entries, key)
+		removed++
+	}
+	c.order = order
+	return removed
+}
+
+// stats 返回缓存统计
+func (c *embeddingCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CacheStats{
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+		SizeBytes:  c.sizeBytes,
+		EntryCount: len(c.entries),
+	}
+	if len(c.order) > 0 {
+		stats.OldestEntry = c.entries[c.order[0]].created
+		stats.NewestEntry = c.entries[c.order[len(c.order)-1]].created
+	}
+	return stats
+}
+
+// GetEmbedding 使用配置的 embedding_model 获取文本的嵌入向量，结果会缓存在内存中
+// 如果某个提供商的模型列表中包含 embedding_model，使用该提供商；否则使用当前提供商
+// 参数:
+//   - ctx: 上下文
+//   - text: 文本内容
+// 返回:
+//   - []float64: 嵌入向量
+//   - error: 未设置 embedding_model（ErrNoEmbeddingModel）或调用失败时返回错误
+func (engine *Engine) GetEmbedding(ctx context.Context, text string) ([]float64, error) {
+	embedder, err := engine.embeddingEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	key := embeddingCacheKey(embedder.GetCurrentProviderName(), embedder.ModelId, text)
+	if engine.embeddings != nil {
+		if v, ok := engine.embeddings.get(key); ok {
+			engine.debug(ctx, "嵌入向量缓存命中", "provider", embedder.GetCurrentProviderName(), "model", embedder.ModelId, "text_length", len(text))
+			return v, nil
+		}
+		engine.debug(ctx, "嵌入向量缓存未命中", "provider", embedder.GetCurrentProviderName(), "model", embedder.ModelId, "text_length", len(text))
+	}
+
+	var resp *openai.CreateEmbeddingResponse
+	_, err = embedder.callWithRotatedKey(func(client openai.Client) (err error) {
+		resp, err = client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+			Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String(text)},
+			Model: openai.EmbeddingModel(embedder.ModelId),
+		})
+		return err
+	})
+	if err != nil {
+		embedder.recordError(1, err)
+		return nil, fmt.Errorf("获取嵌入向量失败: %w", err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("获取嵌入向量失败: 模型 %s 返回了空结果", embedder.ModelId)
+	}
+	embedder.recordUsage(ctx, resp.Usage.TotalTokens)
+
+	v := resp.Data[0].Embedding
+	if engine.embeddings != nil {
+		engine.embeddings.put(key, text, v)
+	}
+	return v, nil
+}
+
+// ComputeEmbeddingSimilarity 计算两段文本嵌入向量的余弦相似度
+// 参数:
+//   - ctx: 上下文
+//   - text1: 第一段文本
+//   - text2: 第二段文本
+// 返回:
+//   - float64: 余弦相似度，范围 [-1, 1]
+//   - error: 未设置 embedding_model（ErrNoEmbeddingModel）、调用失败或向量无法比较时返回错误
+func (engine *Engine) ComputeEmbeddingSimilarity(ctx context.Context, text1, text2 string) (float64, error) {
+	v1, err := engine.GetEmbedding(ctx, text1)
+	if err != nil {
+		return 0, err
+	}
+	v2, err := engine.GetEmbedding(ctx, text2)
+	if err != nil {
+		return 0, err
+	}
+	return cosineSimilarity(v1, v2)
+}
+
+// embeddingEngine 返回使用 embedding_model 的 Engine 副本
+func (engine *Engine) embeddingEngine() (*Engine, error) {
+	if engine.config == nil || engine.config.EmbeddingModel == "" {
+		return nil, ErrNoEmbeddingModel
+	}
+	return engine.embeddingEngineFor(engine.config.EmbeddingModel), nil
+}
+
+// embeddingEngineFor 返回使用指定嵌入模型的 Engine 副本：优先使用模型列表中包含该模型的提供商，否则使用当前提供商
+func (engine *Engine) embeddingEngineFor(modelId string) *Engine {
+	if clone, err := engine.copyForModel(modelId); err == nil {
+		return clone
+	}
+	// 嵌入模型通常不在对话模型列表中，直接使用当前提供商
+	clone := engine.Clone()
+	clone.ModelId = modelId
+	return clone
+}
+
+// cosineSimilarity 计算两个向量的余弦相似度
+func cosineSimilarity(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("嵌入向量维度不一致: %d != %d", len(a), len(b))
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0, fmt.Errorf("嵌入向量为零向量，无法计算相似度")
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// EmbeddingHandler 实现 EventHandler 接口，处理嵌入向量事件（embed）
+// 每次请求都调用嵌入接口，不使用 GetEmbedding 的内存缓存，以便返回实际消耗的 token 数
+type EmbeddingHandler struct{}
+
+// EmbeddingRequest embed 事件的参数
+type EmbeddingRequest struct {
+	Text  string `json:"text"`            // 文本内容
+	Model string `json:"model,omitempty"` // 嵌入模型，为空时使用配置的 embedding_model
+}
+
+// EmbeddingResult embed 事件的结果
+type EmbeddingResult struct {
+	Embedding    []float64 `json:"embedding"`     // 嵌入向量
+	Dimensions   int       `json:"dimensions"`    // 向量维度
+	ModelUsed    string    `json:"model_used"`    // 实际使用的模型
+	ProviderUsed string    `json:"provider_used"` // 实际使用的提供商
+	TotalTokens  int64     `json:"total_tokens"`  // 消耗的 token 数，提供商未返回用量时为 0
+}
+
+// ParseEmbeddingRequest 解析 embed 事件的参数
+// 参数:
+//   - params: JSON 格式的 EmbeddingRequest，例如 {"text":"你好","model":"text-embedding-3-small"}；不是 JSON 对象时整体作为文本
+// 返回:
+//   - *EmbeddingRequest: 嵌入向量请求
+//   - error: JSON 解析失败或文本为空时返回错误
+func ParseEmbeddingRequest(params string) (*EmbeddingRequest, error) {
+	req := &EmbeddingRequest{Text: params}
+	trimmed := strings.TrimSpace(params)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		req = &EmbeddingRequest{}
+		if err := json.Unmarshal([]byte(trimmed), req); err != nil {
+			return nil, fmt.Errorf("解析请求参数失败: %w", err)
+		}
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, fmt.Errorf("嵌入向量的文本不能为空")
+	}
+	return req, nil
+}
+
+// Handle 处理 embed 命令，返回文本的嵌入向量
+// 参数:
+//   - ctx: 上下文
+//   - engine: Engine 实例
+//   - params: 参数，纯文本或 JSON 格式的 EmbeddingRequest
+//   - event: 事件类型
+// 返回:
+//   - rsp: *EmbeddingResult 嵌入向量
+//   - err: 参数无效、未指定模型且未设置 embedding_model（ErrNoEmbeddingModel）或调用失败时返回错误
+func (h *EmbeddingHandler) Handle(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
+	req, err := ParseEmbeddingRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	var embedder *Engine
+	if req.Model != "" {
+		embedder = engine.embeddingEngineFor(req.Model)
+	} else if embedder, err = engine.embeddingEngine(); err != nil {
+		return nil, err
+	}
+
+	var resp *openai.CreateEmbeddingResponse
+	_, err = embedder.callWithRotatedKey(func(client openai.Client) (err error) {
+		resp, err = client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+			Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String(req.Text)},
+			Model: openai.EmbeddingModel(embedder.ModelId),
+		}, embedder.requestOptions(ctx)...)
+		return err
+	})
+	if err != nil {
+		embedder.recordError(1, err)
+		return nil, fmt.Errorf("获取嵌入向量失败: %w", err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("获取嵌入向量失败: 模型 %s 返回了空结果", embedder.ModelId)
+	}
+	embedder.recordUsage(ctx, resp.Usage.TotalTokens)
+
+	// 提供商可能返回模型的完整名称，未返回时使用请求的模型ID
+	modelUsed := resp.Model
+	if modelUsed == "" {
+		modelUsed = embedder.ModelId
+	}
+	embedding := resp.Data[0].Embedding
+	return &EmbeddingResult{
+		Embedding:    embedding,
+		Dimensions:   len(embedding),
+		ModelUsed:    modelUsed,
+		ProviderUsed: embedder.GetCurrentProviderName(),
+		TotalTokens:  resp.Usage.TotalTokens,
+	}, nil
+}
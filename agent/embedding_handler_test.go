@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// embeddingDimensions 模拟的嵌入模型及其输出维度
+var embeddingDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+// newEmbeddingServer 启动模拟嵌入接口的测试服务器，按请求的模型返回对应维度的向量；测试结束时关闭
+func newEmbeddingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		if r.URL.Path != "/embeddings" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		dims, ok := embeddingDimensions[req.Model]
+		if !ok || req.Input == "" {
+			w.Header().Set("x-should-retry", "false")
+			http.Error(w, `{"error":{"message":"unknown model"}}`, http.StatusNotFound)
+			return
+		}
+		embedding := make([]float64, dims)
+		for i := range embedding {
+			embedding[i] = float64(i%7) / 7
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"model":  req.Model,
+			"data":   []map[string]any{{"object": "embedding", "index": 0, "embedding": embedding}},
+			"usage":  map[string]any{"prompt_tokens": 4, "total_tokens": 4},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEmbeddingHandlerDimensions(t *testing.T) {
+	server := newEmbeddingServer(t)
+	config := newTestConfig(t, testProvider("mock", server.URL, "m1"))
+	config.EmbeddingModel = "text-embedding-3-small"
+	engine := newTestEngine(t, config)
+
+	tests := []struct {
+		name   string
+		params string
+		model  string
+	}{
+		{name: "纯文本使用 embedding_model", params: "你好", model: "text-embedding-3-small"},
+		{name: "JSON 指定模型", params: `{"text":"你好","model":"text-embedding-3-large"}`, model: "text-embedding-3-large"},
+		{name: "JSON 未指定模型", params: `{"text":"你好"}`, model: "text-embedding-3-small"},
+		{name: "ada", params: `{"text":"你好","model":"text-embedding-ada-002"}`, model: "text-embedding-ada-002"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, match, err := DispatchAndHandleTyped[*EmbeddingResult](engine, t.Context(), tt.params, "embed")
+			if err != nil || !match {
+				t.Fatalf("embed 事件处理失败: match=%v, err=%v", match, err)
+			}
+			want := embeddingDimensions[tt.model]
+			if len(result.Embedding) != want || result.Dimensions != want {
+				t.Errorf("向量长度 %d、Dimensions %d，期望 %s 的 %d 维", len(result.Embedding), result.Dimensions, tt.model, want)
+			}
+			if result.ModelUsed != tt.model || result.ProviderUsed != "mock" || result.TotalTokens != 4 {
+				t.Errorf("结果为 %s/%s、%d tokens，期望 mock/%s、4 tokens", result.ProviderUsed, result.ModelUsed, result.TotalTokens, tt.model)
+			}
+		})
+	}
+}
+
+func TestEmbeddingHandlerWithoutModel(t *testing.T) {
+	engine := newTestEngine(t, newTestConfig(t, testProvider("mock", newEmbeddingServer(t).URL, "m1")))
+	_, _, err := engine.DispatchAndHandle(t.Context(), "你好", "embed")
+	if !errors.Is(err, ErrNoEmbeddingModel) {
+		t.Errorf("错误为 %v，期望 ErrNoEmbeddingModel", err)
+	}
+}
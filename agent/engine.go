@@ -4,14 +4,26 @@ import (
 	"agent_engine/conf"
 	"context"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
 	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	// 处理器映射，根据事件类型查找对应的处理接口实现
+	// 处理器映射，根据事件类型查找对应的处理接口实现；运行中读写需持有 eventHandlerMu（见 HotSwapHandler）
 	eventHandlerMap = map[string]EventHandler{
 		"query": &QueryHandler{},
-		"list":  &ListHandler{}, // 列出所有提供商和模型
+		"list":  &ListHandler{},      // 列出所有提供商和模型
+		"embed": &EmbeddingHandler{}, // 获取文本的嵌入向量
+		"image": &ImageHandler{},     // 生成图片
 	}
 )
 
@@ -28,10 +40,49 @@ type Engine struct {
 	BaseUrl string `json:"base_url"` // 当前使用的基础URL
 
 	// 私有字段
-	apiKey       string       // 当前使用的API密钥（敏感信息）
-	configPath   string       // 配置文件路径
-	config       *conf.Config // 配置对象
-	providerName string       // 当前提供商名称
+	apiKey       string          // 当前使用的API密钥（敏感信息）
+	configPath   string          // 配置文件路径
+	config       *conf.Config    // 配置对象
+	providerName string          // 当前提供商名称
+	baseCtx      context.Context // 基础上下文，DispatchAndHandle 会将其与每次调用的上下文合并
+	lifecycle    *lifecycle      // 运行状态（所有副本共享）
+	errorLog     *errorRing      // 最近的错误记录（所有副本共享）
+	logger       *slog.Logger    // 日志记录器，为空时使用 slog.Default()
+	keyRotation  *keyRotator     // API 密钥轮换（所有副本共享），为空时使用 apiKey
+	usage        *usageTracker   // token 用量记录（所有副本共享）
+	requestLog   *requestLog     // 请求日志（所有副本共享）
+	statsFile    *statsStore     // 持久化的统计数据，如响应长度直方图和检查点（所有副本共享）
+	sessions     *sync.Map       // 活跃会话: 会话ID -> *ConversationSession（所有副本共享）
+
+	smartFallback bool                 // 模型调用失败后是否按相似度选择替代模型
+	debugMode     bool                 // 是否以 debug 级别记录内部决策和完整请求，见 WithDebugMode
+	stats         *latencyStats        // 模型调用延迟统计（所有副本共享）
+	throughput    *throughputCounter   // 查询吞吐量统计（所有副本共享）
+	roundRobin    *roundRobinCounter   // round-robin 负载均衡的轮询位置（所有副本共享）
+	selections    *selectionCounter    // 负载均衡选择初始模型的次数（所有副本共享）
+	eventBus      *EventBus            // 事件总线，为空时不发布事件
+	embeddings    *embeddingCache      // 嵌入向量缓存（所有副本共享）
+	checkpoints   *sync.Map            // 检查点中的查询钩子: 检查点名称 -> checkpointHooks（所有副本共享）
+	queryCache    *SemanticCache       // 查询结果缓存（所有副本共享），未配置 cache_type 时为空
+	conversation  *ConversationSession // 进行中的多轮对话，为空时每次查询互不相关，见 StartConversation
+	rateLimiters  *rateLimiters        // 按提供商 rate_limit 配置的令牌桶（所有副本共享）
+	responseCache Cache                // 按提供商、模型、系统提示和查询内容缓存查询结果（所有副本共享），未配置 cache_ttl 时为空
+	tracer        trace.Tracer         // 链路追踪（所有副本共享），未配置 telemetry.otlp_endpoint 时为 no-op
+	classifier    *QueryClassifier     // 按 classifier 配置编译的分类规则（所有副本共享），未配置 classifier.routes 时为空
+
+	batchConcurrency  int                              // QueryBatch 的最大并发查询数，小于等于 0 时使用 DefaultBatchConcurrency
+	handlerMiddleware map[string][]HandlerMiddleware   // 事件类型 -> 处理器中间件（先添加的在外层），副本之间不共享修改
+	globalTimeout     time.Duration                    // 每次分发请求的默认超时时间，小于等于 0 时不设置
+	metricsMiddleware HandlerMiddleware                // 为所有事件记录 Prometheus 指标的中间件，为空时不记录，见 WithMetrics
+	preQueryHooks     []prioritizedHook[PreQueryHook]  // 查询前置钩子，按优先级排列，副本之间不共享修改
+	postQueryHooks    []prioritizedHook[PostQueryHook] // 查询后置钩子，按优先级排列，副本之间不共享修改
+	concurrency       chan struct{}                    // 限制并发分发请求数的信号量，为空时不限制，见 WithMaxConcurrency
+	maxResponseTokens int                              // 每次查询最多生成的 token 数，小于等于 0 时使用 modelParams 或配置，见 WithMaxResponseTokens
+	systemPrompt      string                           // 覆盖提供商 system_prompt 的系统提示，为空时使用配置，见 WithSystemPrompt
+	providerFallback  bool                             // 当前提供商的模型都失败后是否按配置顺序切换到其他提供商，见 SetCrossProviderFallback
+	modelParams       conf.ModelParams                 // 覆盖提供商配置的生成参数，见 WithModelParams
+	httpClient        *http.Client                     // 调用模型接口使用的 HTTP 客户端，为空时使用默认传输，见 SetCustomTransport
+	responseCacheTTL  time.Duration                    // responseCache 中查询结果的有效期，见 WithResponseCache
 }
 
 // GetApiKey 获取 API 密钥（提供受控访问）
@@ -39,9 +90,31 @@ func (engine *Engine) GetApiKey() string {
 	return engine.apiKey
 }
 
+// newClient 使用当前提供商的 API 密钥和基础URL创建 OpenAI 客户端
+// 提供商配置了 api_version 时按 api_version_location 通过请求头或查询参数传递；请求上下文中的 Trace Context 会通过请求头转发
+// 提供商配置了 custom_endpoints 时改写对应接口的请求路径
+func (engine *Engine) newClient() openai.Client {
+	opts := []option.RequestOption{option.WithAPIKey(engine.GetApiKey()), option.WithBaseURL(engine.BaseUrl), option.WithHTTPClient(engine.modelHTTPClient())}
+	if engine.config != nil {
+		if provider, err := engine.config.GetProviderByName(engine.providerName); err == nil {
+			if provider.APIVersion != "" {
+				if provider.APIVersionLocation == conf.APIVersionQuery {
+					opts = append(opts, option.WithQuery("api-version", provider.APIVersion))
+				} else {
+					opts = append(opts, option.WithHeader("anthropic-version", provider.APIVersion))
+				}
+			}
+			if len(provider.CustomEndpoints) > 0 {
+				opts = append(opts, option.WithMiddleware(endpointMiddleware(engine.BaseUrl, provider.CustomEndpoints)))
+			}
+		}
+	}
+	return openai.NewClient(opts...)
+}
+
 // NewEngineFromConfig 从配置文件创建 Engine 实例
 // 参数:
-//   - configPath: 配置文件路径（支持相对路径和绝对路径）
+//   - configPath: 配置文件路径（支持相对路径、绝对路径和 github://owner/repo/path 地址）
 //   - providerName: 提供商名称，如果为空则使用默认提供商（第一个）
 //   - modelId: 模型ID，如果为空则使用提供商的默认模型（第一个）
 // 返回:
@@ -51,9 +124,14 @@ func NewEngineFromConfig(configPath string, providerName string, modelId string)
 	// 将配置文件路径转换为绝对路径
 	// 如果传入的是相对路径，会基于当前工作目录转换为绝对路径
 	// 如果传入的已经是绝对路径，则保持不变
-	absConfigPath, err := filepath.Abs(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("转换配置文件路径为绝对路径失败: %w", err)
+	// github:// 地址保持原样
+	absConfigPath := configPath
+	if !conf.IsGitHubURI(configPath) {
+		var err error
+		absConfigPath, err = filepath.Abs(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("转换配置文件路径为绝对路径失败: %w", err)
+		}
 	}
 
 	// 加载配置文件（使用原始路径加载，因为相对路径也能正常工作）
@@ -87,41 +165,248 @@ func NewEngineFromConfig(configPath string, providerName string, modelId string)
 			return nil, fmt.Errorf("获取默认模型失败: %w", err)
 		}
 	} else {
-		// 验证指定的模型是否存在
-		if !provider.HasModel(modelId) {
+		// 验证指定的模型是否存在（支持别名）
+		model, ok := provider.GetModel(modelId)
+		if !ok {
 			return nil, fmt.Errorf("提供商 %s 不支持模型 %s", provider.Name, modelId)
 		}
-		finalModelId = modelId
+		finalModelId = model.ID
 	}
 
+	return newEngine(config, absConfigPath, provider, finalModelId)
+}
+
+// newEngine 使用已加载的配置创建 Engine 实例，并按配置初始化所有副本共享的状态
+func newEngine(config *conf.Config, configPath string, provider *conf.ProviderConfig, modelId string) (*Engine, error) {
 	// 创建 Engine 实例
 	engine := &Engine{
-		ModelId:      finalModelId,
+		ModelId:      modelId,
 		BaseUrl:      provider.BaseUrl,
 		apiKey:       provider.ApiKey,
-		configPath:   absConfigPath, // 存储绝对路径
+		configPath:   configPath,
 		config:       config,
 		providerName: provider.Name,
+		baseCtx:      context.Background(),
+		lifecycle:    newLifecycle(),
+		errorLog:     newErrorRing(config.ErrorHistorySize),
+		usage:        newUsageTracker(config.UsageFile),
+		requestLog:   newRequestLog(config.RequestLogFile),
+		statsFile:    newStatsStore(config.StatsFile),
+		stats:        newLatencyStats(),
+		throughput:   newThroughputCounter(),
+		roundRobin:   newRoundRobinCounter(),
+		selections:   newSelectionCounter(),
+		embeddings:   newEmbeddingCache(),
+		sessions:     &sync.Map{},
+		checkpoints:  &sync.Map{},
+		rateLimiters: newRateLimiters(),
+	}
+
+	// 按 telemetry.otlp_endpoint 启用链路追踪
+	tracer, err := engine.newTracer(config.Telemetry)
+	if err != nil {
+		return nil, err
+	}
+	engine.tracer = tracer
+
+	// 配置了查询路由时预先编译分类规则，避免每次查询重复编译正则表达式
+	if len(config.Classifier.Routes) > 0 {
+		classifier, err := NewQueryClassifier(&config.Classifier)
+		if err != nil {
+			return nil, fmt.Errorf("创建查询分类器失败: %w", err)
+		}
+		engine.classifier = classifier
+	}
+
+	// 按 cache_ttl 启用精确匹配的查询结果缓存
+	if config.CacheTTL > 0 {
+		engine.responseCache = NewMemoryCache()
+		engine.responseCacheTTL = config.CacheTTL
+	}
+
+	// 按 cache_type 启用查询结果缓存
+	switch config.CacheType {
+	case "":
+	case conf.CacheTypeSemantic:
+		if config.EmbeddingModel == "" {
+			return nil, fmt.Errorf("cache_type 为 %s 时必须设置 embedding_model", conf.CacheTypeSemantic)
+		}
+		engine.queryCache = NewSemanticCache(config.CacheSimilarityThreshold, engine.GetEmbedding)
+	default:
+		return nil, fmt.Errorf("不支持的 cache_type: %s", config.CacheType)
 	}
 
+	// 关闭时刷新请求日志、用量统计和缓存
+	engine.registerFlushHooks()
+
 	return engine, nil
 }
 
+// Clone 复制一个 Engine 实例
+// 副本拥有独立的提供商和模型选择状态，对副本调用 SwitchProvider/SwitchModel 不会影响原 Engine
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) Clone() *Engine {
+	clone := *engine
+	return &clone
+}
+
+// CopyWithModel 返回使用指定模型的 Engine 副本，不修改原 Engine
+// 适用于并发处理请求时每个请求使用不同模型的场景
+// 参数:
+//   - modelId: 模型ID，必须属于当前提供商
+// 返回:
+//   - *Engine: Engine 副本指针
+//   - error: 模型不存在时返回错误
+func (engine *Engine) CopyWithModel(modelId string) (*Engine, error) {
+	clone := engine.Clone()
+	if err := clone.SwitchModel(modelId); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// copyForModel 返回使用指定模型的 Engine 副本
+// 优先在当前提供商中查找模型，找不到时按配置顺序在其他提供商中查找
+func (engine *Engine) copyForModel(modelId string) (*Engine, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	if clone, err := engine.CopyWithModel(modelId); err == nil {
+		return clone, nil
+	}
+	for _, p := range engine.config.Provider {
+		if p.HasModel(modelId) {
+			clone := engine.Clone()
+			if err := clone.SwitchProvider(p.Name, modelId); err != nil {
+				return nil, err
+			}
+			return clone, nil
+		}
+	}
+	return nil, fmt.Errorf("所有提供商均不支持模型 %s", modelId)
+}
+
+// WithContext 返回以 ctx 作为基础上下文的 Engine 副本
+// 基础上下文中的值（如链路追踪信息）和取消信号会作用于副本的所有操作
+// 参数:
+//   - ctx: 基础上下文
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithContext(ctx context.Context) *Engine {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	clone := engine.Clone()
+	clone.baseCtx = ctx
+	return clone
+}
+
+// WithLogger 返回使用指定 logger 的 Engine 副本
+// logger 会通过上下文传递给处理器，处理器使用 LoggerFromContext 获取
+// 参数:
+//   - l: 日志记录器
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithLogger(l *slog.Logger) *Engine {
+	clone := engine.Clone()
+	clone.logger = l
+	return clone
+}
+
+// withLogger 上下文中没有 logger 时注入 Engine 的 logger
+func (engine *Engine) withLogger(ctx context.Context) context.Context {
+	if engine.logger == nil {
+		return ctx
+	}
+	if _, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return ctx
+	}
+	return ContextWithLogger(ctx, engine.logger)
+}
+
+// loggerFrom 获取上下文中的 logger，上下文未设置时使用 Engine 的 logger
+func (engine *Engine) loggerFrom(ctx context.Context) *slog.Logger {
+	return LoggerFromContext(engine.withLogger(ctx))
+}
+
+// baseContext 获取 Engine 的基础上下文，未设置时返回 context.Background()
+func (engine *Engine) baseContext() context.Context {
+	if engine.baseCtx == nil {
+		return context.Background()
+	}
+	return engine.baseCtx
+}
+
+// mergedContext 合并后的上下文：截止时间和取消信号取自两者中更早的一方，值优先从调用上下文查找
+type mergedContext struct {
+	context.Context
+	base context.Context
+}
+
+// Value 优先从调用上下文查找值，找不到时再从基础上下文查找
+func (c mergedContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.base.Value(key)
+}
+
+// mergeContext 合并基础上下文和调用上下文
+// 参数:
+//   - base: 基础上下文
+//   - call: 调用上下文
+// 返回:
+//   - context.Context: 合并后的上下文
+//   - context.CancelFunc: 释放合并上下文资源的函数
+func mergeContext(base context.Context, call context.Context) (context.Context, context.CancelFunc) {
+	if base == nil || base == context.Background() {
+		return call, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(call)
+	cancelDeadline := context.CancelFunc(func() {})
+	if deadline, ok := base.Deadline(); ok {
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+	}
+	// 基础上下文被取消时同步取消合并上下文
+	stop := context.AfterFunc(base, func() {
+		cancel(context.Cause(base))
+	})
+
+	merged := mergedContext{Context: ctx, base: context.WithoutCancel(base)}
+	return merged, func() {
+		stop()
+		cancelDeadline()
+		cancel(context.Canceled)
+	}
+}
+
 // GetAvailableProviders 获取所有可用的提供商列表
 // 返回:
-//   - []string: 提供商名称列表
+//   - []string: 提供商名称列表，按配置文件中的顺序排列
 //   - error: 错误信息
 func (engine *Engine) GetAvailableProviders() ([]string, error) {
 	if engine.config == nil {
 		return nil, fmt.Errorf("配置未加载")
 	}
+	return engine.GetAllProviderNames(), nil
+}
 
-	providers := make([]string, 0, len(engine.config.Provider))
-	for _, p := range engine.config.Provider {
-		providers = append(providers, p.Name)
+// GetAllProviderNames 获取所有提供商名称
+// 顺序与配置文件中 provider 列表的顺序一致（第一个为默认提供商），依赖顺序的轮询和优先级选择可直接使用
+// 返回:
+//   - []string: 提供商名称列表，配置未加载时为空
+func (engine *Engine) GetAllProviderNames() []string {
+	if engine.config == nil {
+		return nil
 	}
 
-	return providers, nil
+	names := make([]string, 0, len(engine.config.Provider))
+	for _, p := range engine.config.Provider {
+		names = append(names, p.Name)
+	}
+	return names
 }
 
 // GetAvailableModels 获取当前提供商的所有可用模型列表
@@ -139,7 +424,7 @@ func (engine *Engine) GetAvailableModels() ([]string, error) {
 		return nil, fmt.Errorf("获取当前提供商配置失败: %w", err)
 	}
 
-	return provider.Model, nil
+	return provider.ModelIDs(), nil
 }
 
 // GetAllModels 获取所有提供商的所有模型列表（带提供商信息）
@@ -153,7 +438,7 @@ func (engine *Engine) GetAllModels() (map[string][]string, error) {
 
 	allModels := make(map[string][]string)
 	for _, p := range engine.config.Provider {
-		allModels[p.Name] = p.Model
+		allModels[p.Name] = p.ModelIDs()
 	}
 
 	return allModels, nil
@@ -185,11 +470,12 @@ func (engine *Engine) SwitchProvider(providerName string, modelId string) error
 			return fmt.Errorf("获取默认模型失败: %w", err)
 		}
 	} else {
-		// 验证指定的模型是否存在
-		if !provider.HasModel(modelId) {
+		// 验证指定的模型是否存在（支持别名）
+		model, ok := provider.GetModel(modelId)
+		if !ok {
 			return fmt.Errorf("提供商 %s 不支持模型 %s", provider.Name, modelId)
 		}
-		finalModelId = modelId
+		finalModelId = model.ID
 	}
 
 	// 更新 Engine 配置
@@ -217,17 +503,56 @@ func (engine *Engine) SwitchModel(modelId string) error {
 		return fmt.Errorf("获取当前提供商配置失败: %w", err)
 	}
 
-	// 验证模型是否存在
-	if !provider.HasModel(modelId) {
+	// 验证模型是否存在（支持别名）
+	model, ok := provider.GetModel(modelId)
+	if !ok {
 		return fmt.Errorf("当前提供商 %s 不支持模型 %s", engine.providerName, modelId)
 	}
 
 	// 更新模型ID
-	engine.ModelId = modelId
+	engine.ModelId = model.ID
 
 	return nil
 }
 
+// ResolveAlias 在当前提供商中查找别名对应的模型ID
+// 参数:
+//   - alias: 模型别名（不区分大小写）
+// 返回:
+//   - string: 模型ID
+//   - error: 当前提供商没有模型使用该别名时返回错误
+func (engine *Engine) ResolveAlias(alias string) (string, error) {
+	if engine.config == nil {
+		return "", fmt.Errorf("配置未加载")
+	}
+
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return "", fmt.Errorf("获取当前提供商配置失败: %w", err)
+	}
+
+	for i := range provider.Models {
+		if provider.Models[i].HasAlias(alias) {
+			return provider.Models[i].ID, nil
+		}
+	}
+	return "", fmt.Errorf("当前提供商 %s 没有别名为 %s 的模型", engine.providerName, alias)
+}
+
+// SwitchModelByAlias 按别名在当前提供商下切换模型
+// 参数:
+//   - alias: 模型别名（不区分大小写）
+// 返回:
+//   - error: 错误信息
+func (engine *Engine) SwitchModelByAlias(alias string) error {
+	modelId, err := engine.ResolveAlias(alias)
+	if err != nil {
+		return err
+	}
+	engine.ModelId = modelId
+	return nil
+}
+
 // GetCurrentProviderName 获取当前提供商名称
 // 返回:
 //   - string: 提供商名称
@@ -235,6 +560,30 @@ func (engine *Engine) GetCurrentProviderName() string {
 	return engine.providerName
 }
 
+// GetCurrentModelConfig 获取当前模型的完整配置（别名、最大上下文、权重、能力、每日配额等）
+// 返回的是副本，修改不会影响 Engine 的配置
+// 返回:
+//   - *conf.ModelConfig: 模型配置副本指针
+//   - error: 配置未加载或当前模型不在提供商的模型列表中时返回错误
+func (engine *Engine) GetCurrentModelConfig() (*conf.ModelConfig, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return nil, fmt.Errorf("获取当前提供商配置失败: %w", err)
+	}
+	model, ok := provider.GetModel(engine.ModelId)
+	if !ok {
+		return nil, fmt.Errorf("提供商 %s 的模型列表中没有模型 %s", provider.Name, engine.ModelId)
+	}
+
+	copied := *model
+	copied.Aliases = slices.Clone(model.Aliases)
+	copied.Capabilities = maps.Clone(model.Capabilities)
+	return &copied, nil
+}
+
 // GetConfigPath 获取配置文件路径
 // 返回:
 //   - string: 配置文件绝对路径
@@ -242,7 +591,7 @@ func (engine *Engine) GetConfigPath() string {
 	return engine.configPath
 }
 
-// GetAllProvidersInfo 获取所有提供商的详细信息（包括 base_url）
+// GetAllProvidersInfo 获取所有提供商的详细信息（包括 base_url 和 metadata）
 // 返回:
 //   - map[string]*conf.ProviderConfig: 提供商名称到配置的映射
 //   - error: 错误信息
@@ -260,6 +609,28 @@ func (engine *Engine) GetAllProvidersInfo() (map[string]*conf.ProviderConfig, er
 	return providersInfo, nil
 }
 
+// GetProvidersByMetadata 获取 metadata 中 key 的值等于 value 的提供商，适用于多个团队共用配置文件时只展示各自的提供商
+// 参数:
+//   - key: metadata 键，如 team
+//   - value: metadata 值
+// 返回:
+//   - []*conf.ProviderConfig: 匹配的提供商配置，按配置顺序排列，没有匹配时为空
+//   - error: 配置未加载时返回错误
+func (engine *Engine) GetProvidersByMetadata(key, value string) ([]*conf.ProviderConfig, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+
+	var providers []*conf.ProviderConfig
+	for i := range engine.config.Provider {
+		p := &engine.config.Provider[i]
+		if v, ok := p.Metadata[key]; ok && v == value {
+			providers = append(providers, p)
+		}
+	}
+	return providers, nil
+}
+
 // DispatchAndHandle 分发和处理
 // 参数:
 //   - ctx: 上下文
@@ -270,11 +641,94 @@ func (engine *Engine) GetAllProvidersInfo() (map[string]*conf.ProviderConfig, er
 //   - match: 是否匹配到处理器
 //   - err: 错误信息
 func (engine *Engine) DispatchAndHandle(ctx context.Context, params string, event string) (rsp any, match bool, err error) {
-	match = false
-	if handler, ok := eventHandlerMap[event]; ok {
-		match = true
-		rsp, err = handler.Handle(ctx, engine, params, event)
-		return
-	}
-	return
-}
\ No newline at end of file
+	return engine.dispatch(ctx, event, func(ctx context.Context, handler EventHandler) (any, error) {
+		return handler.Handle(ctx, engine, params, event)
+	})
+}
+
+// DispatchAndHandleTyped 分发和处理，并将结果断言为类型 T
+// 参数:
+//   - engine: Engine 实例
+//   - ctx: 上下文
+//   - params: 参数字符串
+//   - event: 事件类型
+// 返回:
+//   - T: 响应数据，类型不匹配时为零值
+//   - bool: 是否匹配到处理器
+//   - error: 处理失败或结果类型不是 T 时返回错误
+func DispatchAndHandleTyped[T any](engine *Engine, ctx context.Context, params string, event string) (T, bool, error) {
+	var zero T
+	rsp, match, err := engine.DispatchAndHandle(ctx, params, event)
+	if err != nil || !match {
+		return zero, match, err
+	}
+	typed, ok := rsp.(T)
+	if !ok {
+		return zero, match, fmt.Errorf("事件 %s 返回的结果类型为 %T，期望 %T", event, rsp, zero)
+	}
+	return typed, match, nil
+}
+
+// QueryAndHandle 分发 query 事件并返回 *QueryResult
+func QueryAndHandle(engine *Engine, ctx context.Context, params string) (*QueryResult, bool, error) {
+	return DispatchAndHandleTyped[*QueryResult](engine, ctx, params, "query")
+}
+
+// ListAndHandle 分发 list 事件并返回 *ListResult
+func ListAndHandle(engine *Engine, ctx context.Context, params string) (*ListResult, bool, error) {
+	return DispatchAndHandleTyped[*ListResult](engine, ctx, params, "list")
+}
+
+// DispatchRequest 使用结构化请求分发和处理
+// 未实现 EventHandlerV2 的处理器会通过 AdaptEventHandler 适配
+// 参数:
+//   - ctx: 上下文
+//   - req: 结构化请求
+//   - event: 事件类型
+// 返回:
+//   - rsp: 响应数据
+//   - match: 是否匹配到处理器
+//   - err: 错误信息
+func (engine *Engine) DispatchRequest(ctx context.Context, req *QueryRequest, event string) (rsp any, match bool, err error) {
+	return engine.dispatch(ctx, event, func(ctx context.Context, handler EventHandler) (any, error) {
+		return AdaptEventHandler(handler).HandleRequest(ctx, engine, req, event)
+	})
+}
+
+// dispatch 查找事件对应的处理器并调用 handle
+// 负责登记进行中的请求，以及合并基础上下文和本次调用的上下文
+func (engine *Engine) dispatch(ctx context.Context, event string, handle func(ctx context.Context, handler EventHandler) (any, error)) (rsp any, match bool, err error) {
+	handler, ok := lookupEventHandler(event)
+	if !ok {
+		return nil, false, nil
+	}
+	handler = engine.wrapHandler(event, handler)
+	match = true
+
+	// Engine 关闭后不再接受新请求
+	if engine.lifecycle != nil {
+		if err = engine.lifecycle.acquire(); err != nil {
+			return nil, match, err
+		}
+		defer engine.lifecycle.release()
+	}
+
+	// 调用上下文没有截止时间时应用默认超时，再合并基础上下文和本次调用的上下文
+	ctx, cancelTimeout := engine.withGlobalTimeout(ctx)
+	defer cancelTimeout()
+	ctx, cancel := mergeContext(engine.baseCtx, ctx)
+	defer cancel()
+	ctx = withCorrelation(engine.withLogger(ctx))
+	ctx, span := engine.startSpan(ctx, "agent_engine.dispatch", AttrEventType.String(event))
+	defer func() { endSpan(span, err) }()
+
+	entry := engine.startRequestLog(ctx, event)
+	// 设置了最大并发数时，等待并发名额后再处理
+	release, err := engine.acquireConcurrency(ctx)
+	if err == nil {
+		rsp, err = handle(ctx, handler)
+		release()
+	}
+	engine.logRequest(ctx, entry, err)
+	return rsp, match, err
+}
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"agent_engine/conf"
+)
+
+// chatServer 模拟对话补全接口的测试服务器，记录收到的请求
+type chatServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	bodies  [][]byte      // 收到的请求体，按到达顺序排列
+	headers []http.Header // 收到的请求头，与 bodies 一一对应
+}
+
+// newChatServer 启动模拟对话补全接口的测试服务器，handle 返回 HTTP 状态码和回复内容；测试结束时关闭
+func newChatServer(t *testing.T, handle func(call int) (status int, reply string)) *chatServer {
+	t.Helper()
+	s := &chatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		s.headers = append(s.headers, r.Header.Clone())
+		call := len(s.bodies)
+		s.mu.Unlock()
+
+		status, reply := handle(call)
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			// 禁止 SDK 自动重试，尝试次数只由 QueryHandler 决定
+			w.Header().Set("x-should-retry", "false")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": reply, "type": "server_error"}})
+			return
+		}
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.Unmarshal(body, &req)
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": 0,
+			"model":   req.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": reply},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]any{"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5},
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// calls 返回收到的请求数
+func (s *chatServer) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+// lastBody 返回最后一次收到的请求体
+func (s *chatServer) lastBody(t *testing.T) map[string]any {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.bodies) == 0 {
+		t.Fatal("服务器没有收到请求")
+	}
+	var body map[string]any
+	if err := json.Unmarshal(s.bodies[len(s.bodies)-1], &body); err != nil {
+		t.Fatalf("解析请求体失败: %v", err)
+	}
+	return body
+}
+
+// lastHeader 返回最后一次收到的请求头
+func (s *chatServer) lastHeader(t *testing.T) http.Header {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.headers) == 0 {
+		t.Fatal("服务器没有收到请求")
+	}
+	return s.headers[len(s.headers)-1]
+}
+
+// testProvider 返回使用 baseURL 和指定模型的提供商配置
+func testProvider(name, baseURL string, models ...string) conf.ProviderConfig {
+	p := conf.ProviderConfig{Name: name, ApiKey: "test-key", BaseUrl: baseURL}
+	for _, id := range models {
+		p.Models = append(p.Models, conf.ModelConfig{ID: id})
+	}
+	return p
+}
+
+// newTestConfig 返回包含指定提供商的配置，请求日志、用量、统计和会话文件都写入测试的临时目录
+func newTestConfig(t *testing.T, providers ...conf.ProviderConfig) *conf.Config {
+	t.Helper()
+	dir := t.TempDir()
+	return &conf.Config{
+		Provider:       providers,
+		UsageFile:      filepath.Join(dir, "usage.json"),
+		RequestLogFile: filepath.Join(dir, "requests.jsonl"),
+		StatsFile:      filepath.Join(dir, "stats.json"),
+		SessionDir:     filepath.Join(dir, "sessions"),
+	}
+}
+
+// newTestEngine 使用配置中的第一个提供商及其第一个模型创建 Engine，测试结束时关闭
+func newTestEngine(t *testing.T, config *conf.Config) *Engine {
+	t.Helper()
+	provider := &config.Provider[0]
+	engine, err := newEngine(config, "", provider, provider.Models[0].ID)
+	if err != nil {
+		t.Fatalf("创建 Engine 失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := engine.Shutdown(context.Background()); err != nil {
+			t.Errorf("关闭 Engine 失败: %v", err)
+		}
+	})
+	return engine
+}
+
+func TestGetAllProviderNamesConfigOrder(t *testing.T) {
+	// 名称故意不按字母顺序排列，确认返回的是配置顺序
+	config := newTestConfig(t,
+		testProvider("zeta", "http://127.0.0.1", "z1"),
+		testProvider("alpha", "http://127.0.0.1", "a1"),
+		testProvider("mid", "http://127.0.0.1", "m1"),
+	)
+	engine := newTestEngine(t, config)
+	assertOrder := func(want ...string) {
+		t.Helper()
+		if got := engine.GetAllProviderNames(); !slices.Equal(got, want) {
+			t.Errorf("GetAllProviderNames() = %v，期望 %v", got, want)
+		}
+		if got, err := engine.GetAvailableProviders(); err != nil || !slices.Equal(got, want) {
+			t.Errorf("GetAvailableProviders() = %v, %v，期望 %v", got, err, want)
+		}
+	}
+	assertOrder("zeta", "alpha", "mid")
+
+	// 修改返回的切片不影响之后的结果
+	names := engine.GetAllProviderNames()
+	slices.Reverse(names)
+	assertOrder("zeta", "alpha", "mid")
+
+	// 切换提供商不改变顺序
+	if err := engine.SwitchProvider("mid", ""); err != nil {
+		t.Fatalf("切换提供商失败: %v", err)
+	}
+	assertOrder("zeta", "alpha", "mid")
+
+	// 修改配置后按新的配置顺序返回
+	config.Provider = append(config.Provider, testProvider("beta", "http://127.0.0.1", "b1"))
+	assertOrder("zeta", "alpha", "mid", "beta")
+	config.Provider[1].Name = "omega"
+	assertOrder("zeta", "omega", "mid", "beta")
+	config.Provider = slices.Delete(config.Provider, 0, 1)
+	assertOrder("omega", "mid", "beta")
+	config.Provider[0], config.Provider[2] = config.Provider[2], config.Provider[0]
+	assertOrder("beta", "mid", "omega")
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// DefaultErrorHistorySize 默认保留的错误记录数量
+const DefaultErrorHistorySize = 100
+
+// EngineError 一次模型调用失败的记录
+type EngineError struct {
+	Time          time.Time `json:"time"`           // 发生时间
+	Provider      string    `json:"provider"`       // 提供商名称
+	Model         string    `json:"model"`          // 模型ID
+	AttemptNumber int       `json:"attempt_number"` // 第几次尝试
+	ErrorMsg      string    `json:"error_msg"`      // 错误信息
+	StatusCode    int       `json:"status_code"`    // HTTP 状态码（非 API 错误时为 0）
+}
+
+// errorRing 固定容量的错误记录环形缓冲区，写满后覆盖最旧的记录
+type errorRing struct {
+	mu      sync.Mutex
+	entries []EngineError
+	next    int  // 下一次写入的位置
+	full    bool // 是否已写满一轮
+}
+
+// newErrorRing 创建指定容量的错误记录缓冲区
+func newErrorRing(capacity int) *errorRing {
+	if capacity <= 0 {
+		capacity = DefaultErrorHistorySize
+	}
+	return &errorRing{entries: make([]EngineError, capacity)}
+}
+
+// add 写入一条错误记录
+func (r *errorRing) add(e EngineError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// recent 返回最近的 n 条记录，按时间从新到旧排列
+func (r *errorRing) recent(n int) []EngineError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	size := r.next
+	if r.full {
+		size = len(r.entries)
+	}
+	if n <= 0 || n > size {
+		n = size
+	}
+
+	result := make([]EngineError, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := (r.next - i + len(r.entries)) % len(r.entries)
+		result = append(result, r.entries[idx])
+	}
+	return result
+}
+
+// statusCodeOf 从 API 错误中提取 HTTP 状态码，非 API 错误返回 0
+func statusCodeOf(err error) int {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// recordError 记录一次模型调用失败
+// 参数:
+//   - attempt: 第几次尝试
+//   - err: 调用返回的错误
+func (engine *Engine) recordError(attempt int, err error) {
+	if err == nil {
+		return
+	}
+	if engine.stats != nil {
+		engine.stats.recordFailure(engine.GetCurrentProviderName(), engine.ModelId)
+	}
+	if engine.errorLog == nil {
+		return
+	}
+	engine.errorLog.add(EngineError{
+		Time:          time.Now(),
+		Provider:      engine.GetCurrentProviderName(),
+		Model:         engine.ModelId,
+		AttemptNumber: attempt,
+		ErrorMsg:      err.Error(),
+		StatusCode:    statusCodeOf(err),
+	})
+}
+
+// ListRecentErrors 获取最近的模型调用错误记录
+// 参数:
+//   - n: 返回的记录数量，小于等于 0 或超过已有数量时返回全部
+// 返回:
+//   - []EngineError: 错误记录，按时间从新到旧排列
+func (engine *Engine) ListRecentErrors(n int) []EngineError {
+	if engine.errorLog == nil {
+		return nil
+	}
+	return engine.errorLog.recent(n)
+}
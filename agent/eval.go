@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 评测类型
+const (
+	EvalExact    = "exact"    // 回复与期望完全一致（忽略首尾空白）
+	EvalContains = "contains" // 回复包含期望内容（默认）
+	EvalRegex    = "regex"    // 回复匹配期望的正则表达式
+	EvalLLM      = "llm"      // 由评判模型判断回复是否正确
+)
+
+// evalJudgePrompt llm 类型评测使用的评判提示词
+const evalJudgePrompt = `你是一个严格的评测员。请判断"回答"是否正确地满足了"期望"。
+只回复 PASS 或 FAIL，不要输出其他内容。
+
+问题：%s
+
+期望：%s
+
+回答：%s`
+
+// EvalCase 评测套件中的一条用例（JSONL 文件中的一行）
+type EvalCase struct {
+	Query    string `json:"query"`     // 查询内容
+	Expected string `json:"expected"`  // 期望结果
+	EvalType string `json:"eval_type"` // 评测类型: exact|contains|regex|llm，为空时使用 contains
+}
+
+// EvalEntry 单条用例的评测结果
+type EvalEntry struct {
+	Line     int    `json:"line"`            // 用例在文件中的行号
+	Query    string `json:"query"`           // 查询内容
+	Expected string `json:"expected"`        // 期望结果
+	EvalType string `json:"eval_type"`       // 评测类型
+	Reply    string `json:"reply"`           // 模型回复
+	Passed   bool   `json:"passed"`          // 是否通过
+	Error    string `json:"error,omitempty"` // 查询或评测出错时的错误信息
+}
+
+// EvalReport 评测报告
+type EvalReport struct {
+	Total    int         `json:"total"`     // 用例总数
+	Passed   int         `json:"passed"`    // 通过数
+	Failed   int         `json:"failed"`    // 失败数（包括出错的用例）
+	Results  []EvalEntry `json:"results"`   // 每条用例的结果
+	PassRate float64     `json:"pass_rate"` // 通过率（0~1）
+}
+
+// RunEvalSuite 运行评测套件
+// 参数:
+//   - ctx: 上下文
+//   - suitePath: JSONL 格式的评测套件文件路径
+// 返回:
+//   - *EvalReport: 评测报告
+//   - error: 读取或解析套件文件失败时返回错误，单条用例的失败记录在报告中
+func (engine *Engine) RunEvalSuite(ctx context.Context, suitePath string) (*EvalReport, error) {
+	cases, err := loadEvalSuite(suitePath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EvalReport{Results: make([]EvalEntry, 0, len(cases))}
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("评测被取消: %w", err)
+		}
+
+		entry := engine.runEvalCase(ctx, c)
+		report.Total++
+		if entry.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, entry)
+	}
+
+	if report.Total > 0 {
+		report.PassRate = float64(report.Passed) / float64(report.Total)
+	}
+	engine.loggerFrom(ctx).Info("评测完成", "total", report.Total, "passed", report.Passed, "pass_rate", report.PassRate)
+	return report, nil
+}
+
+// evalSuiteCase 带行号的评测用例
+type evalSuiteCase struct {
+	EvalCase
+	line int
+}
+
+// loadEvalSuite 读取 JSONL 格式的评测套件，空行会被跳过
+func loadEvalSuite(suitePath string) ([]evalSuiteCase, error) {
+	file, err := os.Open(suitePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开评测套件失败: %w", err)
+	}
+	defer file.Close()
+
+	var cases []evalSuiteCase
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var c EvalCase
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("解析评测套件第 %d 行失败: %w", line, err)
+		}
+		if c.EvalType == "" {
+			c.EvalType = EvalContains
+		}
+		cases = append(cases, evalSuiteCase{EvalCase: c, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取评测套件失败: %w", err)
+	}
+	return cases, nil
+}
+
+// runEvalCase 执行单条用例并评测结果
+func (engine *Engine) runEvalCase(ctx context.Context, c evalSuiteCase) EvalEntry {
+	entry := EvalEntry{Line: c.line, Query: c.Query, Expected: c.Expected, EvalType: c.EvalType}
+
+	result, err := engine.Query(ctx, c.Query)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Reply = result.Reply
+
+	passed, err := engine.evaluate(ctx, c.EvalCase, result.Reply)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Passed = passed
+	return entry
+}
+
+// evaluate 根据评测类型判断回复是否通过
+func (engine *Engine) evaluate(ctx context.Context, c EvalCase, reply string) (bool, error) {
+	switch c.EvalType {
+	case EvalExact:
+		return strings.TrimSpace(reply) == strings.TrimSpace(c.Expected), nil
+	case EvalContains:
+		return strings.Contains(reply, c.Expected), nil
+	case EvalRegex:
+		re, err := regexp.Compile(c.Expected)
+		if err != nil {
+			return false, fmt.Errorf("编译期望的正则表达式失败: %w", err)
+		}
+		return re.MatchString(reply), nil
+	case EvalLLM:
+		return engine.judgeWithLLM(ctx, c, reply)
+	default:
+		return false, fmt.Errorf("未知的评测类型: %s", c.EvalType)
+	}
+}
+
+// judgeWithLLM 使用配置的 eval_model 判断回复是否正确
+func (engine *Engine) judgeWithLLM(ctx context.Context, c EvalCase, reply string) (bool, error) {
+	if engine.config == nil || engine.config.EvalModel == "" {
+		return false, fmt.Errorf("llm 类型评测需要在配置文件中设置 eval_model")
+	}
+
+	judge, err := engine.copyForModel(engine.config.EvalModel)
+	if err != nil {
+		return false, fmt.Errorf("获取评判模型失败: %w", err)
+	}
+	// 评判只使用指定的模型，不进行路由
+	result, err := judge.QueryRequest(ctx, &QueryRequest{
+		Query:         fmt.Sprintf(evalJudgePrompt, c.Query, c.Expected, reply),
+		OverrideModel: judge.ModelId,
+	})
+	if err != nil {
+		return false, fmt.Errorf("评判模型调用失败: %w", err)
+	}
+
+	verdict := strings.ToUpper(strings.TrimSpace(result.Reply))
+	return strings.HasPrefix(verdict, "PASS"), nil
+}
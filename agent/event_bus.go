@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 引擎事件类型
+const (
+	EventQueryStarted         = "QueryStarted"         // 开始处理查询，Data 为 QueryEventData
+	EventQuerySucceeded       = "QuerySucceeded"       // 查询成功，Data 为 QueryEventData
+	EventQueryFailed          = "QueryFailed"          // 查询失败，Data 为 QueryEventData
+	EventModelSwitched        = "ModelSwitched"        // 处理查询时切换了模型，Data 为 SwitchEventData
+	EventProviderSwitched     = "ProviderSwitched"     // 处理查询时切换了提供商，Data 为 SwitchEventData
+	EventCacheHit             = "CacheHit"             // 命中响应缓存（为缓存功能预留，当前不会发布）
+	EventCircuitBreakerOpened = "CircuitBreakerOpened" // 熔断器打开（为熔断功能预留，当前不会发布）
+
+	// EventAll 订阅所有类型的事件
+	EventAll = "*"
+)
+
+// Event 引擎事件
+type Event struct {
+	Type      string    `json:"type"`      // 事件类型
+	Timestamp time.Time `json:"timestamp"` // 发生时间
+	Data      any       `json:"data"`      // 事件数据，类型见各事件类型的说明
+}
+
+// QueryEventData 查询相关事件的数据
+type QueryEventData struct {
+	Query         string        `json:"query"`                    // 查询内容
+	Provider      string        `json:"provider"`                 // 提供商名称
+	Model         string        `json:"model"`                    // 模型ID（失败时为最后尝试的模型）
+	CorrelationID string        `json:"correlation_id,omitempty"` // 请求关联ID
+	Attempts      int           `json:"attempts,omitempty"`       // 尝试次数（QueryStarted 时为 0）
+	Duration      time.Duration `json:"duration,omitempty"`       // 处理耗时（QueryStarted 时为 0）
+	Error         string        `json:"error,omitempty"`          // 错误信息（仅 QueryFailed）
+}
+
+// SwitchEventData 模型/提供商切换事件的数据
+type SwitchEventData struct {
+	From          string `json:"from"`                     // 切换前的模型ID或提供商名称
+	To            string `json:"to"`                       // 切换后的模型ID或提供商名称
+	Provider      string `json:"provider"`                 // 切换后的提供商名称
+	Reason        string `json:"reason"`                   // 切换原因: override、route、load_balancing、failover
+	CorrelationID string `json:"correlation_id,omitempty"` // 请求关联ID
+}
+
+// UnsubscribeFunc 取消订阅的函数，重复调用无副作用
+type UnsubscribeFunc func()
+
+// subscription 单个订阅
+type subscription struct {
+	id int
+	fn func(Event)
+}
+
+// EventBus 将引擎事件分发给订阅者，可在多个 Engine 之间共享
+type EventBus struct {
+	mu     sync.RWMutex
+	nextId int
+	subs   map[string][]subscription // 事件类型 -> 订阅列表
+}
+
+// NewEventBus 创建事件总线
+// 返回:
+//   - *EventBus: 事件总线指针
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string][]subscription)}
+}
+
+// Subscribe 订阅指定类型的事件
+// 参数:
+//   - eventType: 事件类型，EventAll 表示订阅所有事件
+//   - fn: 事件回调，在 Publish 的调用方 goroutine 中同步执行，耗时操作应自行异步处理
+// 返回:
+//   - UnsubscribeFunc: 取消订阅的函数
+func (b *EventBus) Subscribe(eventType string, fn func(Event)) UnsubscribeFunc {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextId++
+	id := b.nextId
+	b.subs[eventType] = append(b.subs[eventType], subscription{id: id, fn: fn})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			subs := b.subs[eventType]
+			for i, s := range subs {
+				if s.id == id {
+					b.subs[eventType] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// Publish 发布事件，按订阅顺序依次调用该类型的订阅者，再调用订阅了所有事件的订阅者
+// Timestamp 为空时使用当前时间
+// 参数:
+//   - e: 事件
+func (b *EventBus) Publish(e Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	// 复制订阅列表后再调用，允许回调中订阅或取消订阅
+	b.mu.RLock()
+	subs := make([]subscription, 0, len(b.subs[e.Type])+len(b.subs[EventAll]))
+	subs = append(subs, b.subs[e.Type]...)
+	if e.Type != EventAll {
+		subs = append(subs, b.subs[EventAll]...)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.fn(e)
+	}
+}
+
+// WithEventBus 返回使用指定事件总线的 Engine 副本
+// 参数:
+//   - bus: 事件总线，为空时不发布事件
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithEventBus(bus *EventBus) *Engine {
+	clone := engine.Clone()
+	clone.eventBus = bus
+	return clone
+}
+
+// publish 向 Engine 的事件总线发布事件，未设置事件总线时忽略
+func (engine *Engine) publish(eventType string, data any) {
+	if engine.eventBus == nil {
+		return
+	}
+	engine.eventBus.Publish(Event{Type: eventType, Timestamp: time.Now(), Data: data})
+}
+
+// publishSwitch 发布模型或提供商切换事件，切换前后相同时忽略
+func (engine *Engine) publishSwitch(ctx context.Context, eventType, from, to, reason string) {
+	if from == to {
+		return
+	}
+	engine.publish(eventType, SwitchEventData{
+		From:          from,
+		To:            to,
+		Provider:      engine.GetCurrentProviderName(),
+		Reason:        reason,
+		CorrelationID: CorrelationIDFromContext(ctx),
+	})
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 缓存状态
+const (
+	CacheStatusDisabled = "disabled" // 未启用响应缓存
+)
+
+// ExplainResult 查询的处理预览
+type ExplainResult struct {
+	EstimatedTokens       int      `json:"estimated_tokens"`        // 按所选提供商的 token_encoding 计算的查询内容 token 数
+	SelectedProvider      string   `json:"selected_provider"`       // 将使用的提供商
+	SelectedModel         string   `json:"selected_model"`          // 将首先尝试的模型（random、weighted-random 策略下为一次抽样结果）
+	WillStream            bool     `json:"will_stream"`             // 是否流式输出
+	CacheStatus           string   `json:"cache_status"`            // 响应缓存状态
+	LoadBalancingStrategy string   `json:"load_balancing_strategy"` // 选择初始模型的负载均衡策略，未生效时为空
+	AppliedFilters        []string `json:"applied_filters"`         // 处理查询时生效的规则，例如 route:code_generation
+	EstimatedCostUSD      float64  `json:"estimated_cost_usd"`      // 按模型 price_per_m_tokens 估算的输入费用（美元），未配置价格时为 0
+}
+
+// ExplainQuery 预览 QueryHandler 将如何处理查询，不调用模型，也不改变 Engine 和负载均衡的状态
+// 配置了 classifier.fallback_model 且规则无法确定查询类型时，分类仍会调用辅助分类模型
+// 参数:
+//   - ctx: 上下文
+//   - query: 查询内容
+// 返回:
+//   - *ExplainResult: 处理预览
+//   - error: 配置未加载或负载均衡策略无效时返回错误
+func (engine *Engine) ExplainQuery(ctx context.Context, query string) (*ExplainResult, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	logger := engine.loggerFrom(ctx)
+
+	// 在副本上模拟 QueryHandler 的路由和负载均衡
+	preview := engine.Clone()
+	result := &ExplainResult{
+		CacheStatus:    CacheStatusDisabled,
+		AppliedFilters: []string{},
+	}
+
+	routed := false
+	if len(engine.config.Classifier.Routes) > 0 {
+		router, err := NewQueryRouter(preview)
+		if err != nil {
+			return nil, fmt.Errorf("创建查询路由器失败: %w", err)
+		}
+		queryType, ok, err := router.Route(ctx, preview, query)
+		if err != nil {
+			logger.Warn("预览查询路由失败，继续使用当前模型", "error", err)
+		}
+		result.AppliedFilters = append(result.AppliedFilters, "classify:"+queryType.String())
+		if ok {
+			routed = true
+			result.AppliedFilters = append(result.AppliedFilters, "route:"+queryType.String())
+		}
+	}
+
+	provider, err := engine.config.GetProviderByName(preview.GetCurrentProviderName())
+	if err != nil {
+		return nil, fmt.Errorf("获取提供商配置失败: %w", err)
+	}
+	if !routed && provider.LoadBalancing != "" {
+		modelId, err := preview.balancedModel(false)
+		if err != nil {
+			return nil, err
+		}
+		preview.ModelId = modelId
+		result.LoadBalancingStrategy = provider.LoadBalancing
+		result.AppliedFilters = append(result.AppliedFilters, "load_balancing:"+provider.LoadBalancing)
+	}
+
+	if provider.MonthlyTokenBudget > 0 {
+		remaining, err := preview.GetTokenBudgetRemaining(provider.Name)
+		if err != nil && !errors.Is(err, ErrNoTokenBudget) {
+			return nil, fmt.Errorf("获取剩余 token 预算失败: %w", err)
+		}
+		result.AppliedFilters = append(result.AppliedFilters, fmt.Sprintf("token_budget:remaining=%d", remaining))
+	}
+
+	result.SelectedProvider = preview.GetCurrentProviderName()
+	result.SelectedModel = preview.ModelId
+	result.EstimatedTokens = preview.estimateTokens(query)
+	if model, ok := provider.GetModel(preview.ModelId); ok {
+		result.EstimatedCostUSD = float64(result.EstimatedTokens) * model.PricePerMTokens / 1_000_000
+	}
+	return result, nil
+}
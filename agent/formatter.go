@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// 支持的输出格式，其他包含 {{ 的字符串按 Go 模板处理
+const (
+	FormatJSON     = "json"     // 缩进的 JSON
+	FormatText     = "text"     // 纯文本回复
+	FormatMarkdown = "markdown" // 包含推理过程和调用信息的 Markdown 文档
+	FormatTable    = "table"    // Markdown 表格
+)
+
+// FormatResponse 将查询结果格式化为字符串
+// 参数:
+//   - result: 查询结果
+//   - format: json、text、markdown、table，或包含 {{ 的 Go 模板（如 "{{.ModelUsed}}: {{.Reply}}"）
+// 返回:
+//   - string: 格式化后的内容
+//   - error: 格式不支持、模板无效或序列化失败时返回错误
+func (engine *Engine) FormatResponse(result *QueryResult, format string) (string, error) {
+	if result == nil {
+		return "", fmt.Errorf("查询结果为空")
+	}
+
+	if strings.Contains(format, "{{") {
+		return formatTemplate(result, format)
+	}
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("序列化查询结果失败: %w", err)
+		}
+		return string(data), nil
+	case FormatText:
+		return strings.Join(resultReplies(result), "\n\n"), nil
+	case FormatMarkdown:
+		return formatMarkdown(result), nil
+	case FormatTable:
+		return formatTable(result), nil
+	default:
+		return "", fmt.Errorf("不支持的输出格式 %s，可选值: json、text、markdown、table 或 Go 模板", format)
+	}
+}
+
+// resultReplies 返回结果中的全部回复，只有一个回复时返回 Reply
+func resultReplies(result *QueryResult) []string {
+	if len(result.Replies) > 0 {
+		return result.Replies
+	}
+	return []string{result.Reply}
+}
+
+// formatTemplate 使用 Go 模板格式化查询结果
+func formatTemplate(result *QueryResult, format string) (string, error) {
+	tmpl, err := template.New("response").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("解析输出模板失败: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, result); err != nil {
+		return "", fmt.Errorf("渲染输出模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatMarkdown 生成 Markdown 文档：推理过程（引用块）、回复和调用信息
+func formatMarkdown(result *QueryResult) string {
+	var sb strings.Builder
+	sb.WriteString(markdownBody(result))
+	fmt.Fprintf(&sb, "---\n*%s / %s，尝试 %d 次*\n", result.ProviderUsed, result.ModelUsed, result.Attempts)
+	return sb.String()
+}
+
+// markdownBody 生成推理过程（引用块）和回复部分的 Markdown
+func markdownBody(result *QueryResult) string {
+	var sb strings.Builder
+	if think := strings.TrimSpace(result.Think); think != "" {
+		sb.WriteString("> **推理过程**\n>\n")
+		for _, line := range strings.Split(think, "\n") {
+			sb.WriteString("> " + line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	replies := resultReplies(result)
+	for i, reply := range replies {
+		if len(replies) > 1 {
+			fmt.Fprintf(&sb, "### 回复 %d\n\n", i+1)
+		}
+		sb.WriteString(reply + "\n\n")
+	}
+	return sb.String()
+}
+
+// formatTable 生成字段/值两列的 Markdown 表格
+func formatTable(result *QueryResult) string {
+	rows := [][2]string{
+		{"查询", result.Query},
+		{"回复", result.Reply},
+	}
+	if result.Think != "" {
+		rows = append(rows, [2]string{"推理过程", result.Think})
+	}
+	if len(result.Replies) > 1 {
+		for i, reply := range result.Replies {
+			rows = append(rows, [2]string{fmt.Sprintf("回复 %d", i+1), reply})
+		}
+	}
+	rows = append(rows,
+		[2]string{"模型", result.ModelUsed},
+		[2]string{"提供商", result.ProviderUsed},
+		[2]string{"尝试次数", fmt.Sprint(result.Attempts)},
+	)
+	if result.CorrelationID != "" {
+		rows = append(rows, [2]string{"关联ID", result.CorrelationID})
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| 字段 | 值 |\n|---|---|\n")
+	for _, row := range rows {
+		fmt.Fprintf(&sb, "| %s | %s |\n", row[0], tableCell(row[1]))
+	}
+	return sb.String()
+}
+
+// tableCell 转义表格单元格中的竖线和换行
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// 模糊测试发现的问题类型
+const (
+	FuzzKindError     = "error"     // 调用失败
+	FuzzKindEmpty     = "empty"     // 回复为空
+	FuzzKindAnomalous = "anomalous" // 回复中包含错误信息
+)
+
+// fuzzAnomalyMarkers 回复中出现时视为异常的错误信息（不区分大小写）
+var fuzzAnomalyMarkers = []string{
+	"internal server error",
+	"traceback (most recent call last)",
+	"exception:",
+	"panic:",
+	"segmentation fault",
+	`"error":`,
+}
+
+// fuzzCharsets 生成随机输入使用的字符集
+var fuzzCharsets = [][]rune{
+	[]rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ "),
+	[]rune("0123456789+-*/=.,eE"),
+	[]rune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"),
+	[]rune("的一是不了人我在有他这中大来上国个到说们为子和你地出道也时年"),
+	[]rune("абвгдеёжзийклмнопрстуфхцчшщъыьэюя"),
+	[]rune("ابتثجحخدذرزسشصضطظعغفقكلمنهوي"),
+	[]rune("あいうえおかきくけこさしすせそアイウエオカキクケコ"),
+	// emoji，含零宽连接符和变体选择符
+	[]rune("😀😂🤖🔥✨👍🚀🎉\u200d\ufe0f"),
+	// 空白、零宽、方向控制和控制字符
+	[]rune(" \t\n\r\u00a0\u200b\u2028\u202e\ufeff\x00\x07\x1b"),
+}
+
+// FuzzSample 一个引发问题的输入
+type FuzzSample struct {
+	Input string `json:"input"`           // 发送的输入
+	Kind  string `json:"kind"`            // 问题类型: error、empty 或 anomalous
+	Reply string `json:"reply,omitempty"` // 模型回复
+	Error string `json:"error,omitempty"` // 调用失败时的错误信息
+}
+
+// FuzzReport 模糊测试报告
+type FuzzReport struct {
+	Provider         string       `json:"provider"`          // 提供商名称
+	Model            string       `json:"model"`             // 模型ID
+	Total            int          `json:"total"`             // 发送的输入数
+	Errors           int          `json:"errors"`            // 调用失败数
+	EmptyReplies     int          `json:"empty_replies"`     // 回复为空的次数
+	AnomalousReplies int          `json:"anomalous_replies"` // 回复中包含错误信息的次数
+	Samples          []FuzzSample `json:"samples"`           // 引发问题的输入，按发送顺序排列
+}
+
+// FuzzTest 向模型发送 n 个随机生成的输入（长度、字符集、语言和特殊字符各不相同），记录引发调用失败、空回复或回复中包含错误信息的输入
+// 请求不重试、不做模型轮换，测试在 Engine 副本上进行，不修改原 Engine
+// 参数:
+//   - ctx: 上下文，取消后停止测试
+//   - model: 模型ID或别名，为空时使用当前模型
+//   - n: 发送的输入数
+// 返回:
+//   - *FuzzReport: 模糊测试报告
+//   - error: 参数无效、模型不存在、Engine 已关闭或测试被取消时返回错误
+func (engine *Engine) FuzzTest(ctx context.Context, model string, n int) (*FuzzReport, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("输入数必须大于 0，当前为 %d", n)
+	}
+	clone := engine.Clone()
+	if model != "" {
+		if err := clone.SwitchModel(model); err != nil {
+			return nil, err
+		}
+	}
+
+	if engine.lifecycle != nil {
+		if err := engine.lifecycle.acquire(); err != nil {
+			return nil, err
+		}
+		defer engine.lifecycle.release()
+	}
+	ctx, cancel := mergeContext(engine.baseCtx, ctx)
+	defer cancel()
+	ctx = engine.withLogger(ctx)
+	logger := LoggerFromContext(ctx).With("handler", "FuzzTest")
+
+	report := &FuzzReport{Provider: clone.providerName, Model: clone.ModelId}
+	client := clone.newClient()
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 1; i <= n; i++ {
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("模糊测试被取消: %w", err)
+		}
+
+		input := fuzzInput(rnd)
+		reqCtx := withCorrelation(ctx)
+		opts := append(clone.requestOptions(reqCtx), option.WithMaxRetries(0))
+		completion, err := client.Chat.Completions.New(reqCtx, openai.ChatCompletionNewParams{
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(input)},
+			Model:    clone.ModelId,
+		}, opts...)
+		report.Total++
+
+		sample := FuzzSample{Input: input}
+		switch {
+		case err != nil:
+			report.Errors++
+			clone.recordError(i, err)
+			sample.Kind, sample.Error = FuzzKindError, err.Error()
+		case len(completion.Choices) == 0 || strings.TrimSpace(completion.Choices[0].Message.Content) == "":
+			report.EmptyReplies++
+			sample.Kind = FuzzKindEmpty
+		case fuzzAnomalous(completion.Choices[0].Message.Content):
+			report.AnomalousReplies++
+			sample.Kind, sample.Reply = FuzzKindAnomalous, completion.Choices[0].Message.Content
+		default:
+			continue
+		}
+		LoggerFromContext(reqCtx).Warn("模糊测试发现问题", "model", clone.ModelId, "iteration", i, "kind", sample.Kind, "input_length", len([]rune(input)))
+		report.Samples = append(report.Samples, sample)
+	}
+	logger.Info("模糊测试完成", "model", clone.ModelId, "total", report.Total, "errors", report.Errors,
+		"empty_replies", report.EmptyReplies, "anomalous_replies", report.AnomalousReplies)
+	return report, nil
+}
+
+// fuzzInput 生成一个随机输入：长度在空字符串到数千字符之间，从一个或多个字符集中取字符
+func fuzzInput(rnd *rand.Rand) string {
+	var length int
+	switch rnd.Intn(5) {
+	case 0:
+		length = rnd.Intn(2) // 空字符串或单个字符
+	case 1:
+		length = 2 + rnd.Intn(15)
+	case 2, 3:
+		length = 16 + rnd.Intn(240)
+	default:
+		length = 256 + rnd.Intn(3840)
+	}
+
+	// 一半的输入只使用一个字符集，其余混合多个字符集
+	charsets := [][]rune{fuzzCharsets[rnd.Intn(len(fuzzCharsets))]}
+	if rnd.Intn(2) == 0 {
+		for range 1 + rnd.Intn(3) {
+			charsets = append(charsets, fuzzCharsets[rnd.Intn(len(fuzzCharsets))])
+		}
+	}
+
+	var b strings.Builder
+	for range length {
+		charset := charsets[rnd.Intn(len(charsets))]
+		b.WriteRune(charset[rnd.Intn(len(charset))])
+	}
+	return b.String()
+}
+
+// fuzzAnomalous 回复中是否包含错误信息
+func fuzzAnomalous(reply string) bool {
+	reply = strings.ToLower(reply)
+	for _, marker := range fuzzAnomalyMarkers {
+		if strings.Contains(reply, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package agent
+
+import (
+	pb "agent_engine/proto"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcDefaultBatchConcurrency Batch 请求未指定并发数时的默认值
+const grpcDefaultBatchConcurrency = 4
+
+// grpcCorrelationIDMetadata 携带请求关联ID的 gRPC 元数据键（gRPC 元数据键均为小写）
+const grpcCorrelationIDMetadata = "x-correlation-id"
+
+// grpcServer 基于 Engine 实现 AgentService
+type grpcServer struct {
+	pb.UnimplementedAgentServiceServer
+	engine *Engine
+}
+
+// ServeGRPC 在指定地址启动 gRPC 服务，阻塞直到 ctx 结束
+// 每个请求在 Engine 副本上处理，请求之间互不影响；ctx 结束后停止接受新连接并等待进行中的请求完成
+// 参数:
+//   - ctx: 上下文，结束后停止服务
+//   - addr: 监听地址，如 ":50051"
+// 返回:
+//   - error: 监听或服务失败时返回错误，ctx 结束导致的正常停止返回 nil
+func (engine *Engine) ServeGRPC(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听地址 %s 失败: %w", addr, err)
+	}
+
+	server := grpc.NewServer()
+	pb.RegisterAgentServiceServer(server, &grpcServer{engine: engine})
+
+	// ctx 结束时优雅停止服务
+	stop := context.AfterFunc(ctx, server.GracefulStop)
+	defer stop()
+
+	engine.loggerFrom(ctx).Info("gRPC 服务已启动", "addr", lis.Addr().String())
+	if err := server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("gRPC 服务异常退出: %w", err)
+	}
+	return nil
+}
+
+// requestContext 将 gRPC 元数据中的请求关联ID和 W3C Trace Context 写入上下文
+func (s *grpcServer) requestContext(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(grpcCorrelationIDMetadata); len(ids) > 0 && ids[0] != "" {
+			ctx = WithCorrelationID(ctx, ids[0])
+		}
+		if parents := md.Get(TraceParentHeader); len(parents) > 0 {
+			ctx = WithTraceContext(ctx, parents[0], strings.Join(md.Get(TraceStateHeader), ","))
+		}
+	}
+	return ctx
+}
+
+// Query 处理查询请求，回复内容分片以 delta 消息依次返回，最后返回 done 为 true 的完整结果
+// 请求多个回复（n > 1）或 query 事件的处理器不支持流式输出时，只返回完整结果
+func (s *grpcServer) Query(req *pb.QueryRequest, stream grpc.ServerStreamingServer[pb.QueryResponse]) error {
+	handler, _ := lookupEventHandler("query")
+	if _, ok := handler.(StreamingEventHandler); !ok || req.GetN() > 1 {
+		rsp, err := s.query(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		rsp.Done = true
+		return stream.Send(rsp)
+	}
+
+	queryReq, err := toQueryRequest(req)
+	if err != nil {
+		return err
+	}
+	// QueryHandler 处理过程中会切换模型，每个请求使用独立的副本
+	result, err := s.engine.Clone().StreamRequest(s.requestContext(stream.Context()), queryReq, grpcDeltaWriter{stream: stream})
+	if err != nil {
+		return grpcError(err)
+	}
+	rsp := toQueryResponse(result)
+	rsp.Done = true
+	return stream.Send(rsp)
+}
+
+// grpcDeltaWriter 将回复内容分片作为 Query 流的中间消息发送
+type grpcDeltaWriter struct {
+	stream grpc.ServerStreamingServer[pb.QueryResponse]
+}
+
+// Write 发送一条只包含 delta 的消息
+func (w grpcDeltaWriter) Write(p []byte) (int, error) {
+	if err := w.stream.Send(&pb.QueryResponse{Delta: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// query 通过 DispatchAndHandle 处理单个查询请求
+func (s *grpcServer) query(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	queryReq, err := toQueryRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(queryReq)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "序列化请求参数失败: %v", err)
+	}
+
+	// QueryHandler 处理过程中会切换模型，每个请求使用独立的副本
+	result, _, err := QueryAndHandle(s.engine.Clone(), s.requestContext(ctx), string(params))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toQueryResponse(result), nil
+}
+
+// toQueryRequest 将 gRPC 请求转换为 QueryRequest，查询内容为空时返回 InvalidArgument 错误
+func toQueryRequest(req *pb.QueryRequest) (*QueryRequest, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "查询内容不能为空")
+	}
+
+	queryReq := &QueryRequest{
+		Query:            req.GetQuery(),
+		OverrideModel:    req.GetOverrideModel(),
+		OverrideProvider: req.GetOverrideProvider(),
+		N:                int(req.GetN()),
+	}
+	if len(req.GetTemplateVars()) > 0 {
+		queryReq.TemplateVars = make(map[string]any, len(req.GetTemplateVars()))
+		for k, v := range req.GetTemplateVars() {
+			queryReq.TemplateVars[k] = v
+		}
+	}
+	return queryReq, nil
+}
+
+// List 列出所有提供商和模型
+func (s *grpcServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	params, err := json.Marshal(ListRequest{Verbose: req.GetVerbose()})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "序列化请求参数失败: %v", err)
+	}
+
+	ctx = s.requestContext(ctx)
+	result, _, err := ListAndHandle(s.engine.Clone(), ctx, string(params))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	rsp := &pb.ListResponse{
+		ConfigPath:      result.ConfigPath,
+		CurrentProvider: result.CurrentProvider,
+		CurrentModel:    result.CurrentModel,
+		CurrentBaseUrl:  result.CurrentBaseUrl,
+		TotalProviders:  int32(result.TotalProviders),
+		Providers:       make([]*pb.ProviderInfo, 0, len(result.Providers)),
+	}
+	for _, p := range result.Providers {
+		info := &pb.ProviderInfo{
+			Name:      p.Name,
+			BaseUrl:   p.BaseUrl,
+			Models:    p.Models,
+			IsCurrent: p.IsCurrent,
+		}
+		if len(p.ModelMetadata) > 0 {
+			info.ModelMetadata = make(map[string]*structpb.Struct, len(p.ModelMetadata))
+			for model, m := range p.ModelMetadata {
+				st, err := structpb.NewStruct(m)
+				if err != nil {
+					s.engine.loggerFrom(ctx).Warn("转换模型元数据失败", "provider", p.Name, "model", model, "error", err)
+					continue
+				}
+				info.ModelMetadata[model] = st
+			}
+		}
+		rsp.Providers = append(rsp.Providers, info)
+	}
+	return rsp, nil
+}
+
+// Batch 并发执行多个查询，单个查询失败不影响其他查询
+func (s *grpcServer) Batch(ctx context.Context, req *pb.BatchRequest) (*pb.BatchResponse, error) {
+	concurrency := int(req.GetConcurrency())
+	if concurrency <= 0 {
+		concurrency = grpcDefaultBatchConcurrency
+	}
+
+	items := make([]*pb.BatchItem, len(req.GetRequests()))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, queryReq := range req.GetRequests() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			rsp, err := s.query(ctx, queryReq)
+			if err != nil {
+				items[i] = &pb.BatchItem{Error: status.Convert(err).Message()}
+				return
+			}
+			items[i] = &pb.BatchItem{Result: rsp}
+		}()
+	}
+	wg.Wait()
+
+	return &pb.BatchResponse{Items: items}, nil
+}
+
+// HealthCheck 返回服务状态、最近 60 秒的查询吞吐量和各提供商的健康状态（见 GetProviderHealth），Engine 关闭后返回 NOT_SERVING
+func (s *grpcServer) HealthCheck(ctx context.Context, req *pb.HealthCheckRequest) (*pb.HealthCheckResponse, error) {
+	ctx = s.requestContext(ctx)
+	rsp := &pb.HealthCheckResponse{
+		Status:           pb.HealthCheckResponse_SERVING,
+		Provider:         s.engine.GetCurrentProviderName(),
+		Model:            s.engine.ModelId,
+		QueriesPerSecond: s.engine.GetQueryThroughput(time.Minute),
+	}
+	if s.engine.lifecycle != nil && s.engine.lifecycle.isClosed() {
+		rsp.Status = pb.HealthCheckResponse_NOT_SERVING
+	}
+	for name, health := range s.engine.GetProviderHealth(ctx) {
+		if rsp.Providers == nil {
+			rsp.Providers = make(map[string]*pb.ProviderHealth)
+		}
+		rsp.Providers[name] = toProviderHealth(health)
+	}
+	return rsp, nil
+}
+
+// toProviderHealth 将 HealthStatus 转换为 gRPC 响应
+func toProviderHealth(health HealthStatus) *pb.ProviderHealth {
+	rsp := &pb.ProviderHealth{
+		Status:              health.Status,
+		Latency:             durationpb.New(health.Latency),
+		ErrorRate:           health.ErrorRate,
+		CircuitBreakerState: health.CircuitBreakerState,
+		TokenBudgetPct:      health.TokenBudgetPct,
+		LastError:           health.LastError,
+	}
+	if !health.LastSuccess.IsZero() {
+		rsp.LastSuccess = timestamppb.New(health.LastSuccess)
+	}
+	return rsp
+}
+
+// toQueryResponse 将 QueryResult 转换为 gRPC 响应
+func toQueryResponse(result *QueryResult) *pb.QueryResponse {
+	rsp := &pb.QueryResponse{
+		Query:              result.Query,
+		Reply:              result.Reply,
+		Think:              result.Think,
+		Replies:            result.Replies,
+		ModelUsed:          result.ModelUsed,
+		ProviderUsed:       result.ProviderUsed,
+		Attempts:           int32(result.Attempts),
+		CorrelationId:      result.CorrelationID,
+		RequestId:          result.RequestID,
+		ApiKeyUsed:         result.APIKeyUsed,
+		TotalTokens:        result.TotalTokens,
+		Fallback:           result.Fallback,
+		EffectiveMaxTokens: result.EffectiveMaxTokens,
+		CachedQuery:        result.CachedQuery,
+		CacheSimilarity:    result.CacheSimilarity,
+		PostHookErrors:     result.PostHookErrors,
+	}
+	for _, call := range result.ToolCalls {
+		rsp.ToolCalls = append(rsp.ToolCalls, &pb.ToolCallRecord{
+			Name:      call.Name,
+			Arguments: call.Arguments,
+			Output:    call.Output,
+			Error:     call.Error,
+		})
+	}
+	if score := result.Score; score != nil {
+		rsp.Score = &pb.ResponseScore{
+			Relevance:    score.Relevance,
+			Coherence:    score.Coherence,
+			Length:       int32(score.Length),
+			HasCode:      score.HasCode,
+			HasMarkdown:  score.HasMarkdown,
+			OverallScore: score.OverallScore,
+		}
+	}
+	return rsp
+}
+
+// grpcError 将处理器返回的错误转换为对应状态码的 gRPC 错误
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrEngineShutdown):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,45 @@
+package agent
+
+import "context"
+
+// HandlerMiddleware 事件处理器中间件，返回包装后的处理器
+type HandlerMiddleware func(EventHandler) EventHandler
+
+// EventHandlerFunc 将函数适配为 EventHandler，便于在中间件中实现包装处理器
+type EventHandlerFunc func(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error)
+
+// Handle 实现 EventHandler 接口
+func (f EventHandlerFunc) Handle(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
+	return f(ctx, engine, params, event)
+}
+
+// WithEventHandlerMiddleware 返回为指定事件的处理器添加了中间件的 Engine 副本
+// 可用于为单个事件添加日志、计时、鉴权等逻辑而不替换处理器；多次调用时中间件依次嵌套，先添加的在最外层
+// 参数:
+//   - event: 事件类型，例如 query、list；事件没有注册处理器时中间件不生效
+//   - mw: 中间件
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithEventHandlerMiddleware(event string, mw func(EventHandler) EventHandler) *Engine {
+	clone := engine.Clone()
+	// 复制映射和切片，避免影响原 Engine 及其其他副本
+	clone.handlerMiddleware = make(map[string][]HandlerMiddleware, len(engine.handlerMiddleware)+1)
+	for e, mws := range engine.handlerMiddleware {
+		clone.handlerMiddleware[e] = mws
+	}
+	mws := engine.handlerMiddleware[event]
+	clone.handlerMiddleware[event] = append(mws[:len(mws):len(mws)], mw)
+	return clone
+}
+
+// wrapHandler 使用为事件添加的中间件包装处理器，配置了指标中间件时将其放在最外层
+func (engine *Engine) wrapHandler(event string, handler EventHandler) EventHandler {
+	mws := engine.handlerMiddleware[event]
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	if engine.metricsMiddleware != nil {
+		handler = engine.metricsMiddleware(handler)
+	}
+	return handler
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// 提供商的健康状态
+const (
+	HealthHealthy   = "healthy"   // 熔断器关闭且错误率不超过 DegradedErrorRate
+	HealthDegraded  = "degraded"  // 错误率超过 DegradedErrorRate
+	HealthUnhealthy = "unhealthy" // 熔断器打开
+)
+
+// 熔断器状态
+const (
+	CircuitClosed = "closed" // 正常调用
+	CircuitOpen   = "open"   // 失败次数达到阈值，CircuitBreakerHook 会拒绝查询
+)
+
+// GetProviderHealth 评估健康状态使用的默认值
+const (
+	DegradedErrorRate              = 0.1              // 最近调用的错误率超过该值时为 degraded
+	DefaultCircuitBreakerThreshold = 5                // 熔断器打开所需的失败次数，与 CircuitBreakerHook 一起使用时可作为 threshold
+	DefaultCircuitBreakerCooldown  = 30 * time.Second // 统计失败次数的时长，与 CircuitBreakerHook 一起使用时可作为 cooldown
+)
+
+// HealthStatus 提供商的健康状态
+type HealthStatus struct {
+	Status              string        `json:"status"`                // healthy、degraded 或 unhealthy
+	Latency             time.Duration `json:"latency"`               // 所有模型最近成功调用的平均延迟，没有记录时为 0
+	ErrorRate           float64       `json:"error_rate"`            // 最近 20 次模型调用的错误率，没有记录时为 0
+	CircuitBreakerState string        `json:"circuit_breaker_state"` // 熔断器状态: closed 或 open
+	TokenBudgetPct      float64       `json:"token_budget_pct"`      // 当前计费周期已使用的 token 预算百分比，未配置预算时为 0
+	LastError           string        `json:"last_error,omitempty"`  // 最近一次调用失败的错误信息
+	LastSuccess         time.Time     `json:"last_success"`          // 最近一次成功调用的时间，没有记录时为零值
+}
+
+// GetProviderHealth 根据已有的统计数据评估每个已配置提供商的健康状态，不调用模型接口
+// 熔断器以 DefaultCircuitBreakerThreshold 和 DefaultCircuitBreakerCooldown 评估：最近 cooldown 内的失败次数达到阈值时为 open；
+// 熔断器打开时为 unhealthy，否则最近调用的错误率超过 DegradedErrorRate 时为 degraded，其余为 healthy
+// 参数:
+//   - ctx: 上下文，用于记录读取用量文件失败等日志
+// 返回:
+//   - map[string]HealthStatus: 提供商名称 -> 健康状态，配置未加载时为空
+func (engine *Engine) GetProviderHealth(ctx context.Context) map[string]HealthStatus {
+	health := make(map[string]HealthStatus)
+	if engine.config == nil {
+		return health
+	}
+
+	now := time.Now()
+	var lastErrors map[string]string
+	if engine.errorLog != nil {
+		lastErrors = make(map[string]string)
+		for _, e := range engine.errorLog.recent(0) {
+			if _, ok := lastErrors[e.Provider]; !ok {
+				lastErrors[e.Provider] = e.ErrorMsg
+			}
+		}
+	}
+
+	for _, p := range engine.config.Provider {
+		status := HealthStatus{
+			CircuitBreakerState: CircuitClosed,
+			LastError:           lastErrors[p.Name],
+		}
+		if engine.stats != nil {
+			status.Latency, status.ErrorRate, status.LastSuccess = engine.stats.providerHealth(p.Name)
+		}
+		if engine.recentFailures(p.Name, now.Add(-DefaultCircuitBreakerCooldown)) >= DefaultCircuitBreakerThreshold {
+			status.CircuitBreakerState = CircuitOpen
+		}
+		if budget, err := engine.tokenBudget(p.Name, now); err != nil {
+			engine.loggerFrom(ctx).Warn("获取 token 预算失败", "provider", p.Name, "error", err)
+		} else if budget.Budget > 0 {
+			status.TokenBudgetPct = float64(budget.Used) / float64(budget.Budget) * 100
+		}
+
+		switch {
+		case status.CircuitBreakerState == CircuitOpen:
+			status.Status = HealthUnhealthy
+		case status.ErrorRate > DegradedErrorRate:
+			status.Status = HealthDegraded
+		default:
+			status.Status = HealthHealthy
+		}
+		health[p.Name] = status
+	}
+	return health
+}
+
+// recentFailures 统计提供商在 since 之后（含）的模型调用失败次数
+func (engine *Engine) recentFailures(provider string, since time.Time) int {
+	if engine.errorLog == nil {
+		return 0
+	}
+	failures := 0
+	for _, e := range engine.errorLog.recent(0) {
+		if e.Time.Before(since) {
+			break
+		}
+		if e.Provider == provider {
+			failures++
+		}
+	}
+	return failures
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultStatsFile 默认的统计数据文件
+const DefaultStatsFile = "./agent_engine_logs/stats.json"
+
+// ResponseHistogramBuckets 响应长度直方图的分桶（按回复的 completion token 数），按长度从小到大排列
+var ResponseHistogramBuckets = []string{"0-100", "101-500", "501-2000", "2001+"}
+
+// responseBucket 返回 completion token 数所在的分桶
+func responseBucket(tokens int) string {
+	switch {
+	case tokens <= 100:
+		return "0-100"
+	case tokens <= 500:
+		return "101-500"
+	case tokens <= 2000:
+		return "501-2000"
+	default:
+		return "2001+"
+	}
+}
+
+// statsData 统计数据文件的内容
+type statsData struct {
+	ResponseHistogram map[string]map[string]int  `json:"response_histogram"`    // provider + "/" + model -> 分桶 -> 回复数
+	Checkpoints       map[string]*checkpointData `json:"checkpoints,omitempty"` // 检查点名称 -> 保存的状态，见 CreateCheckpoint
+}
+
+// statsStore 持久化的统计数据，由 Engine 及其所有副本共享
+// 每次读写都直接操作 JSON 文件，多次运行之间的统计可以累计
+type statsStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newStatsStore 创建统计数据存储
+func newStatsStore(path string) *statsStore {
+	if path == "" {
+		path = DefaultStatsFile
+	}
+	return &statsStore{path: path}
+}
+
+// flush 等待进行中的写入完成后将统计数据文件同步到磁盘
+func (s *statsStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := syncFile(s.path); err != nil {
+		return fmt.Errorf("同步统计数据文件失败: %w", err)
+	}
+	return nil
+}
+
+// load 读取统计数据文件，文件不存在时返回空记录
+func (s *statsStore) load() (*statsData, error) {
+	stats := &statsData{ResponseHistogram: make(map[string]map[string]int)}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取统计数据文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("解析统计数据文件失败: %w", err)
+	}
+	if stats.ResponseHistogram == nil {
+		stats.ResponseHistogram = make(map[string]map[string]int)
+	}
+	return stats, nil
+}
+
+// save 写入统计数据文件，调用方需持有锁
+func (s *statsStore) save(stats *statsData) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化统计数据失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建统计数据文件目录失败: %w", err)
+	}
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入统计数据文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入统计数据文件失败: %w", err)
+	}
+	return nil
+}
+
+// addResponse 将一次回复的长度计入直方图
+func (s *statsStore) addResponse(provider, model string, tokens int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, err := s.load()
+	if err != nil {
+		return err
+	}
+	key := provider + "/" + model
+	if stats.ResponseHistogram[key] == nil {
+		stats.ResponseHistogram[key] = make(map[string]int)
+	}
+	stats.ResponseHistogram[key][responseBucket(tokens)]++
+	return s.save(stats)
+}
+
+// histogram 返回模型的响应长度直方图
+func (s *statsStore) histogram(provider, model string) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return stats.ResponseHistogram[provider+"/"+model], nil
+}
+
+// recordResponseLength 记录当前模型一次回复的长度，提供商未返回 token 用量时不记录，失败时只记录日志
+func (engine *Engine) recordResponseLength(ctx context.Context, completionTokens int64) {
+	if engine.statsFile == nil || completionTokens <= 0 {
+		return
+	}
+	provider := engine.GetCurrentProviderName()
+	if err := engine.statsFile.addResponse(provider, engine.ModelId, int(completionTokens)); err != nil {
+		LoggerFromContext(ctx).Warn("记录响应长度失败", "provider", provider, "model", engine.ModelId, "error", err)
+	}
+}
+
+// GetResponseHistogram 获取模型回复长度（completion token 数）的直方图
+// 统计保存在配置文件的 stats_file（默认 ./agent_engine_logs/stats.json）中，多次运行之间累计
+// 参数:
+//   - provider: 提供商名称
+//   - model: 模型ID
+// 返回:
+//   - map[string]int: 分桶（见 ResponseHistogramBuckets）-> 回复数，包含所有分桶；读取统计数据失败时各分桶均为 0
+func (engine *Engine) GetResponseHistogram(provider, model string) map[string]int {
+	result := make(map[string]int, len(ResponseHistogramBuckets))
+	for _, bucket := range ResponseHistogramBuckets {
+		result[bucket] = 0
+	}
+	if engine.statsFile == nil {
+		return result
+	}
+	counts, err := engine.statsFile.histogram(provider, model)
+	if err != nil {
+		engine.loggerFrom(engine.baseContext()).Warn("读取响应长度直方图失败", "provider", provider, "model", model, "error", err)
+		return result
+	}
+	for bucket, n := range counts {
+		result[bucket] = n
+	}
+	return result
+}
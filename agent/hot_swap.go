@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+)
+
+// eventHandlerMu 保护 eventHandlerMap，使处理器可以在服务运行中替换
+var eventHandlerMu sync.RWMutex
+
+// lookupEventHandler 查找事件对应的处理器
+func lookupEventHandler(event string) (EventHandler, bool) {
+	eventHandlerMu.RLock()
+	defer eventHandlerMu.RUnlock()
+	handler, ok := eventHandlerMap[event]
+	return handler, ok
+}
+
+// HotSwapHandler 原子替换事件的处理器，无需重启服务（如 gRPC 服务模式）
+// 处理器注册表由所有 Engine 共享；进行中的请求继续使用旧处理器完成，之后的请求使用新处理器
+// 参数:
+//   - event: 事件类型，必须已注册处理器
+//   - handler: 新的处理器
+// 返回:
+//   - error: 处理器为空或事件未注册时返回错误
+func (engine *Engine) HotSwapHandler(event string, handler EventHandler) error {
+	if handler == nil {
+		return fmt.Errorf("事件 %s 的处理器不能为空", event)
+	}
+
+	eventHandlerMu.Lock()
+	defer eventHandlerMu.Unlock()
+	old, ok := eventHandlerMap[event]
+	if !ok {
+		return fmt.Errorf("事件 %s 未注册处理器", event)
+	}
+	eventHandlerMap[event] = handler
+
+	engine.loggerFrom(engine.baseContext()).Info("已替换事件处理器", "event", event, "old", fmt.Sprintf("%T", old), "new", fmt.Sprintf("%T", handler))
+	return nil
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent_engine/conf"
+	"github.com/openai/openai-go/v3"
+)
+
+// ErrImageNotSupported 当前提供商未配置 image 时 ImageHandler 返回的错误
+var ErrImageNotSupported = errors.New("提供商不支持图片生成")
+
+// ImageHandler 实现 EventHandler 接口，处理图片生成事件（image）
+// 使用当前提供商的 image 配置调用图片生成接口（如 DALL-E），未配置 image 的提供商返回 ErrImageNotSupported
+type ImageHandler struct{}
+
+// ImageRequest image 事件的参数，Size、Quality、N 为空时使用提供商的 image 配置
+type ImageRequest struct {
+	Prompt  string `json:"prompt"`            // 图片描述
+	Size    string `json:"size,omitempty"`    // 图片尺寸，如 1024x1024
+	Quality string `json:"quality,omitempty"` // 图片质量，如 standard、hd
+	N       int    `json:"n,omitempty"`       // 生成的图片数
+}
+
+// GeneratedImage 生成的一张图片
+type GeneratedImage struct {
+	URL           string `json:"url"`                      // 图片地址；接口返回 base64 数据时为 data URL（data:image/png;base64,...）
+	RevisedPrompt string `json:"revised_prompt,omitempty"` // 模型改写后实际使用的描述（dall-e-3）
+}
+
+// ImageResult image 事件的结果
+type ImageResult struct {
+	Prompt       string           `json:"prompt"`        // 图片描述
+	Images       []GeneratedImage `json:"images"`        // 生成的图片
+	ModelUsed    string           `json:"model_used"`    // 使用的图片模型
+	ProviderUsed string           `json:"provider_used"` // 使用的提供商
+}
+
+// ParseImageRequest 解析 image 事件的参数
+// 参数:
+//   - params: JSON 格式的 ImageRequest，例如 {"prompt":"未来城市","size":"1024x1024"}；不是 JSON 对象时整体作为图片描述
+// 返回:
+//   - *ImageRequest: 图片生成请求
+//   - error: JSON 解析失败或描述为空时返回错误
+func ParseImageRequest(params string) (*ImageRequest, error) {
+	req := &ImageRequest{Prompt: params}
+	trimmed := strings.TrimSpace(params)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		req = &ImageRequest{}
+		if err := json.Unmarshal([]byte(trimmed), req); err != nil {
+			return nil, fmt.Errorf("解析请求参数失败: %w", err)
+		}
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, fmt.Errorf("图片描述不能为空")
+	}
+	if req.N < 0 {
+		return nil, fmt.Errorf("图片数不能为负数: %d", req.N)
+	}
+	return req, nil
+}
+
+// Handle 处理 image 命令，使用当前提供商生成图片
+// 参数:
+//   - ctx: 上下文
+//   - engine: Engine 实例
+//   - params: 参数，纯文本描述或 JSON 格式的 ImageRequest
+//   - event: 事件类型
+// 返回:
+//   - rsp: *ImageResult 生成的图片
+//   - err: 参数无效、当前提供商未配置 image（ErrImageNotSupported）或调用失败时返回错误
+func (h *ImageHandler) Handle(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
+	req, err := ParseImageRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	providerName := engine.GetCurrentProviderName()
+	provider, err := engine.config.GetProviderByName(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Image == nil {
+		var supported []string
+		for _, p := range engine.config.Provider {
+			if p.Image != nil {
+				supported = append(supported, p.Name)
+			}
+		}
+		if len(supported) == 0 {
+			return nil, fmt.Errorf("%w: 提供商 %s 未配置 image，请在支持图片生成的提供商下添加 image 配置（如 model: %s）", ErrImageNotSupported, providerName, conf.DefaultImageModel)
+		}
+		return nil, fmt.Errorf("%w: 提供商 %s 未配置 image，可通过 --provider 选择已配置的提供商: %s", ErrImageNotSupported, providerName, strings.Join(supported, "、"))
+	}
+
+	imageParams := imageGenerateParams(*provider.Image, req)
+	var resp *openai.ImagesResponse
+	_, err = engine.callWithRotatedKey(func(client openai.Client) (err error) {
+		resp, err = client.Images.Generate(ctx, imageParams, engine.requestOptions(ctx)...)
+		return err
+	})
+	if err != nil {
+		engine.recordError(1, err)
+		return nil, fmt.Errorf("生成图片失败: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("生成图片失败: 模型 %s 未返回任何图片", imageParams.Model)
+	}
+	engine.recordUsage(ctx, resp.Usage.TotalTokens)
+
+	// gpt-image-1 等模型只返回 base64 数据，转换为 data URL
+	format := string(resp.OutputFormat)
+	if format == "" {
+		format = "png"
+	}
+	result := &ImageResult{
+		Prompt:       req.Prompt,
+		ModelUsed:    string(imageParams.Model),
+		ProviderUsed: providerName,
+	}
+	for _, image := range resp.Data {
+		url := image.URL
+		if url == "" && image.B64JSON != "" {
+			url = "data:image/" + format + ";base64," + image.B64JSON
+		}
+		result.Images = append(result.Images, GeneratedImage{URL: url, RevisedPrompt: image.RevisedPrompt})
+	}
+	return result, nil
+}
+
+// imageGenerateParams 合并提供商的 image 配置和请求参数，请求中设置的参数优先
+func imageGenerateParams(config conf.ImageConfig, req *ImageRequest) openai.ImageGenerateParams {
+	params := openai.ImageGenerateParams{
+		Prompt: req.Prompt,
+		Model:  openai.ImageModel(config.Model),
+	}
+	if params.Model == "" {
+		params.Model = conf.DefaultImageModel
+	}
+	if size := cmp.Or(req.Size, config.Size); size != "" {
+		params.Size = openai.ImageGenerateParamsSize(size)
+	}
+	if quality := cmp.Or(req.Quality, config.Quality); quality != "" {
+		params.Quality = openai.ImageGenerateParamsQuality(quality)
+	}
+	if n := cmp.Or(req.N, config.N); n > 0 {
+		params.N = openai.Int(int64(n))
+	}
+	return params
+}
+
+// Save 将图片保存到文件：data URL 直接解码，其他地址通过 HTTP GET 下载
+// 参数:
+//   - ctx: 上下文
+//   - path: 保存路径，目录不存在时自动创建
+// 返回:
+//   - error: 解码、下载或写入失败时返回错误
+func (img GeneratedImage) Save(ctx context.Context, path string) error {
+	var data []byte
+	if rest, ok := strings.CutPrefix(img.URL, "data:"); ok {
+		_, encoded, found := strings.Cut(rest, ";base64,")
+		if !found {
+			return fmt.Errorf("不支持的 data URL: 只支持 base64 编码")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("解码图片数据失败: %w", err)
+		}
+		data = decoded
+	} else {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, img.URL, nil)
+		if err != nil {
+			return fmt.Errorf("图片地址无效: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(httpReq)
+		if err != nil {
+			return fmt.Errorf("下载图片失败: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("下载图片失败: HTTP %d", resp.StatusCode)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("下载图片失败: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("保存图片失败: %w", err)
+	}
+	return nil
+}
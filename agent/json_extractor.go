@@ -0,0 +1,607 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExtractJSONPath 使用 JSONPath 表达式从 JSON 数据中提取值
+// 支持的语法:
+//   - $ 根节点，@ 过滤表达式中的当前节点
+//   - .name、['name'] 子节点，.* 与 [*] 通配
+//   - ..name 递归查找
+//   - [0]、[-1] 下标，[0,2] 多个下标，[1:3] 切片
+//   - [?(@.is_current==true)] 过滤表达式，支持 == != < <= > >= 比较、&& || 组合以及 @.name 存在性判断
+// 参数:
+//   - data: JSON 数据
+//   - path: JSONPath 表达式
+// 返回:
+//   - []any: 所有匹配的值（按文档顺序）
+//   - error: 数据或表达式无效时返回错误
+func ExtractJSONPath(data []byte, path string) ([]any, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析 JSON 数据失败: %w", err)
+	}
+
+	segments, err := parseJSONPath(path, '$')
+	if err != nil {
+		return nil, err
+	}
+	return evalJSONPath(root, segments), nil
+}
+
+// jsonPathSegmentKind 路径片段类型
+type jsonPathSegmentKind int
+
+const (
+	segmentChild    jsonPathSegmentKind = iota // 按名称选择子节点
+	segmentWildcard                            // 选择全部子节点
+	segmentIndex                               // 按下标选择数组元素
+	segmentSlice                               // 数组切片
+	segmentFilter                              // 过滤表达式
+)
+
+// jsonPathSegment 解析后的路径片段
+type jsonPathSegment struct {
+	kind      jsonPathSegmentKind
+	recursive bool // 是否为 .. 递归查找
+	names     []string
+	indexes   []int
+	slice     [3]*int // start、end、step
+	filter    jsonPathFilter
+}
+
+// parseJSONPath 将路径表达式解析为片段列表
+// 参数:
+//   - path: 路径表达式
+//   - rootChar: 根节点字符，完整路径为 $，过滤表达式中为 @
+func parseJSONPath(path string, rootChar byte) ([]jsonPathSegment, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path[0] != rootChar {
+		return nil, fmt.Errorf("JSONPath 表达式必须以 %c 开头: %s", rootChar, path)
+	}
+
+	var segments []jsonPathSegment
+	i := 1
+	for i < len(path) {
+		recursive := false
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			recursive = true
+			i += 2
+		case path[i] == '.':
+			i++
+		case path[i] == '[':
+		default:
+			return nil, fmt.Errorf("JSONPath 表达式第 %d 个字符无效: %s", i, path)
+		}
+
+		if i >= len(path) {
+			return nil, fmt.Errorf("JSONPath 表达式不完整: %s", path)
+		}
+
+		// 方括号形式
+		if path[i] == '[' {
+			end, err := findClosingBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			seg, err := parseBracketSegment(path[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			seg.recursive = recursive
+			segments = append(segments, seg)
+			i = end + 1
+			continue
+		}
+
+		// 点号形式：名称或通配符
+		start := i
+		for i < len(path) && path[i] != '.' && path[i] != '[' {
+			i++
+		}
+		name := path[start:i]
+		if name == "*" {
+			segments = append(segments, jsonPathSegment{kind: segmentWildcard, recursive: recursive})
+		} else {
+			segments = append(segments, jsonPathSegment{kind: segmentChild, recursive: recursive, names: []string{name}})
+		}
+	}
+	return segments, nil
+}
+
+// findClosingBracket 查找与 start 位置的 [ 匹配的 ]，忽略引号和嵌套括号中的内容
+func findClosingBracket(path string, start int) (int, error) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+			if depth == 0 && c == ']' {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("JSONPath 表达式的方括号未闭合: %s", path)
+}
+
+// parseBracketSegment 解析方括号中的内容
+func parseBracketSegment(content string) (jsonPathSegment, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "*":
+		return jsonPathSegment{kind: segmentWildcard}, nil
+
+	case strings.HasPrefix(content, "?"):
+		expr := strings.TrimSpace(content[1:])
+		if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+			return jsonPathSegment{}, fmt.Errorf("过滤表达式必须写成 ?(...) 形式: %s", content)
+		}
+		filter, err := parseJSONPathFilter(expr[1 : len(expr)-1])
+		if err != nil {
+			return jsonPathSegment{}, err
+		}
+		return jsonPathSegment{kind: segmentFilter, filter: filter}, nil
+
+	case strings.HasPrefix(content, "'") || strings.HasPrefix(content, "\""):
+		var names []string
+		for _, part := range splitOutsideQuotes(content, ",") {
+			name, err := unquoteJSONPathString(strings.TrimSpace(part))
+			if err != nil {
+				return jsonPathSegment{}, err
+			}
+			names = append(names, name)
+		}
+		return jsonPathSegment{kind: segmentChild, names: names}, nil
+
+	case strings.Contains(content, ":"):
+		parts := strings.Split(content, ":")
+		if len(parts) > 3 {
+			return jsonPathSegment{}, fmt.Errorf("切片表达式无效: %s", content)
+		}
+		seg := jsonPathSegment{kind: segmentSlice}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return jsonPathSegment{}, fmt.Errorf("切片表达式无效: %s", content)
+			}
+			seg.slice[i] = &n
+		}
+		return seg, nil
+
+	default:
+		seg := jsonPathSegment{kind: segmentIndex}
+		for _, part := range strings.Split(content, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return jsonPathSegment{}, fmt.Errorf("下标表达式无效: %s", content)
+			}
+			seg.indexes = append(seg.indexes, n)
+		}
+		return seg, nil
+	}
+}
+
+// unquoteJSONPathString 去掉单引号或双引号
+func unquoteJSONPathString(s string) (string, error) {
+	if len(s) < 2 || (s[0] != '\'' && s[0] != '"') || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("字符串缺少引号: %s", s)
+	}
+	inner := s[1 : len(s)-1]
+	inner = strings.ReplaceAll(inner, "\\"+string(s[0]), string(s[0]))
+	return strings.ReplaceAll(inner, "\\\\", "\\"), nil
+}
+
+// splitOutsideQuotes 按分隔符拆分字符串，忽略引号和括号中的分隔符
+func splitOutsideQuotes(s string, sep string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// evalJSONPath 依次应用路径片段
+func evalJSONPath(root any, segments []jsonPathSegment) []any {
+	nodes := []any{root}
+	for _, seg := range segments {
+		if seg.recursive {
+			nodes = descendants(nodes)
+		}
+		next := make([]any, 0, len(nodes))
+		for _, node := range nodes {
+			next = append(next, applySegment(node, seg)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// descendants 返回节点自身及其所有后代节点（按文档顺序）
+func descendants(nodes []any) []any {
+	var result []any
+	var walk func(node any)
+	walk = func(node any) {
+		result = append(result, node)
+		for _, child := range children(node) {
+			walk(child)
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+	return result
+}
+
+// children 返回节点的所有直接子节点，对象按键名排序以保证结果稳定
+func children(node any) []any {
+	switch v := node.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		keys := sortedKeys(v)
+		result := make([]any, 0, len(keys))
+		for _, k := range keys {
+			result = append(result, v[k])
+		}
+		return result
+	}
+	return nil
+}
+
+// sortedKeys 返回对象的键名（升序）
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// 插入排序，对象键通常很少
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	return keys
+}
+
+// applySegment 对单个节点应用路径片段
+func applySegment(node any, seg jsonPathSegment) []any {
+	switch seg.kind {
+	case segmentChild:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		var result []any
+		for _, name := range seg.names {
+			if v, ok := obj[name]; ok {
+				result = append(result, v)
+			}
+		}
+		return result
+
+	case segmentWildcard:
+		return children(node)
+
+	case segmentIndex:
+		arr, ok := node.([]any)
+		if !ok {
+			return nil
+		}
+		var result []any
+		for _, idx := range seg.indexes {
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				result = append(result, arr[idx])
+			}
+		}
+		return result
+
+	case segmentSlice:
+		arr, ok := node.([]any)
+		if !ok {
+			return nil
+		}
+		return sliceArray(arr, seg.slice)
+
+	case segmentFilter:
+		var result []any
+		for _, child := range children(node) {
+			if seg.filter.match(child) {
+				result = append(result, child)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// sliceArray 按 Python 风格的切片规则截取数组
+func sliceArray(arr []any, slice [3]*int) []any {
+	n := len(arr)
+	step := 1
+	if slice[2] != nil {
+		step = *slice[2]
+	}
+	if step == 0 {
+		return nil
+	}
+
+	normalize := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		v := *p
+		if v < 0 {
+			v += n
+		}
+		if v < 0 {
+			v = -1
+			if step > 0 {
+				v = 0
+			}
+		}
+		if v > n {
+			v = n
+		}
+		return v
+	}
+
+	var result []any
+	if step > 0 {
+		start, end := normalize(slice[0], 0), normalize(slice[1], n)
+		for i := start; i < end; i += step {
+			result = append(result, arr[i])
+		}
+	} else {
+		start, end := normalize(slice[0], n-1), normalize(slice[1], -1)
+		if start >= n {
+			start = n - 1
+		}
+		for i := start; i > end; i += step {
+			result = append(result, arr[i])
+		}
+	}
+	return result
+}
+
+// jsonPathFilter 过滤表达式，外层为 || 连接的条件组，内层为 && 连接的条件
+type jsonPathFilter [][]jsonPathCondition
+
+// jsonPathCondition 单个过滤条件
+type jsonPathCondition struct {
+	left  jsonPathOperand
+	op    string // 为空时表示存在性判断
+	right jsonPathOperand
+}
+
+// jsonPathOperand 比较操作数：相对路径或字面量
+type jsonPathOperand struct {
+	path    []jsonPathSegment
+	isPath  bool
+	literal any
+}
+
+// jsonPathOperators 支持的比较运算符（长的在前，避免 <= 被识别为 <）
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseJSONPathFilter 解析过滤表达式
+func parseJSONPathFilter(expr string) (jsonPathFilter, error) {
+	var filter jsonPathFilter
+	for _, orPart := range splitOutsideQuotes(expr, "||") {
+		var group []jsonPathCondition
+		for _, andPart := range splitOutsideQuotes(orPart, "&&") {
+			cond, err := parseJSONPathCondition(strings.TrimSpace(andPart))
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, cond)
+		}
+		filter = append(filter, group)
+	}
+	return filter, nil
+}
+
+// parseJSONPathCondition 解析单个过滤条件
+func parseJSONPathCondition(expr string) (jsonPathCondition, error) {
+	// 去掉多余的括号
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	if expr == "" {
+		return jsonPathCondition{}, fmt.Errorf("过滤条件为空")
+	}
+
+	for _, op := range jsonPathOperators {
+		parts := splitOutsideQuotes(expr, op)
+		if len(parts) != 2 {
+			continue
+		}
+		left, err := parseJSONPathOperand(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return jsonPathCondition{}, err
+		}
+		right, err := parseJSONPathOperand(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return jsonPathCondition{}, err
+		}
+		return jsonPathCondition{left: left, op: op, right: right}, nil
+	}
+
+	// 没有运算符时为存在性判断
+	operand, err := parseJSONPathOperand(expr)
+	if err != nil {
+		return jsonPathCondition{}, err
+	}
+	if !operand.isPath {
+		return jsonPathCondition{}, fmt.Errorf("过滤条件无效: %s", expr)
+	}
+	return jsonPathCondition{left: operand}, nil
+}
+
+// parseJSONPathOperand 解析操作数
+func parseJSONPathOperand(s string) (jsonPathOperand, error) {
+	switch {
+	case strings.HasPrefix(s, "@"):
+		path, err := parseJSONPath(s, '@')
+		if err != nil {
+			return jsonPathOperand{}, err
+		}
+		return jsonPathOperand{path: path, isPath: true}, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, "\""):
+		str, err := unquoteJSONPathString(s)
+		if err != nil {
+			return jsonPathOperand{}, err
+		}
+		return jsonPathOperand{literal: str}, nil
+	case s == "true":
+		return jsonPathOperand{literal: true}, nil
+	case s == "false":
+		return jsonPathOperand{literal: false}, nil
+	case s == "null":
+		return jsonPathOperand{literal: nil}, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return jsonPathOperand{}, fmt.Errorf("无法识别的过滤操作数: %s", s)
+	}
+	return jsonPathOperand{literal: n}, nil
+}
+
+// match 判断节点是否满足过滤表达式
+func (f jsonPathFilter) match(node any) bool {
+	for _, group := range f {
+		matched := true
+		for _, cond := range group {
+			if !cond.match(node) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve 计算操作数的值
+func (o jsonPathOperand) resolve(node any) (any, bool) {
+	if !o.isPath {
+		return o.literal, true
+	}
+	values := evalJSONPath(node, o.path)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// match 判断节点是否满足条件
+func (c jsonPathCondition) match(node any) bool {
+	left, ok := c.left.resolve(node)
+	if c.op == "" {
+		return ok
+	}
+	if !ok {
+		return false
+	}
+	right, ok := c.right.resolve(node)
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "==":
+		return jsonValuesEqual(left, right)
+	case "!=":
+		return !jsonValuesEqual(left, right)
+	}
+
+	// 大小比较仅支持数字与数字、字符串与字符串
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		return compareOrdered(l, r, c.op)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		return compareOrdered(l, r, c.op)
+	}
+	return false
+}
+
+// jsonValuesEqual 比较两个 JSON 值是否相等（仅比较标量）
+func jsonValuesEqual(a, b any) bool {
+	switch a.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	switch b.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return a == b
+}
+
+// compareOrdered 按运算符比较两个可排序的值
+func compareOrdered[T float64 | string](l, r T, op string) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
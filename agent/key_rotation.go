@@ -0,0 +1,236 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// RotationStrategy API 密钥轮换策略
+type RotationStrategy int
+
+const (
+	RoundRobin RotationStrategy = iota // 依次轮流使用
+	Random                             // 随机选择
+)
+
+// 失败密钥的退避时间：第 n 次连续失败后排除 keyBackoffBase * 2^(n-1)，最长 keyBackoffMax
+const (
+	keyBackoffBase = time.Second
+	keyBackoffMax  = 5 * time.Minute
+)
+
+// ErrAllAPIKeysRevoked 轮换中的所有 API 密钥都因 401 被停用时返回的错误
+var ErrAllAPIKeysRevoked = errors.New("所有 API 密钥均已失效")
+
+// keyState 单个密钥的退避状态
+type keyState struct {
+	failures int       // 连续失败次数
+	until    time.Time // 在此时间之前不参与轮换
+	revoked  bool      // 是否因 401 被停用，停用后本次运行不再使用
+}
+
+// keyRotator API 密钥轮换器，由 Engine 及其所有副本共享
+type keyRotator struct {
+	mu       sync.Mutex
+	provider string // 密钥所属的提供商，切换到其他提供商时不使用轮换
+	keys     []string
+	strategy RotationStrategy
+	next     int // RoundRobin 下一个候选密钥的下标
+	states   map[string]*keyState
+}
+
+// SetAPIKeyRotation 为当前提供商设置多个 API 密钥轮流使用
+// QueryHandler 每次调用模型前按策略选择密钥，返回 429 的密钥会按指数退避暂时排除，所有密钥都在退避中时使用最早恢复的那个；
+// 返回 401 的密钥视为已过期或被吊销，停用后立即换用下一个密钥重试（不计入模型的尝试次数），所有密钥都停用后返回 ErrAllAPIKeysRevoked。
+// 切换到其他提供商后使用该提供商配置的密钥
+// 参数:
+//   - keys: API 密钥列表，为空时取消轮换
+//   - strategy: 轮换策略
+func (engine *Engine) SetAPIKeyRotation(keys []string, strategy RotationStrategy) {
+	if len(keys) == 0 {
+		engine.keyRotation = nil
+		return
+	}
+	rotator := &keyRotator{
+		provider: engine.providerName,
+		keys:     append([]string(nil), keys...),
+		strategy: strategy,
+		states:   make(map[string]*keyState, len(keys)),
+	}
+	for _, k := range keys {
+		rotator.states[k] = &keyState{}
+	}
+	engine.keyRotation = rotator
+}
+
+// nextAPIKey 返回下一次调用使用的 API 密钥，未设置轮换时返回当前提供商的密钥
+func (engine *Engine) nextAPIKey() (string, error) {
+	r := engine.keyRotation
+	if r == nil || r.provider != engine.providerName {
+		return engine.apiKey, nil
+	}
+	key, ok := r.pick(time.Now())
+	if !ok {
+		return "", fmt.Errorf("%w（提供商 %s）", ErrAllAPIKeysRevoked, r.provider)
+	}
+	return key, nil
+}
+
+// reportAPIKeyResult 记录密钥的调用结果，用于更新退避和停用状态
+func (engine *Engine) reportAPIKeyResult(key string, err error) {
+	r := engine.keyRotation
+	if r == nil || r.provider != engine.providerName {
+		return
+	}
+	if err == nil {
+		r.succeed(key)
+		return
+	}
+	switch code := statusCodeOf(err); code {
+	case http.StatusUnauthorized:
+		r.revoke(key)
+		engine.loggerFrom(engine.baseContext()).Warn("API 密钥返回 401，已停用", "provider", r.provider, "key", maskAPIKey(key))
+	case http.StatusTooManyRequests:
+		until := r.fail(key, time.Now())
+		engine.loggerFrom(engine.baseContext()).Warn("API 密钥暂时排除出轮换", "provider", r.provider, "status_code", code, "until", until)
+	}
+}
+
+// rotatedClient 使用轮换选出的密钥创建客户端，返回客户端和所用密钥
+func (engine *Engine) rotatedClient() (openai.Client, string, error) {
+	key, err := engine.nextAPIKey()
+	if err != nil {
+		return openai.Client{}, "", err
+	}
+	clone := *engine
+	clone.apiKey = key
+	return clone.newClient(), key, nil
+}
+
+// callWithRotatedKey 使用轮换选出的密钥调用 call
+// 设置了密钥轮换且调用返回 401 时停用该密钥，换用下一个密钥重试，直到成功、返回其他错误或没有可用的密钥
+// 返回:
+//   - string: 最后一次调用使用的密钥
+//   - error: call 返回的错误，或所有密钥都停用时的 ErrAllAPIKeysRevoked
+func (engine *Engine) callWithRotatedKey(call func(client openai.Client) error) (string, error) {
+	var lastErr error
+	for {
+		client, key, err := engine.rotatedClient()
+		if err != nil {
+			if lastErr != nil {
+				return "", fmt.Errorf("%w，最后错误: %w", err, lastErr)
+			}
+			return "", err
+		}
+		err = call(client)
+		engine.reportAPIKeyResult(key, err)
+		if err == nil || statusCodeOf(err) != http.StatusUnauthorized || engine.keyRotation == nil || engine.keyRotation.provider != engine.providerName {
+			return key, err
+		}
+		lastErr = err
+	}
+}
+
+// apiKeyUsed 返回写入结果的脱敏密钥，未对当前提供商设置轮换时为空
+func (engine *Engine) apiKeyUsed(key string) string {
+	r := engine.keyRotation
+	if r == nil || r.provider != engine.providerName {
+		return ""
+	}
+	return maskAPIKey(key)
+}
+
+// maskAPIKey 隐藏密钥中间部分，只保留前 3 位和后 4 位用于区分
+func maskAPIKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:3] + "..." + key[len(key)-4:]
+}
+
+// pick 按策略选择一个未停用且不在退避中的密钥，所有密钥都已停用时返回 false
+func (r *keyRotator) pick(now time.Time) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var active, available []int
+	for i, k := range r.keys {
+		if r.states[k].revoked {
+			continue
+		}
+		active = append(active, i)
+		if !now.Before(r.states[k].until) {
+			available = append(available, i)
+		}
+	}
+	if len(active) == 0 {
+		return "", false
+	}
+
+	// 全部在退避中时使用最早恢复的密钥
+	if len(available) == 0 {
+		earliest := active[0]
+		for _, i := range active {
+			if r.states[r.keys[i]].until.Before(r.states[r.keys[earliest]].until) {
+				earliest = i
+			}
+		}
+		return r.keys[earliest], true
+	}
+
+	if r.strategy == Random {
+		return r.keys[available[rand.IntN(len(available))]], true
+	}
+
+	// RoundRobin：从 next 开始找到第一个可用的密钥
+	for offset := 0; offset < len(r.keys); offset++ {
+		i := (r.next + offset) % len(r.keys)
+		if state := r.states[r.keys[i]]; !state.revoked && !now.Before(state.until) {
+			r.next = (i + 1) % len(r.keys)
+			return r.keys[i], true
+		}
+	}
+	return r.keys[available[0]], true
+}
+
+// revoke 停用密钥，本次运行不再使用
+func (r *keyRotator) revoke(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if state, ok := r.states[key]; ok {
+		state.revoked = true
+	}
+}
+
+// fail 记录密钥失败并返回其恢复时间
+func (r *keyRotator) fail(key string, now time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[key]
+	if !ok {
+		return now
+	}
+	state.failures++
+	backoff := keyBackoffMax
+	if state.failures <= 10 { // 2^9 秒已超过上限，避免移位溢出
+		backoff = min(keyBackoffBase<<(state.failures-1), keyBackoffMax)
+	}
+	state.until = now.Add(backoff)
+	return state.until
+}
+
+// succeed 调用成功后重置密钥的退避状态
+func (r *keyRotator) succeed(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if state, ok := r.states[key]; ok {
+		state.failures = 0
+		state.until = time.Time{}
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrEngineShutdown Engine 已关闭时返回的错误
+var ErrEngineShutdown = errors.New("Engine 已关闭，不再接受新的请求")
+
+// ShutdownHook 关闭时执行的清理函数
+type ShutdownHook func(ctx context.Context) error
+
+// lifecycle 记录 Engine 的运行状态，由 Engine 及其所有副本共享
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+	hooks    []ShutdownHook
+}
+
+// newLifecycle 创建运行状态记录
+func newLifecycle() *lifecycle {
+	return &lifecycle{}
+}
+
+// acquire 登记一个进行中的请求，Engine 已关闭时返回 ErrEngineShutdown
+func (l *lifecycle) acquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrEngineShutdown
+	}
+	l.inflight.Add(1)
+	return nil
+}
+
+// release 标记一个请求已完成
+func (l *lifecycle) release() {
+	l.inflight.Done()
+}
+
+// isClosed 返回 Engine 是否已关闭
+func (l *lifecycle) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// OnShutdown 注册关闭时执行的清理函数
+// 清理函数按注册的相反顺序执行（后注册的先执行）
+// 参数:
+//   - hook: 清理函数
+func (engine *Engine) OnShutdown(hook ShutdownHook) {
+	if engine.lifecycle == nil {
+		return
+	}
+	engine.lifecycle.mu.Lock()
+	defer engine.lifecycle.mu.Unlock()
+	engine.lifecycle.hooks = append(engine.lifecycle.hooks, hook)
+}
+
+// Shutdown 优雅关闭 Engine
+// 停止接受新请求，等待进行中的请求完成（或 ctx 到期），然后依次执行通过 OnShutdown 注册的清理函数
+// Engine 的所有副本共享同一运行状态，关闭任意一个副本即关闭全部；重复调用会直接返回
+// 参数:
+//   - ctx: 上下文，用于限制等待时间
+// 返回:
+//   - error: 等待超时或清理函数返回的错误（多个错误会合并返回）
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	l := engine.lifecycle
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	hooks := l.hooks
+	l.hooks = nil
+	l.mu.Unlock()
+
+	// 等待进行中的请求完成
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("等待进行中的请求完成超时: %w", ctx.Err()))
+	}
+
+	// 按注册的相反顺序执行清理函数
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// registerFlushHooks 注册 Engine 自带的清理函数，创建 Engine 时调用，因此在用户注册的清理函数之后执行：
+// 等待请求日志、token 用量和统计数据的写入完成并同步到磁盘，清空内存中的查询结果缓存并停止过期定时器
+func (engine *Engine) registerFlushHooks() {
+	requestLog, usage, statsFile := engine.requestLog, engine.usage, engine.statsFile
+	engine.OnShutdown(func(ctx context.Context) error {
+		return requestLog.flush()
+	})
+	engine.OnShutdown(func(ctx context.Context) error {
+		return errors.Join(usage.flush(), statsFile.flush())
+	})
+	if cache, ok := engine.responseCache.(*MemoryCache); ok {
+		engine.OnShutdown(func(ctx context.Context) error {
+			cache.Clear()
+			return nil
+		})
+	}
+	if queryCache := engine.queryCache; queryCache != nil {
+		engine.OnShutdown(func(ctx context.Context) error {
+			queryCache.Clear()
+			return nil
+		})
+	}
+}
+
+// syncFile 将文件内容同步到磁盘，文件不存在时不做任何事
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
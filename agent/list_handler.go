@@ -2,22 +2,62 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"maps"
+	"strings"
 )
 
 // ListHandler 实现 EventHandler 接口，处理列表查询事件
 // 用于列出所有可用的提供商和模型信息
 type ListHandler struct{}
 
+// ProviderInfo 单个提供商的详细信息
+type ProviderInfo struct {
+	Name      string   `json:"name"`       // 提供商名称
+	BaseUrl   string   `json:"base_url"`   // 提供商的 base_url
+	Models    []string `json:"models"`     // 该提供商支持的模型列表
+	IsCurrent bool     `json:"is_current"` // 是否为当前使用的提供商
+
+	Metadata      map[string]string         `json:"metadata,omitempty"`       // 提供商的自定义标注
+	ModelMetadata map[string]map[string]any `json:"model_metadata,omitempty"` // 每个模型的元数据（verbose 模式）
+}
+
+// ListResult list 命令的结果
+type ListResult struct {
+	ConfigPath      string         `json:"config_path"`      // 配置文件绝对路径
+	CurrentProvider string         `json:"current_provider"` // 当前提供商名称
+	CurrentModel    string         `json:"current_model"`    // 当前模型ID
+	CurrentBaseUrl  string         `json:"current_base_url"` // 当前使用的 base_url
+	Providers       []ProviderInfo `json:"providers"`        // 所有提供商的详细信息
+	TotalProviders  int            `json:"total_providers"`  // 提供商总数
+
+	Documentation string `json:"documentation,omitempty"` // Markdown 格式的配置参考文档（docs 模式）
+}
+
+// ListRequest list 命令的参数
+type ListRequest struct {
+	Verbose bool `json:"verbose"` // 是否包含每个模型的元数据
+	Docs    bool `json:"docs"`    // 是否生成配置参考文档，见 conf.Config.GenerateDocumentation
+}
+
 // Handle 处理 list 命令，返回所有提供商和模型的信息
 // 参数:
 //   - ctx: 上下文
 //   - engine: Engine 实例
-//   - params: 参数（list 命令不需要参数，可以忽略）
+//   - params: 参数，可选的 JSON 格式 ListRequest，例如 {"verbose":true}、{"docs":true}
 //   - event: 事件类型
 // 返回:
-//   - rsp: 包含所有提供商和模型信息的响应
+//   - rsp: *ListResult 所有提供商和模型的信息
 //   - err: 错误信息
 func (h *ListHandler) Handle(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
+	// 解析可选参数
+	var req ListRequest
+	if strings.HasPrefix(strings.TrimSpace(params), "{") {
+		if err := json.Unmarshal([]byte(params), &req); err != nil {
+			return nil, err
+		}
+	}
+
 	// 获取所有提供商列表
 	providers, err := engine.GetAvailableProviders()
 	if err != nil {
@@ -45,38 +85,63 @@ func (h *ListHandler) Handle(ctx context.Context, engine *Engine, params string,
 	configPath := engine.GetConfigPath()
 
 	// 构建详细的提供商信息列表
-	type ProviderInfo struct {
-		Name      string   `json:"name"`       // 提供商名称
-		BaseUrl   string   `json:"base_url"`   // 提供商的 base_url
-		Models    []string `json:"models"`     // 该提供商支持的模型列表
-		IsCurrent bool     `json:"is_current"` // 是否为当前使用的提供商
-	}
-
 	providerInfos := make([]ProviderInfo, 0, len(providers))
 	for _, providerName := range providers {
-		// 从详细信息中获取 base_url
+		// 从详细信息中获取 base_url 和 metadata
 		baseUrl := ""
+		var metadata map[string]string
 		if providerConfig, ok := allProvidersInfo[providerName]; ok {
 			baseUrl = providerConfig.BaseUrl
+			metadata = maps.Clone(providerConfig.Metadata)
 		}
 
-		providerInfos = append(providerInfos, ProviderInfo{
+		info := ProviderInfo{
 			Name:      providerName,
 			BaseUrl:   baseUrl,
 			Models:    allModels[providerName],
 			IsCurrent: providerName == currentProvider,
-		})
+			Metadata:  metadata,
+		}
+		if req.Verbose {
+			info.ModelMetadata = h.collectModelMetadata(ctx, engine, providerName, info.Models)
+		}
+		providerInfos = append(providerInfos, info)
 	}
 
 	// 构建响应数据
-	rsp = map[string]interface{}{
-		"config_path":       configPath,         // 配置文件绝对路径
-		"current_provider":  currentProvider,    // 当前提供商名称
-		"current_model":     currentModel,       // 当前模型ID
-		"current_base_url":  currentBaseUrl,     // 当前使用的 base_url
-		"providers":         providerInfos,      // 所有提供商的详细信息
-		"total_providers":   len(providers),     // 提供商总数
+	result := &ListResult{
+		ConfigPath:      configPath,
+		CurrentProvider: currentProvider,
+		CurrentModel:    currentModel,
+		CurrentBaseUrl:  currentBaseUrl,
+		Providers:       providerInfos,
+		TotalProviders:  len(providers),
+	}
+	if req.Docs && engine.config != nil {
+		result.Documentation = engine.config.GenerateDocumentation()
 	}
 
-	return rsp, nil
-}
\ No newline at end of file
+	return result, nil
+}
+
+// collectModelMetadata 获取指定提供商下每个模型的元数据，获取失败的模型记录错误信息
+func (h *ListHandler) collectModelMetadata(ctx context.Context, engine *Engine, providerName string, models []string) map[string]map[string]any {
+	logger := LoggerFromContext(ctx).With("handler", "ListHandler")
+
+	clone := engine.WithContext(ctx)
+	if err := clone.SwitchProvider(providerName, ""); err != nil {
+		logger.Warn("切换提供商失败，跳过模型元数据", "provider", providerName, "error", err)
+		return nil
+	}
+
+	metadata := make(map[string]map[string]any, len(models))
+	for _, model := range models {
+		m, err := clone.GetModelMetadata(model)
+		if err != nil {
+			logger.Warn("获取模型元数据失败", "provider", providerName, "model", model, "error", err)
+			m = map[string]any{"error": err.Error()}
+		}
+		metadata[model] = m
+	}
+	return metadata
+}
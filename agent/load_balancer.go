@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"sync"
+)
+
+// roundRobinCounter 记录每个提供商下一次轮询的位置，由 Engine 及其所有副本共享
+type roundRobinCounter struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// newRoundRobinCounter 创建轮询计数器
+func newRoundRobinCounter() *roundRobinCounter {
+	return &roundRobinCounter{next: make(map[string]int)}
+}
+
+// take 返回提供商本次轮询的下标并前移
+func (c *roundRobinCounter) take(provider string, n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.next[provider] % n
+	c.next[provider] = i + 1
+	return i
+}
+
+// peek 返回提供商下一次轮询的下标，不前移
+func (c *roundRobinCounter) peek(provider string, n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.next[provider] % n
+}
+
+// selectionCounter 记录负载均衡选择各模型作为初始模型的次数，由 Engine 及其所有副本共享
+type selectionCounter struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int // 提供商名称 -> 模型ID -> 选择次数
+}
+
+// newSelectionCounter 创建选择计数器
+func newSelectionCounter() *selectionCounter {
+	return &selectionCounter{counts: make(map[string]map[string]int)}
+}
+
+// add 记录一次选择
+func (c *selectionCounter) add(provider, model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[provider] == nil {
+		c.counts[provider] = make(map[string]int)
+	}
+	c.counts[provider][model]++
+}
+
+// snapshot 返回计数的副本
+func (c *selectionCounter) snapshot() map[string]map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make(map[string]map[string]int, len(c.counts))
+	for provider, models := range c.counts {
+		result[provider] = maps.Clone(models)
+	}
+	return result
+}
+
+// LoadBalancingStats 负载均衡选择某个模型作为初始模型的统计
+type LoadBalancingStats struct {
+	ModelID         string  `json:"model_id"`         // 模型ID
+	SelectionCount  int     `json:"selection_count"`  // 被选为初始模型的次数（不含失败后的切换）
+	SelectionWeight float64 `json:"selection_weight"` // 在所属提供商所有选择中的占比（0~1），可与配置的 weight 比较
+}
+
+// selectBalancedModel 按当前提供商的 load_balancing 策略选择本次请求的初始模型，并记录选择结果
+// 返回:
+//   - string: 模型ID，未配置策略时为当前模型
+//   - error: 策略无效时返回错误
+func (engine *Engine) selectBalancedModel() (string, error) {
+	modelId, err := engine.balancedModel(true)
+	if err != nil {
+		return "", err
+	}
+	if engine.selections != nil {
+		if provider, err := engine.config.GetProviderByName(engine.providerName); err == nil && provider.LoadBalancing != "" {
+			engine.selections.add(provider.Name, modelId)
+		}
+	}
+	return modelId, nil
+}
+
+// GetLoadBalancingStats 获取负载均衡选择初始模型的分布，用于检查选择比例是否符合配置
+// 配置了 load_balancing 的提供商的所有模型都会列出（未被选择过的次数为 0）
+// 返回:
+//   - map[string]LoadBalancingStats: 提供商名称/模型ID -> 选择统计
+func (engine *Engine) GetLoadBalancingStats() map[string]LoadBalancingStats {
+	result := make(map[string]LoadBalancingStats)
+	if engine.config == nil || engine.selections == nil {
+		return result
+	}
+
+	counts := engine.selections.snapshot()
+	for _, p := range engine.config.Provider {
+		models := counts[p.Name]
+		if p.LoadBalancing == "" && len(models) == 0 {
+			continue
+		}
+		total := 0
+		for _, n := range models {
+			total += n
+		}
+		add := func(modelId string, n int) {
+			stats := LoadBalancingStats{ModelID: modelId, SelectionCount: n}
+			if total > 0 {
+				stats.SelectionWeight = float64(n) / float64(total)
+			}
+			result[p.Name+"/"+modelId] = stats
+		}
+		for _, m := range p.Models {
+			add(m.ID, models[m.ID])
+		}
+		// 按查询切换模型等情况下选择的模型可能不在配置列表中
+		for modelId, n := range models {
+			if !p.HasModel(modelId) {
+				add(modelId, n)
+			}
+		}
+	}
+	return result
+}
+
+// balancedModel 按负载均衡策略选择模型，advance 为 false 时不前移 round-robin 的轮询位置（用于预览）
+func (engine *Engine) balancedModel(advance bool) (string, error) {
+	if engine.config == nil {
+		return "", fmt.Errorf("配置未加载")
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return "", fmt.Errorf("获取当前提供商配置失败: %w", err)
+	}
+	models := provider.Models
+	if provider.LoadBalancing == "" || len(models) == 0 {
+		return engine.ModelId, nil
+	}
+
+	switch provider.LoadBalancing {
+	case conf.LoadBalancingFirst:
+		return models[0].ID, nil
+	case conf.LoadBalancingRandom:
+		return models[rand.IntN(len(models))].ID, nil
+	case conf.LoadBalancingRoundRobin:
+		if engine.roundRobin == nil {
+			return models[0].ID, nil
+		}
+		if !advance {
+			return models[engine.roundRobin.peek(provider.Name, len(models))].ID, nil
+		}
+		return models[engine.roundRobin.take(provider.Name, len(models))].ID, nil
+	case conf.LoadBalancingWeightedRandom:
+		return weightedRandomModel(models), nil
+	case conf.LoadBalancingLeastLatency:
+		return engine.leastLatencyModel(provider), nil
+	default:
+		return "", fmt.Errorf("提供商 %s 的负载均衡策略 %s 无效", provider.Name, provider.LoadBalancing)
+	}
+}
+
+// weightedRandomModel 按权重随机选择模型，未配置权重（小于等于 0）的模型按权重 1 计算
+func weightedRandomModel(models []conf.ModelConfig) string {
+	total := 0
+	for _, m := range models {
+		total += max(m.Weight, 1)
+	}
+	n := rand.IntN(total)
+	for _, m := range models {
+		n -= max(m.Weight, 1)
+		if n < 0 {
+			return m.ID
+		}
+	}
+	return models[len(models)-1].ID
+}
+
+// leastLatencyModel 选择滚动平均延迟最低的模型
+// 还没有延迟记录的模型优先（按配置顺序），以便为每个模型收集样本
+func (engine *Engine) leastLatencyModel(provider *conf.ProviderConfig) string {
+	best, bestLatency := "", int64(-1)
+	for _, m := range provider.Models {
+		avg, ok := engine.GetAverageLatency(provider.Name, m.ID)
+		if !ok {
+			return m.ID
+		}
+		if bestLatency < 0 || int64(avg) < bestLatency {
+			best, bestLatency = m.ID, int64(avg)
+		}
+	}
+	return best
+}
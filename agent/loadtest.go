@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 负载测试的错误类型（HTTP 错误为 http_<状态码>）
+const (
+	LoadTestErrorTimeout  = "timeout"  // 超过截止时间
+	LoadTestErrorCanceled = "canceled" // 上下文被取消
+	LoadTestErrorNetwork  = "network"  // 网络错误
+	LoadTestErrorOther    = "other"    // 其他错误
+)
+
+// LoadTestConfig 负载测试的参数
+type LoadTestConfig struct {
+	RPS      float64       `json:"rps"`      // 目标每秒请求数
+	Duration time.Duration `json:"duration"` // 发送请求的时长
+	Query    string        `json:"query"`    // 每次请求的查询内容
+	Ramp     bool          `json:"ramp"`     // 是否从 1 RPS 线性增加到目标 RPS，否则从一开始就以目标 RPS 发送
+}
+
+// LoadTestReport 负载测试报告
+type LoadTestReport struct {
+	TotalRequests int            `json:"total_requests"` // 发送的请求数
+	SuccessRate   float64        `json:"success_rate"`   // 成功率（0~1）
+	ActualRPS     float64        `json:"actual_rps"`     // 实际每秒请求数（请求数 / 测试时长）
+	P50           time.Duration  `json:"p50"`            // 成功请求的 p50 延迟
+	P95           time.Duration  `json:"p95"`            // 成功请求的 p95 延迟
+	P99           time.Duration  `json:"p99"`            // 成功请求的 p99 延迟
+	ErrorsByType  map[string]int `json:"errors_by_type"` // 错误类型 -> 次数，见 LoadTestError* 常量
+}
+
+// RunLoadTest 按固定速率发送查询进行负载测试，用于容量规划
+// 请求按计划时间发出，不等待之前的请求完成；每次请求都经过完整的查询流程（中间件、并发限制、重试和模型轮换），
+// 与真实调用的表现一致。发送结束后等待进行中的请求完成再生成报告
+// 参数:
+//   - ctx: 上下文，取消后停止发送新请求
+//   - engine: Engine 实例
+//   - config: 负载测试参数
+// 返回:
+//   - *LoadTestReport: 负载测试报告
+//   - error: 参数无效或测试被取消时返回错误
+func RunLoadTest(ctx context.Context, engine *Engine, config LoadTestConfig) (*LoadTestReport, error) {
+	if config.RPS <= 0 {
+		return nil, fmt.Errorf("RPS 必须大于 0，当前为 %g", config.RPS)
+	}
+	if config.Duration <= 0 {
+		return nil, fmt.Errorf("测试时长必须大于 0，当前为 %s", config.Duration)
+	}
+	if config.Query == "" {
+		return nil, fmt.Errorf("查询内容不能为空")
+	}
+	logger := engine.loggerFrom(ctx).With("handler", "RunLoadTest")
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		report    = &LoadTestReport{ErrorsByType: make(map[string]int)}
+	)
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, fmt.Errorf("负载测试被取消: %w", ctx.Err())
+		case <-timer.C:
+		}
+		elapsed := time.Since(start)
+		if elapsed >= config.Duration {
+			break
+		}
+
+		report.TotalRequests++
+		wg.Go(func() {
+			sent := time.Now()
+			// 请求并发发送，QueryHandler 处理过程中会切换模型，每个请求使用独立的副本
+			_, err := engine.Clone().Query(ctx, config.Query)
+			latency := time.Since(sent)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.ErrorsByType[loadTestErrorType(err)]++
+				return
+			}
+			latencies = append(latencies, latency)
+		})
+		timer.Reset(time.Duration(float64(time.Second) / config.rateAt(elapsed)))
+	}
+	testDuration := time.Since(start)
+	wg.Wait()
+
+	report.SuccessRate = float64(len(latencies)) / float64(report.TotalRequests)
+	report.ActualRPS = float64(report.TotalRequests) / testDuration.Seconds()
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		report.P50 = percentile(latencies, 50)
+		report.P95 = percentile(latencies, 95)
+		report.P99 = percentile(latencies, 99)
+	}
+	logger.Info("负载测试完成", "rps", config.RPS, "duration", config.Duration, "ramp", config.Ramp,
+		"total_requests", report.TotalRequests, "success_rate", report.SuccessRate, "p50", report.P50, "p99", report.P99)
+	return report, nil
+}
+
+// rateAt 返回测试开始 elapsed 后的发送速率；Ramp 时从 1 RPS 线性增加到目标 RPS（目标低于 1 时保持目标速率）
+func (c LoadTestConfig) rateAt(elapsed time.Duration) float64 {
+	if !c.Ramp || c.RPS <= 1 {
+		return c.RPS
+	}
+	progress := min(float64(elapsed)/float64(c.Duration), 1)
+	return 1 + (c.RPS-1)*progress
+}
+
+// loadTestErrorType 返回负载测试中请求失败的错误类型
+func loadTestErrorType(err error) string {
+	if code := statusCodeOf(err); code != 0 {
+		return fmt.Sprintf("http_%d", code)
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return LoadTestErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return LoadTestErrorCanceled
+	case errors.As(err, &netErr):
+		return LoadTestErrorNetwork
+	default:
+		return LoadTestErrorOther
+	}
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithMaxResponseTokens 返回限制每次查询最多生成 n 个 token 的 Engine 副本
+// 查询时以 max_tokens 参数发送；模型不支持该参数时记录 warn 日志，并去掉限制重新调用（不计入尝试次数）
+// 参数:
+//   - n: 最多生成的 token 数，覆盖提供商配置的 max_tokens；小于等于 0 时使用配置，未配置时不限制
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithMaxResponseTokens(n int) *Engine {
+	clone := engine.Clone()
+	clone.maxResponseTokens = max(n, 0)
+	return clone
+}
+
+// GetMaxResponseTokens 获取每次查询最多生成的 token 数
+// 返回:
+//   - int: 最多生成的 token 数，0 表示不限制
+func (engine *Engine) GetMaxResponseTokens() int {
+	return engine.maxResponseTokens
+}
+
+// maxTokensUnsupported 调用失败是否因为模型不支持 max_tokens 参数（请求参数错误且错误信息提到 max_tokens）
+func maxTokensUnsupported(err error) bool {
+	switch statusCodeOf(err) {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return strings.Contains(strings.ToLower(err.Error()), "max_tokens")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportMetrics 支持的导出格式（另见 FormatJSON）
+const (
+	FormatPrometheus = "prometheus" // Prometheus 文本格式
+	FormatCSV        = "csv"        // 每行一个指标: metric,labels,value
+)
+
+// MetricsSnapshot 某一时刻的统计数据快照，ExportMetrics 以 json 格式导出时的结构
+type MetricsSnapshot struct {
+	Time              time.Time                     `json:"time"`                // 生成快照的时间
+	Providers         map[string]HealthStatus       `json:"providers"`           // 提供商名称 -> 延迟、错误率、熔断器状态等，见 GetProviderHealth
+	Models            []ModelMetrics                `json:"models"`              // 有成功调用记录的模型，按配置顺序排列
+	EmbeddingCache    CacheStats                    `json:"embedding_cache"`     // 嵌入向量缓存统计，见 GetCacheStats
+	QueryCacheEntries int                           `json:"query_cache_entries"` // 查询结果缓存的条目数，未启用时为 0
+	LoadBalancing     map[string]LoadBalancingStats `json:"load_balancing"`      // 提供商名称/模型ID -> 选择统计，见 GetLoadBalancingStats
+	TokenUsage        []TokenBudget                 `json:"token_usage"`         // 当前计费周期的 token 用量，见 GetTokenBudgets
+}
+
+// ModelMetrics 单个模型最近成功调用的统计
+type ModelMetrics struct {
+	Provider        string        `json:"provider"`          // 提供商名称
+	Model           string        `json:"model"`             // 模型ID
+	AverageLatency  time.Duration `json:"average_latency"`   // 滚动平均延迟，见 GetAverageLatency
+	TokensPerSecond float64       `json:"tokens_per_second"` // 滚动平均生成速度，没有记录时为 0
+}
+
+// prometheusLabelEscaper 转义 Prometheus 文本格式中的标签值
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricSample 导出为 Prometheus 和 CSV 格式时的一个指标值
+type metricSample struct {
+	name   string
+	help   string
+	kind   string   // gauge 或 counter
+	labels []string // 标签名和值交替排列
+	value  float64
+}
+
+// ExportMetrics 导出当前统计数据的快照，用于无法直接抓取 Prometheus 指标的监控系统
+// 包含提供商的延迟、错误率和熔断器状态、模型的延迟和生成速度、缓存统计、负载均衡选择统计和 token 用量
+// 参数:
+//   - format: prometheus（文本格式）、json 或 csv
+// 返回:
+//   - []byte: 导出的内容
+//   - error: 格式不支持、配置未加载或读取用量文件失败时返回错误
+func (engine *Engine) ExportMetrics(format string) ([]byte, error) {
+	snapshot, err := engine.metricsSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("序列化统计数据失败: %w", err)
+		}
+		return data, nil
+	case FormatPrometheus:
+		return prometheusText(snapshot.samples()), nil
+	case FormatCSV:
+		return metricsCSV(snapshot.samples())
+	default:
+		return nil, fmt.Errorf("不支持的导出格式 %s，可选值: prometheus、json、csv", format)
+	}
+}
+
+// metricsSnapshot 收集当前的统计数据
+func (engine *Engine) metricsSnapshot() (*MetricsSnapshot, error) {
+	usage, err := engine.GetTokenBudgets()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &MetricsSnapshot{
+		Time:           time.Now(),
+		Providers:      engine.GetProviderHealth(engine.baseContext()),
+		EmbeddingCache: engine.GetCacheStats(),
+		LoadBalancing:  engine.GetLoadBalancingStats(),
+		TokenUsage:     usage,
+	}
+	if engine.queryCache != nil {
+		snapshot.QueryCacheEntries = engine.queryCache.Len()
+	}
+	for _, p := range engine.config.Provider {
+		for _, modelId := range p.ModelIDs() {
+			latency, ok := engine.GetAverageLatency(p.Name, modelId)
+			if !ok {
+				continue
+			}
+			m := ModelMetrics{Provider: p.Name, Model: modelId, AverageLatency: latency}
+			m.TokensPerSecond, _ = engine.stats.averageTPS(p.Name, modelId)
+			snapshot.Models = append(snapshot.Models, m)
+		}
+	}
+	return snapshot, nil
+}
+
+// samples 将快照展开为指标值，同名指标相邻排列
+func (s *MetricsSnapshot) samples() []metricSample {
+	var samples []metricSample
+	add := func(name, kind, help string, value float64, labels ...string) {
+		samples = append(samples, metricSample{name: name, help: help, kind: kind, labels: labels, value: value})
+	}
+	boolValue := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	// TokenUsage 按配置顺序排列，提供商的指标也按该顺序输出
+	providers := make([]string, 0, len(s.TokenUsage))
+	for _, u := range s.TokenUsage {
+		providers = append(providers, u.Provider)
+	}
+	for _, p := range providers {
+		add("agent_engine_provider_latency_seconds", "gauge", "提供商所有模型最近成功调用的平均延迟（秒）", s.Providers[p].Latency.Seconds(), "provider", p)
+	}
+	for _, p := range providers {
+		add("agent_engine_provider_error_rate", "gauge", "提供商最近 20 次调用的错误率", s.Providers[p].ErrorRate, "provider", p)
+	}
+	for _, p := range providers {
+		add("agent_engine_provider_circuit_breaker_open", "gauge", "提供商的熔断器是否打开（1 为打开）", boolValue(s.Providers[p].CircuitBreakerState == CircuitOpen), "provider", p)
+	}
+	for _, p := range providers {
+		for _, status := range []string{HealthHealthy, HealthDegraded, HealthUnhealthy} {
+			add("agent_engine_provider_health", "gauge", "提供商的健康状态（当前状态为 1）", boolValue(s.Providers[p].Status == status), "provider", p, "status", status)
+		}
+	}
+	for _, p := range providers {
+		if last := s.Providers[p].LastSuccess; !last.IsZero() {
+			add("agent_engine_provider_last_success_timestamp_seconds", "gauge", "提供商最近一次成功调用的 Unix 时间", float64(last.Unix()), "provider", p)
+		}
+	}
+
+	for _, m := range s.Models {
+		add("agent_engine_model_latency_seconds", "gauge", "模型最近成功调用的平均延迟（秒）", m.AverageLatency.Seconds(), "provider", m.Provider, "model", m.Model)
+	}
+	for _, m := range s.Models {
+		add("agent_engine_model_tokens_per_second", "gauge", "模型最近成功调用的平均生成速度", m.TokensPerSecond, "provider", m.Provider, "model", m.Model)
+	}
+
+	for _, u := range s.TokenUsage {
+		add("agent_engine_token_usage", "gauge", "当前计费周期已使用的 token 数", float64(u.Used), "provider", u.Provider, "period", u.Period)
+	}
+	for _, u := range s.TokenUsage {
+		if u.Budget > 0 {
+			add("agent_engine_token_budget", "gauge", "每月 token 预算", float64(u.Budget), "provider", u.Provider, "period", u.Period)
+		}
+	}
+
+	cache := s.EmbeddingCache
+	add("agent_engine_embedding_cache_hits_total", "counter", "嵌入向量缓存命中次数", float64(cache.Hits))
+	add("agent_engine_embedding_cache_misses_total", "counter", "嵌入向量缓存未命中次数", float64(cache.Misses))
+	add("agent_engine_embedding_cache_evictions_total", "counter", "嵌入向量缓存因容量不足淘汰的条目数", float64(cache.Evictions))
+	add("agent_engine_embedding_cache_entries", "gauge", "嵌入向量缓存的条目数", float64(cache.EntryCount))
+	add("agent_engine_embedding_cache_size_bytes", "gauge", "嵌入向量缓存占用的字节数（估算）", float64(cache.SizeBytes))
+	add("agent_engine_query_cache_entries", "gauge", "查询结果缓存的条目数", float64(s.QueryCacheEntries))
+
+	keys := slices.Sorted(maps.Keys(s.LoadBalancing))
+	for _, key := range keys {
+		provider, _, _ := strings.Cut(key, "/")
+		lb := s.LoadBalancing[key]
+		add("agent_engine_load_balancing_selections_total", "counter", "负载均衡选择为初始模型的次数", float64(lb.SelectionCount), "provider", provider, "model", lb.ModelID)
+	}
+	for _, key := range keys {
+		provider, _, _ := strings.Cut(key, "/")
+		lb := s.LoadBalancing[key]
+		add("agent_engine_load_balancing_selection_weight", "gauge", "模型在所属提供商所有选择中的占比", lb.SelectionWeight, "provider", provider, "model", lb.ModelID)
+	}
+	return samples
+}
+
+// prometheusText 将指标值格式化为 Prometheus 文本格式
+func prometheusText(samples []metricSample) []byte {
+	var buf bytes.Buffer
+	for i, sample := range samples {
+		if i == 0 || samples[i-1].name != sample.name {
+			fmt.Fprintf(&buf, "# HELP %s %s\n", sample.name, sample.help)
+			fmt.Fprintf(&buf, "# TYPE %s %s\n", sample.name, sample.kind)
+		}
+		buf.WriteString(sample.name)
+		if len(sample.labels) > 0 {
+			buf.WriteByte('{')
+			for j := 0; j < len(sample.labels); j += 2 {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, `%s="%s"`, sample.labels[j], prometheusLabelEscaper.Replace(sample.labels[j+1]))
+			}
+			buf.WriteByte('}')
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// metricsCSV 将指标值格式化为 CSV，标签格式为 name=value，多个标签以 ; 分隔
+func metricsCSV(samples []metricSample) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	records := [][]string{{"metric", "labels", "value"}}
+	for _, sample := range samples {
+		labels := make([]string, 0, len(sample.labels)/2)
+		for j := 0; j < len(sample.labels); j += 2 {
+			labels = append(labels, sample.labels[j]+"="+sample.labels[j+1])
+		}
+		records = append(records, []string{sample.name, strings.Join(labels, ";"), strconv.FormatFloat(sample.value, 'g', -1, 64)})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("导出 CSV 失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
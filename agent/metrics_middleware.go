@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// handlerMetrics 事件处理的 Prometheus 指标
+type handlerMetrics struct {
+	requests *prometheus.CounterVec   // 请求数，按事件类型、提供商、模型和结果统计
+	errors   *prometheus.CounterVec   // 失败的请求数
+	duration *prometheus.HistogramVec // 处理耗时（秒）
+}
+
+// metricsLabels 指标的标签
+var metricsLabels = []string{"event", "provider", "model"}
+
+// newHandlerMetrics 创建指标并注册到 reg，reg 中已注册过同名指标时复用已注册的指标
+func newHandlerMetrics(reg prometheus.Registerer) (*handlerMetrics, error) {
+	m := &handlerMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_engine_requests_total",
+			Help: "事件请求数，status 为 success 或 error",
+		}, append(metricsLabels, "status")),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_engine_request_errors_total",
+			Help: "处理失败的事件请求数",
+		}, metricsLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "agent_engine_request_duration_seconds",
+			Help:    "事件请求的处理耗时（秒）",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, metricsLabels),
+	}
+	var err error
+	if m.requests, err = registerCollector(reg, m.requests); err != nil {
+		return nil, err
+	}
+	if m.errors, err = registerCollector(reg, m.errors); err != nil {
+		return nil, err
+	}
+	if m.duration, err = registerCollector(reg, m.duration); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// registerCollector 注册指标，已注册过相同指标时返回已注册的实例
+func registerCollector[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	err := reg.Register(c)
+	if err == nil {
+		return c, nil
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+	return c, fmt.Errorf("注册 Prometheus 指标失败: %w", err)
+}
+
+// MetricsMiddleware 返回记录 Prometheus 指标的处理器中间件：请求数（agent_engine_requests_total）、
+// 失败数（agent_engine_request_errors_total）和处理耗时（agent_engine_request_duration_seconds），
+// 标签为事件类型、提供商和模型；query 事件使用实际调用的提供商和模型，其他事件使用处理开始时的提供商和模型
+// 通常通过 Engine.WithMetrics 为所有事件添加，也可以通过 WithEventHandlerMiddleware 只为单个事件添加
+// 参数:
+//   - reg: 指标注册器，如 prometheus.DefaultRegisterer；已注册过相同指标时复用
+// 返回:
+//   - HandlerMiddleware: 处理器中间件，注册指标失败时 panic（与 prometheus.MustRegister 一致）
+func MetricsMiddleware(reg prometheus.Registerer) HandlerMiddleware {
+	m, err := newHandlerMetrics(reg)
+	if err != nil {
+		panic(err)
+	}
+	return m.middleware
+}
+
+// middleware 包装处理器并记录指标
+func (m *handlerMetrics) middleware(next EventHandler) EventHandler {
+	return EventHandlerFunc(func(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
+		provider, model := engine.GetCurrentProviderName(), engine.ModelId
+		start := time.Now()
+		rsp, err = next.Handle(ctx, engine, params, event)
+
+		if result, ok := rsp.(*QueryResult); ok && result != nil {
+			provider, model = result.ProviderUsed, result.ModelUsed
+		}
+		status := "success"
+		if err != nil {
+			status = "error"
+			m.errors.WithLabelValues(event, provider, model).Inc()
+		}
+		m.requests.WithLabelValues(event, provider, model, status).Inc()
+		m.duration.WithLabelValues(event, provider, model).Observe(time.Since(start).Seconds())
+		return rsp, err
+	})
+}
+
+// WithMetrics 返回为所有事件记录 Prometheus 指标的 Engine 副本，指标见 MetricsMiddleware
+// 指标中间件位于所有通过 WithEventHandlerMiddleware 添加的中间件外层，处理器本身不需要关心指标
+// 参数:
+//   - reg: 指标注册器，如 prometheus.DefaultRegisterer；已注册过相同指标时复用
+// 返回:
+//   - *Engine: Engine 副本指针
+//   - error: 注册指标失败时返回错误
+func (engine *Engine) WithMetrics(reg prometheus.Registerer) (*Engine, error) {
+	m, err := newHandlerMetrics(reg)
+	if err != nil {
+		return nil, err
+	}
+	clone := engine.Clone()
+	clone.metricsMiddleware = m.middleware
+	return clone, nil
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 模型名称中表示能力档位的关键词，用于判断两个模型是否处于同一档位
+var (
+	lowTierTokens  = map[string]bool{"mini": true, "nano": true, "lite": true, "flash": true, "small": true, "tiny": true, "haiku": true, "instant": true, "free": true}
+	highTierTokens = map[string]bool{"pro": true, "max": true, "large": true, "ultra": true, "opus": true, "plus": true, "reasoner": true, "r1": true}
+)
+
+// SetSmartFallback 设置模型调用失败后是否按相似度选择替代模型
+// 开启后 QueryHandler 轮换模型时使用 SuggestAlternativeModels 的结果，关闭时随机选择未尝试过的模型
+// 参数:
+//   - enabled: 是否开启
+func (engine *Engine) SetSmartFallback(enabled bool) {
+	engine.smartFallback = enabled
+}
+
+// SetCrossProviderFallback 设置当前提供商的模型都调用失败后是否切换到其他提供商
+// 开启后 QueryHandler 按配置顺序依次切换到其余提供商（从其默认模型开始）并重新轮换模型，直到调用成功或所有提供商都失败；
+// 请求指定了提供商（override_provider）时不切换。处理结束后恢复原来的提供商和模型
+// 参数:
+//   - enabled: 是否开启
+func (engine *Engine) SetCrossProviderFallback(enabled bool) {
+	engine.providerFallback = enabled
+}
+
+// crossProviderCandidates 返回跨提供商回退时依次尝试的提供商：按配置顺序排列的除当前提供商外的所有提供商，未开启时为空
+func (engine *Engine) crossProviderCandidates(req *QueryRequest) []string {
+	if !engine.providerFallback || req.OverrideProvider != "" || engine.config == nil {
+		return nil
+	}
+	var candidates []string
+	for _, p := range engine.config.Provider {
+		if p.Name != engine.GetCurrentProviderName() {
+			candidates = append(candidates, p.Name)
+		}
+	}
+	return candidates
+}
+
+// SuggestAlternativeModels 为调用失败的模型推荐当前提供商中的替代模型
+// 根据配置文件中的模型元数据（能力、上下文长度）和模型名称（系列、档位、名称片段）估算相似度，
+// 不请求远程接口，以免在故障切换时增加延迟
+// 参数:
+//   - ctx: 上下文
+//   - failedModel: 调用失败的模型ID或别名
+// 返回:
+//   - []string: 替代模型ID，按相似度从高到低排列（相同时保持配置顺序），不包含失败的模型
+//   - error: 配置未加载或当前提供商不存在时返回错误
+func (engine *Engine) SuggestAlternativeModels(ctx context.Context, failedModel string) ([]string, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return nil, fmt.Errorf("获取当前提供商配置失败: %w", err)
+	}
+
+	// 失败的模型不在配置中时只按名称比较
+	failed := &conf.ModelConfig{ID: failedModel}
+	if m, ok := provider.GetModel(failedModel); ok {
+		failed = m
+	}
+
+	type candidate struct {
+		id    string
+		score float64
+	}
+	candidates := make([]candidate, 0, len(provider.Models))
+	for i := range provider.Models {
+		m := &provider.Models[i]
+		if m.ID == failed.ID {
+			continue
+		}
+		candidates = append(candidates, candidate{id: m.ID, score: modelSimilarity(failed, m)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	suggestions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		suggestions = append(suggestions, c.id)
+	}
+	engine.loggerFrom(ctx).Debug("推荐替代模型", "failed_model", failed.ID, "suggestions", suggestions)
+	return suggestions, nil
+}
+
+// suggestUntriedModel 返回相似度最高且未尝试过的替代模型
+func (engine *Engine) suggestUntriedModel(ctx context.Context, failedModel string, tried map[string]bool) (string, bool) {
+	suggestions, err := engine.SuggestAlternativeModels(ctx, failedModel)
+	if err != nil {
+		LoggerFromContext(ctx).Warn("推荐替代模型失败，改为随机选择", "model", failedModel, "error", err)
+		return "", false
+	}
+	for _, id := range suggestions {
+		if !tried[id] {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// modelSimilarity 估算两个模型的能力相似度，分值越高越相似
+//   - 同一系列（名称首个片段相同，如 gpt、deepseek）: 3
+//   - 同一档位（mini/pro 等关键词）: 1
+//   - 名称片段重合度: 0~2
+//   - 声明的能力重合度: 0~2（双方都未声明时不计分）
+//   - 上下文长度接近程度: 0~1（任一方未配置时不计分）
+func modelSimilarity(a, b *conf.ModelConfig) float64 {
+	tokensA, tokensB := modelNameTokens(a.ID), modelNameTokens(b.ID)
+
+	score := 0.0
+	if len(tokensA) > 0 && len(tokensB) > 0 && tokensA[0] == tokensB[0] {
+		score += 3
+	}
+	if modelTier(tokensA) == modelTier(tokensB) {
+		score += 1
+	}
+	score += 2 * jaccard(toSet(tokensA), toSet(tokensB))
+
+	capsA, capsB := enabledCapabilities(a), enabledCapabilities(b)
+	if len(capsA) > 0 || len(capsB) > 0 {
+		score += 2 * jaccard(capsA, capsB)
+	}
+
+	if a.MaxContextTokens > 0 && b.MaxContextTokens > 0 {
+		score += float64(min(a.MaxContextTokens, b.MaxContextTokens)) / float64(max(a.MaxContextTokens, b.MaxContextTokens))
+	}
+	return score
+}
+
+// modelNameTokens 将模型ID拆分为小写片段，忽略组织前缀（如 openai/gpt-4o-mini -> gpt, 4o, mini）
+func modelNameTokens(id string) []string {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	return strings.FieldsFunc(strings.ToLower(id), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// modelTier 根据名称片段判断模型档位：-1 低档，1 高档，0 未知
+func modelTier(tokens []string) int {
+	for _, t := range tokens {
+		if lowTierTokens[t] {
+			return -1
+		}
+		if highTierTokens[t] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// enabledCapabilities 返回模型声明支持的能力集合
+func enabledCapabilities(m *conf.ModelConfig) map[string]bool {
+	caps := make(map[string]bool, len(m.Capabilities))
+	for name, ok := range m.Capabilities {
+		if ok {
+			caps[name] = true
+		}
+	}
+	return caps
+}
+
+// toSet 将切片转换为集合
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// jaccard 计算两个集合的 Jaccard 相似度，两个集合都为空时返回 0
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// unavailable 总是返回 503 的模拟接口
+func unavailable(int) (int, string) {
+	return http.StatusServiceUnavailable, "service unavailable"
+}
+
+func TestCrossProviderFallback(t *testing.T) {
+	down := newChatServer(t, unavailable)
+	up := newChatServer(t, func(int) (int, string) { return http.StatusOK, "来自 up 的回复" })
+	engine := newTestEngine(t, newTestConfig(t,
+		testProvider("down", down.URL, "d1"),
+		testProvider("up", up.URL, "u1"),
+	))
+	engine.SetCrossProviderFallback(true)
+
+	result, err := engine.QueryRequest(t.Context(), &QueryRequest{Query: "你好"})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if result.Reply != "来自 up 的回复" || result.ProviderUsed != "up" || result.ModelUsed != "u1" {
+		t.Errorf("结果为 %s/%s 的回复 %q，期望 up/u1 的回复", result.ProviderUsed, result.ModelUsed, result.Reply)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("Attempts = %d，期望 2（down 一次、up 一次）", result.Attempts)
+	}
+	if down.calls() != 1 || up.calls() != 1 {
+		t.Errorf("down 收到 %d 次请求、up 收到 %d 次请求，期望各 1 次", down.calls(), up.calls())
+	}
+	// 处理结束后恢复原来的提供商和模型
+	if engine.GetCurrentProviderName() != "down" || engine.ModelId != "d1" {
+		t.Errorf("查询后当前为 %s/%s，期望恢复为 down/d1", engine.GetCurrentProviderName(), engine.ModelId)
+	}
+}
+
+func TestCrossProviderFallbackDisabled(t *testing.T) {
+	down := newChatServer(t, unavailable)
+	up := newChatServer(t, func(int) (int, string) { return http.StatusOK, "来自 up 的回复" })
+	engine := newTestEngine(t, newTestConfig(t,
+		testProvider("down", down.URL, "d1"),
+		testProvider("up", up.URL, "u1"),
+	))
+
+	_, err := engine.QueryRequest(t.Context(), &QueryRequest{Query: "你好"})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("错误为 %v，期望 *QueryError", err)
+	}
+	if queryErr.Attempts != 1 {
+		t.Errorf("Attempts = %d，期望 1", queryErr.Attempts)
+	}
+	if up.calls() != 0 {
+		t.Errorf("未开启跨提供商回退时 up 收到 %d 次请求，期望 0 次", up.calls())
+	}
+}
+
+func TestQueryRetryAfterServiceUnavailable(t *testing.T) {
+	// 第一次请求返回 503，之后返回 200
+	server := newChatServer(t, func(call int) (int, string) {
+		if call == 1 {
+			return unavailable(call)
+		}
+		return http.StatusOK, "重试成功"
+	})
+	engine := newTestEngine(t, newTestConfig(t, testProvider("mock", server.URL, "m1", "m2")))
+
+	result, err := engine.QueryRequest(t.Context(), &QueryRequest{Query: "你好"})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if result.Reply != "重试成功" || result.ModelUsed != "m2" {
+		t.Errorf("结果为 %s 的回复 %q，期望 m2 的回复 %q", result.ModelUsed, result.Reply, "重试成功")
+	}
+	if result.Attempts != 2 || server.calls() != 2 {
+		t.Errorf("Attempts = %d、服务器收到 %d 次请求，期望都为 2", result.Attempts, server.calls())
+	}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// modelMetadataTimeout 获取远程模型元数据的超时时间
+const modelMetadataTimeout = 10 * time.Second
+
+// GetModelMetadata 获取模型的元数据
+// 优先调用提供商的 /models/{model_id} 接口并返回原始响应，接口不可用时回退到配置文件中的 ModelConfig
+// 参数:
+//   - modelId: 模型ID或别名（在当前提供商中查找）
+// 返回:
+//   - map[string]any: 模型元数据
+//   - error: 远程接口和本地配置都无法提供元数据时返回错误
+func (engine *Engine) GetModelMetadata(modelId string) (map[string]any, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return nil, fmt.Errorf("获取当前提供商配置失败: %w", err)
+	}
+	model, configured := provider.GetModel(modelId)
+	if configured {
+		modelId = model.ID
+	}
+
+	// 尝试从提供商接口获取
+	metadata, remoteErr := engine.fetchRemoteModelMetadata(modelId)
+	if remoteErr == nil {
+		return metadata, nil
+	}
+
+	// 回退到本地配置
+	if !configured {
+		return nil, fmt.Errorf("获取模型 %s 的元数据失败，且当前提供商 %s 未配置该模型: %w", modelId, provider.Name, remoteErr)
+	}
+	engine.loggerFrom(engine.baseContext()).Info("远程模型元数据不可用，使用本地配置", "model", modelId, "error", remoteErr)
+	return localModelMetadata(model)
+}
+
+// fetchRemoteModelMetadata 调用提供商的 /models/{model_id} 接口
+func (engine *Engine) fetchRemoteModelMetadata(modelId string) (map[string]any, error) {
+	ctx, cancel := context.WithTimeout(engine.baseContext(), modelMetadataTimeout)
+	defer cancel()
+
+	client := engine.newClient()
+	model, err := client.Models.Get(ctx, modelId, option.WithMaxRetries(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]any
+	if err := json.Unmarshal([]byte(model.RawJSON()), &metadata); err != nil {
+		return nil, fmt.Errorf("解析模型元数据失败: %w", err)
+	}
+	return metadata, nil
+}
+
+// localModelMetadata 将配置文件中的模型配置转换为元数据
+func localModelMetadata(model *conf.ModelConfig) (map[string]any, error) {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("序列化模型配置失败: %w", err)
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("解析模型配置失败: %w", err)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"agent_engine/conf"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// WithModelParams 返回使用指定生成参数的 Engine 副本，已设置的字段覆盖提供商配置中的同名参数
+// 参数:
+//   - params: 生成参数，为 nil 的字段沿用之前的设置
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithModelParams(params conf.ModelParams) *Engine {
+	clone := engine.Clone()
+	clone.modelParams = clone.modelParams.Merge(params)
+	return clone
+}
+
+// GetModelParams 获取查询使用的生成参数：当前提供商配置的参数，依次被 WithModelParams 和 WithMaxResponseTokens 的设置覆盖
+// 返回:
+//   - conf.ModelParams: 生成参数，为 nil 的字段不发送
+func (engine *Engine) GetModelParams() conf.ModelParams {
+	var params conf.ModelParams
+	if engine.config != nil {
+		if provider, err := engine.config.GetProviderByName(engine.providerName); err == nil {
+			params = provider.ModelParams
+		}
+	}
+	params = params.Merge(engine.modelParams)
+	if engine.maxResponseTokens > 0 {
+		maxTokens := int64(engine.maxResponseTokens)
+		params.MaxTokens = &maxTokens
+	}
+	return params
+}
+
+// applyModelParams 将生成参数中已设置的字段写入请求参数，未设置的字段保持为空，不会出现在请求中
+func (engine *Engine) applyModelParams(params *openai.ChatCompletionNewParams) {
+	p := engine.GetModelParams()
+	if p.Temperature != nil {
+		params.Temperature = openai.Float(*p.Temperature)
+	}
+	if p.MaxTokens != nil {
+		params.MaxTokens = openai.Int(*p.MaxTokens)
+	}
+	if p.TopP != nil {
+		params.TopP = openai.Float(*p.TopP)
+	}
+}
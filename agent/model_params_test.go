@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"agent_engine/conf"
+	"github.com/openai/openai-go/v3"
+)
+
+// requestBody 写入生成参数后序列化请求参数，返回请求体中的字段
+func requestBody(t *testing.T, engine *Engine) map[string]any {
+	t.Helper()
+	params := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("你好")},
+		Model:    engine.ModelId,
+	}
+	engine.applyModelParams(&params)
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("序列化请求参数失败: %v", err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("解析请求体失败: %v", err)
+	}
+	return body
+}
+
+func TestModelParamsNilFieldsOmitted(t *testing.T) {
+	engine := newTestEngine(t, newTestConfig(t, testProvider("mock", "http://127.0.0.1", "m1")))
+	body := requestBody(t, engine)
+	for _, field := range []string{"temperature", "max_tokens", "top_p"} {
+		if v, ok := body[field]; ok {
+			t.Errorf("未设置的 %s 出现在请求中: %v", field, v)
+		}
+	}
+}
+
+func TestModelParamsZeroValuesSent(t *testing.T) {
+	provider := testProvider("mock", "http://127.0.0.1", "m1")
+	temperature := 0.0
+	provider.Temperature = &temperature
+	engine := newTestEngine(t, newTestConfig(t, provider))
+
+	body := requestBody(t, engine)
+	// 指针字段区分未设置和零值，temperature 为 0 时也要发送
+	if v, ok := body["temperature"]; !ok || v != 0.0 {
+		t.Errorf("temperature = %v, %v，期望 0, true", v, ok)
+	}
+	for _, field := range []string{"max_tokens", "top_p"} {
+		if v, ok := body[field]; ok {
+			t.Errorf("未设置的 %s 出现在请求中: %v", field, v)
+		}
+	}
+}
+
+func TestWithModelParamsOverridesConfig(t *testing.T) {
+	provider := testProvider("mock", "http://127.0.0.1", "m1")
+	temperature, topP := 0.7, 0.9
+	provider.Temperature = &temperature
+	provider.TopP = &topP
+	engine := newTestEngine(t, newTestConfig(t, provider))
+
+	override, maxTokens := 0.2, int64(256)
+	body := requestBody(t, engine.WithModelParams(conf.ModelParams{Temperature: &override, MaxTokens: &maxTokens}))
+	want := map[string]any{"temperature": 0.2, "max_tokens": 256.0, "top_p": 0.9}
+	for field, v := range want {
+		if body[field] != v {
+			t.Errorf("%s = %v，期望 %v", field, body[field], v)
+		}
+	}
+	// 原 Engine 不受影响
+	if body := requestBody(t, engine); body["temperature"] != 0.7 || body["max_tokens"] != nil {
+		t.Errorf("原 Engine 的 temperature = %v、max_tokens = %v，期望 0.7、未设置", body["temperature"], body["max_tokens"])
+	}
+}
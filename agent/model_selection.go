@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// GetBestModel 根据任务类型选择当前提供商中最合适的模型
+// 在声明了所需能力的模型中选择权重最高的一个（权重相同时按配置顺序），没有模型支持时回退到当前模型
+// 参数:
+//   - ctx: 上下文
+//   - taskType: 任务类型
+// 返回:
+//   - string: 模型ID
+//   - error: 错误信息
+func (engine *Engine) GetBestModel(ctx context.Context, taskType QueryType) (string, error) {
+	if engine.config == nil {
+		return "", fmt.Errorf("配置未加载")
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return "", fmt.Errorf("获取当前提供商配置失败: %w", err)
+	}
+
+	capability := taskType.Capability()
+	best, bestWeight := "", 0
+	if capability != "" {
+		for _, m := range provider.Models {
+			if !m.Capabilities[capability] {
+				continue
+			}
+			if best == "" || m.Weight > bestWeight {
+				best, bestWeight = m.ID, m.Weight
+			}
+		}
+	}
+
+	if best == "" {
+		engine.loggerFrom(ctx).Warn("没有模型声明支持该任务类型，使用当前模型",
+			"task_type", taskType.String(), "capability", capability, "model", engine.ModelId, "provider", provider.Name)
+		return engine.ModelId, nil
+	}
+	return best, nil
+}
+
+// GetProvidersWithCapability 获取至少有一个模型声明支持指定能力的提供商（按配置顺序）
+// 参数:
+//   - capability: 能力名称（对应 ModelConfig.Capabilities 的键），例如 vision、function_calling
+// 返回:
+//   - []*conf.ProviderConfig: 提供商配置列表，没有提供商支持时为空
+//   - error: 配置未加载或能力名称为空时返回错误
+func (engine *Engine) GetProvidersWithCapability(capability string) ([]*conf.ProviderConfig, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	if capability == "" {
+		return nil, fmt.Errorf("能力名称不能为空")
+	}
+
+	var providers []*conf.ProviderConfig
+	for i := range engine.config.Provider {
+		if engine.config.Provider[i].HasCapability(capability) {
+			providers = append(providers, &engine.config.Provider[i])
+		}
+	}
+	return providers, nil
+}
+
+// SwitchProviderByCapability 切换到配置中第一个支持指定能力的提供商，并使用该提供商中第一个支持该能力的模型
+// 参数:
+//   - capability: 能力名称（对应 ModelConfig.Capabilities 的键），例如 vision
+// 返回:
+//   - error: 没有提供商支持该能力时返回错误，错误信息中列出各提供商声明的能力
+func (engine *Engine) SwitchProviderByCapability(capability string) error {
+	providers, err := engine.GetProvidersWithCapability(capability)
+	if err != nil {
+		return err
+	}
+	if len(providers) == 0 {
+		declared := make([]string, 0, len(engine.config.Provider))
+		for i := range engine.config.Provider {
+			p := &engine.config.Provider[i]
+			caps := providerCapabilities(p)
+			if len(caps) == 0 {
+				declared = append(declared, p.Name+"(未声明)")
+				continue
+			}
+			declared = append(declared, fmt.Sprintf("%s(%s)", p.Name, strings.Join(caps, ", ")))
+		}
+		return fmt.Errorf("没有提供商支持能力 %s，各提供商声明的能力: %s", capability, strings.Join(declared, "; "))
+	}
+
+	provider := providers[0]
+	for _, m := range provider.Models {
+		if m.Capabilities[capability] {
+			return engine.SwitchProvider(provider.Name, m.ID)
+		}
+	}
+	return fmt.Errorf("提供商 %s 中没有支持能力 %s 的模型", provider.Name, capability)
+}
+
+// providerCapabilities 返回提供商所有模型声明支持的能力（去重并排序）
+func providerCapabilities(p *conf.ProviderConfig) []string {
+	set := make(map[string]bool)
+	for i := range p.Models {
+		for name := range enabledCapabilities(&p.Models[i]) {
+			set[name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(set))
+}
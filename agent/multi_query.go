@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// MultiQueryError MultiQuery 中有查询失败时返回的错误
+type MultiQueryError struct {
+	Errors map[string]error // 失败查询的标签到错误的映射，只包含失败的查询
+}
+
+// Error 实现 error 接口
+func (e *MultiQueryError) Error() string {
+	if len(e.Errors) == 0 {
+		return "命名批量查询失败"
+	}
+	labels := slices.Sorted(maps.Keys(e.Errors))
+	return fmt.Sprintf("命名批量查询中 %d 个查询失败，%s: %v", len(labels), labels[0], e.Errors[labels[0]])
+}
+
+// Unwrap 返回所有失败查询的错误，支持 errors.Is/errors.As
+func (e *MultiQueryError) Unwrap() []error {
+	failed := make([]error, 0, len(e.Errors))
+	for _, label := range slices.Sorted(maps.Keys(e.Errors)) {
+		failed = append(failed, e.Errors[label])
+	}
+	return failed
+}
+
+// MultiQuery 并发发送多个带标签的查询，结果按标签返回
+// 与 QueryBatch 一样受 SetBatchConcurrency 限制并发数，开始前检查 token 预算，每个查询仍经过限流和配额检查
+// 查询标签会记录到日志的 label 属性和请求日志的 label 字段中，便于关联
+// 参数:
+//   - ctx: 上下文
+//   - queries: 标签到查询内容的映射
+// 返回:
+//   - map[string]*QueryResult: 成功查询的标签到结果的映射
+//   - error: 有查询失败时返回 *MultiQueryError（通过 errors.As 获取每个标签的错误），预算不足时返回 ErrTokenBudgetExceeded
+func (engine *Engine) MultiQuery(ctx context.Context, queries map[string]string) (map[string]*QueryResult, error) {
+	labels := slices.Sorted(maps.Keys(queries))
+	texts := make([]string, len(labels))
+	for i, label := range labels {
+		texts[i] = queries[label]
+	}
+	if err := engine.checkBatchBudget(texts); err != nil {
+		return nil, err
+	}
+
+	concurrency := engine.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]*QueryResult, len(labels))
+	errs := make([]error, len(labels))
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for i, label := range labels {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				errs[i] = context.Cause(ctx)
+				return nil
+			}
+			// QueryHandler 处理过程中会切换模型，每个查询使用独立的副本
+			results[i], errs[i] = engine.Clone().Query(engine.withQueryLabel(ctx, label), texts[i])
+			// Engine 已关闭时后续查询都会失败，直接取消
+			if errors.Is(errs[i], ErrEngineShutdown) {
+				cancel(errs[i])
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	out := make(map[string]*QueryResult, len(labels))
+	failed := make(map[string]error)
+	for i, label := range labels {
+		if errs[i] != nil {
+			failed[label] = errs[i]
+			continue
+		}
+		out[label] = results[i]
+	}
+	if len(failed) > 0 {
+		return out, &MultiQueryError{Errors: failed}
+	}
+	return out, nil
+}
+
+// withQueryLabel 返回携带查询标签的上下文，logger 附加 label 属性
+func (engine *Engine) withQueryLabel(ctx context.Context, label string) context.Context {
+	ctx = context.WithValue(ctx, queryLabelContextKey, label)
+	return ContextWithLogger(ctx, engine.loggerFrom(ctx).With("label", label))
+}
+
+// queryLabelFromContext 获取上下文中的查询标签，未设置时返回空字符串
+func queryLabelFromContext(ctx context.Context) string {
+	label, _ := ctx.Value(queryLabelContextKey).(string)
+	return label
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"agent_engine/conf"
+)
+
+// OptimizeConfig 使用的统计设置
+const (
+	optimizeUsageWindow   = 7 * 24 * time.Hour // 统计每日请求数时读取的请求日志时长
+	optimizeQuotaHeadroom = 1.5                // daily_quota 相对于观测到的单日最高请求数的余量
+)
+
+// OptimizeConfig 根据运行期间的统计数据生成性能调优后的配置，不修改当前配置，检查后可通过 WriteConfig 保存
+// 提供商按平均延迟升序排列，模型按平均生成速度（tokens/s）降序排列，没有记录的排在最后并保持配置中的顺序；
+// 模型的 weight 设为最近 20 次调用的成功率（百分比，最小为 1），daily_quota 设为最近 7 天请求日志中单日最高请求数的 1.5 倍，
+// 没有记录的模型保持原值。返回的配置包含已替换的 API 密钥
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - *conf.Config: 调优后的配置
+//   - error: 配置未加载或读取请求日志失败时返回错误
+func (engine *Engine) OptimizeConfig(ctx context.Context) (*conf.Config, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	peaks, err := engine.peakDailyRequests(ctx, time.Now().Add(-optimizeUsageWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	optimized := *engine.config
+	optimized.Provider = slices.Clone(engine.config.Provider)
+	for i := range optimized.Provider {
+		p := &optimized.Provider[i]
+		p.Models = slices.Clone(p.Models)
+		for j := range p.Models {
+			m := &p.Models[j]
+			if rate, ok := engine.stats.successRate(p.Name, m.ID); ok {
+				m.Weight = max(int(math.Round(100*rate)), 1)
+			}
+			if peak, ok := peaks[p.Name+"/"+m.ID]; ok {
+				m.DailyQuota = int(math.Ceil(float64(peak) * optimizeQuotaHeadroom))
+			}
+		}
+		slices.SortStableFunc(p.Models, func(a, b conf.ModelConfig) int {
+			tpsA, okA := engine.stats.averageTPS(p.Name, a.ID)
+			tpsB, okB := engine.stats.averageTPS(p.Name, b.ID)
+			return compareMeasured(-tpsA, okA, -tpsB, okB)
+		})
+	}
+
+	slices.SortStableFunc(optimized.Provider, func(a, b conf.ProviderConfig) int {
+		latencyA, _, _ := engine.stats.providerHealth(a.Name)
+		latencyB, _, _ := engine.stats.providerHealth(b.Name)
+		return compareMeasured(latencyA, latencyA > 0, latencyB, latencyB > 0)
+	})
+
+	engine.loggerFrom(ctx).Info("已生成调优后的配置", "providers", len(optimized.Provider), "models_with_usage", len(peaks))
+	return &optimized, nil
+}
+
+// compareMeasured 升序比较两个统计值，没有记录（ok 为 false）的排在后面
+func compareMeasured[T cmp.Ordered](a T, okA bool, b T, okB bool) int {
+	switch {
+	case okA && okB:
+		return cmp.Compare(a, b)
+	case okA:
+		return -1
+	case okB:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// peakDailyRequests 从请求日志统计 since 之后各模型单日（本地时间）的最高请求数，键为 提供商名称/模型ID
+// 请求日志记录的是请求开始时的提供商和模型，失败后切换到的模型不计入
+func (engine *Engine) peakDailyRequests(ctx context.Context, since time.Time) (map[string]int, error) {
+	peaks := make(map[string]int)
+	if engine.requestLog == nil {
+		return peaks, nil
+	}
+	var buf bytes.Buffer
+	if err := engine.requestLog.dump(&buf, since); err != nil {
+		return nil, err
+	}
+
+	daily := make(map[string]int) // 提供商名称/模型ID/日期 -> 请求数
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var entry RequestLogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Event != "query" || entry.Provider == "" || entry.Model == "" {
+			continue
+		}
+		key := entry.Provider + "/" + entry.Model
+		day := key + "/" + entry.Time.Local().Format(time.DateOnly)
+		daily[day]++
+		peaks[key] = max(peaks[key], daily[day])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取请求日志失败: %w", err)
+	}
+	return peaks, nil
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// providerTestTimeout 测试单个提供商的补全请求和模型列表请求的超时时间
+const providerTestTimeout = 30 * time.Second
+
+// ProviderTestResult 单个提供商的连通性测试结果
+type ProviderTestResult struct {
+	Provider   string        `json:"provider"`    // 提供商名称
+	Reachable  bool          `json:"reachable"`   // 基础URL是否可达
+	AuthValid  bool          `json:"auth_valid"`  // API 密钥是否有效（补全请求未返回 401/403）
+	Latency    time.Duration `json:"latency"`     // 最小补全请求的延迟
+	ModelCount int           `json:"model_count"` // 提供商模型列表接口返回的模型数量，接口不可用时为 0
+	Error      string        `json:"error"`       // 发现的问题，多个问题以分号分隔，没有问题时为空
+}
+
+// TestAllProviders 并发测试所有提供商：先发送 GET 请求检查基础URL是否可达，
+// 再使用默认模型发送最多生成 1 个 token 的补全请求验证密钥并测量延迟，最后检查提供商的模型列表是否包含配置的模型
+// 测试在 Engine 副本上进行，使用各提供商配置的密钥，请求不重试
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - map[string]ProviderTestResult: 提供商名称 -> 测试结果，配置未加载或 Engine 已关闭时为空
+func (engine *Engine) TestAllProviders(ctx context.Context) map[string]ProviderTestResult {
+	results := make(map[string]ProviderTestResult)
+	if engine.config == nil {
+		return results
+	}
+	if engine.lifecycle != nil {
+		if err := engine.lifecycle.acquire(); err != nil {
+			return results
+		}
+		defer engine.lifecycle.release()
+	}
+	ctx, cancel := mergeContext(engine.baseCtx, ctx)
+	defer cancel()
+	ctx = withCorrelation(engine.withLogger(ctx))
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, p := range engine.config.Provider {
+		wg.Go(func() {
+			result := engine.testProvider(ctx, p.Name)
+			mu.Lock()
+			results[p.Name] = result
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// testProvider 测试单个提供商
+func (engine *Engine) testProvider(ctx context.Context, providerName string) (result ProviderTestResult) {
+	result.Provider = providerName
+	var problems []string
+	defer func() { result.Error = strings.Join(problems, "; ") }()
+
+	clone := engine.Clone()
+	if err := clone.SwitchProvider(providerName, ""); err != nil {
+		problems = append(problems, err.Error())
+		return result
+	}
+	provider, _ := clone.config.GetProviderByName(providerName)
+
+	if err := probeBaseURL(ctx, clone.BaseUrl); err != nil {
+		problems = append(problems, fmt.Sprintf("基础URL不可达: %v", err))
+		return result
+	}
+	result.Reachable = true
+
+	client := clone.newClient()
+	reqCtx, cancel := context.WithTimeout(ctx, providerTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Chat.Completions.New(reqCtx, openai.ChatCompletionNewParams{
+		Messages:  []openai.ChatCompletionMessageParamUnion{openai.UserMessage("ping")},
+		Model:     clone.ModelId,
+		MaxTokens: openai.Int(1),
+	}, append(clone.requestOptions(reqCtx), option.WithMaxRetries(0))...)
+	result.Latency = time.Since(start)
+	switch code := statusCodeOf(err); {
+	case err == nil:
+		result.AuthValid = true
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		problems = append(problems, fmt.Sprintf("密钥无效: %v", err))
+	case code != 0:
+		// 服务端返回了非鉴权错误（如模型不存在），说明密钥本身有效
+		result.AuthValid = true
+		problems = append(problems, fmt.Sprintf("补全请求失败: %v", err))
+	default:
+		problems = append(problems, fmt.Sprintf("补全请求失败: %v", err))
+	}
+
+	page, err := client.Models.List(reqCtx, option.WithMaxRetries(0))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("获取模型列表失败: %v", err))
+		return result
+	}
+	listed := make(map[string]bool, len(page.Data))
+	for _, m := range page.Data {
+		listed[m.ID] = true
+	}
+	result.ModelCount = len(page.Data)
+
+	var missing []string
+	for _, id := range provider.ModelIDs() {
+		if !listed[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("模型列表中没有配置的模型 %s", strings.Join(missing, ", ")))
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// providerProbeTimeout 检查基础URL可达性的超时时间
+const providerProbeTimeout = time.Second
+
+// imageSizePattern image.size 的格式: 宽x高
+var imageSizePattern = regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*$`)
+
+// apiKeyPrefixes 已知提供商的 API 密钥前缀: 基础URL的主机名 -> 密钥前缀
+var apiKeyPrefixes = map[string]string{
+	"api.openai.com":    "sk-",
+	"api.anthropic.com": "sk-ant-",
+	"api.deepseek.com":  "sk-",
+	"openrouter.ai":     "sk-or-",
+}
+
+// ValidateProviderConfig 检查已加载的提供商配置，返回发现的所有问题
+// 检查项：API 密钥格式（已知提供商检查前缀）、基础URL可达性（GET 请求，超时 1 秒，收到任意 HTTP 响应即视为可达）、
+// 模型列表非空、模型ID和别名不重复、配置了价格时所有模型都配置了有效价格、生成参数在有效范围内、频率限制非负、图片生成设置有效、custom_endpoints 的接口名称和路径有效
+// 参数:
+//   - providerName: 提供商名称
+// 返回:
+//   - []error: 发现的问题，没有问题时为空
+func (engine *Engine) ValidateProviderConfig(providerName string) []error {
+	if engine.config == nil {
+		return []error{fmt.Errorf("配置未加载")}
+	}
+	provider, err := engine.config.GetProviderByName(providerName)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("提供商 %s "+format, append([]any{provider.Name}, args...)...))
+	}
+
+	// API 密钥
+	baseURL, urlErr := url.Parse(provider.BaseUrl)
+	switch {
+	case strings.TrimSpace(provider.ApiKey) == "":
+		addErr("未配置 api_key")
+	case strings.TrimSpace(provider.ApiKey) != provider.ApiKey || strings.ContainsAny(provider.ApiKey, " \t\r\n"):
+		addErr("的 api_key 包含空白字符")
+	case urlErr == nil:
+		if prefix, ok := apiKeyPrefixes[baseURL.Hostname()]; ok && !strings.HasPrefix(provider.ApiKey, prefix) {
+			addErr("的 api_key 格式错误: %s 的密钥应以 %s 开头", baseURL.Hostname(), prefix)
+		}
+	}
+
+	// 基础URL
+	switch {
+	case provider.BaseUrl == "":
+		addErr("未配置 base_url")
+	case urlErr != nil:
+		addErr("的 base_url 无效: %w", urlErr)
+	case baseURL.Scheme != "http" && baseURL.Scheme != "https" || baseURL.Host == "":
+		addErr("的 base_url %s 无效: 需要 http 或 https 地址", provider.BaseUrl)
+	default:
+		if err := probeBaseURL(engine.baseContext(), provider.BaseUrl); err != nil {
+			addErr("的 base_url %s 不可达: %w", provider.BaseUrl, err)
+		}
+	}
+
+	// 模型列表
+	if len(provider.Models) == 0 {
+		addErr("未配置模型")
+	}
+	seen := make(map[string]string) // 模型ID或别名 -> 所属模型ID
+	for _, m := range provider.Models {
+		if m.ID == "" {
+			addErr("存在 id 为空的模型")
+			continue
+		}
+		for _, name := range append([]string{m.ID}, m.Aliases...) {
+			if owner, ok := seen[name]; ok {
+				if owner == m.ID {
+					addErr("的模型 %s 重复", name)
+				} else {
+					addErr("的模型名称 %s 重复（模型 %s 和 %s）", name, owner, m.ID)
+				}
+				continue
+			}
+			seen[name] = m.ID
+		}
+	}
+
+	// 价格配置：部分模型配置了价格时，未配置价格的模型估算费用为 0
+	var priced, unpriced []string
+	for _, m := range provider.Models {
+		switch {
+		case m.PricePerMTokens < 0:
+			addErr("的模型 %s 的 price_per_m_tokens 不能为负数: %g", m.ID, m.PricePerMTokens)
+		case m.PricePerMTokens > 0:
+			priced = append(priced, m.ID)
+		default:
+			unpriced = append(unpriced, m.ID)
+		}
+	}
+	if len(priced) > 0 && len(unpriced) > 0 {
+		addErr("的价格配置不完整: 模型 %s 未配置 price_per_m_tokens", strings.Join(unpriced, ", "))
+	}
+
+	// 生成参数
+	if t := provider.Temperature; t != nil && (*t < 0 || *t > 2) {
+		addErr("的 temperature 超出范围 0~2: %g", *t)
+	}
+	if p := provider.TopP; p != nil && (*p < 0 || *p > 1) {
+		addErr("的 top_p 超出范围 0~1: %g", *p)
+	}
+	if n := provider.MaxTokens; n != nil && *n <= 0 {
+		addErr("的 max_tokens 必须大于 0: %d", *n)
+	}
+
+	// 频率限制
+	if n := provider.RateLimit.RequestsPerMinute; n < 0 {
+		addErr("的 rate_limit.requests_per_minute 不能为负数: %d", n)
+	}
+	if n := provider.RateLimit.TokensPerMinute; n < 0 {
+		addErr("的 rate_limit.tokens_per_minute 不能为负数: %d", n)
+	}
+
+	// 图片生成
+	if image := provider.Image; image != nil {
+		if image.N < 0 {
+			addErr("的 image.n 不能为负数: %d", image.N)
+		}
+		if image.Size != "" && image.Size != "auto" && !imageSizePattern.MatchString(image.Size) {
+			addErr("的 image.size 无效: %s（应为 宽x高，如 1024x1024，或 auto）", image.Size)
+		}
+	}
+
+	// 自定义接口路径
+	for _, name := range slices.Sorted(maps.Keys(provider.CustomEndpoints)) {
+		path := provider.CustomEndpoints[name]
+		switch {
+		case defaultEndpointPaths[name] == "":
+			addErr("的 custom_endpoints 包含未知接口 %s（支持 %s）", name, strings.Join(slices.Sorted(maps.Keys(defaultEndpointPaths)), ", "))
+		case strings.TrimSpace(path) == "":
+			addErr("的 custom_endpoints.%s 为空", name)
+		case strings.Contains(path, "://") || strings.ContainsAny(path, "?#"):
+			addErr("的 custom_endpoints.%s 无效: %s 应为路径，不能包含协议、主机或查询参数", name, path)
+		}
+	}
+
+	return errs
+}
+
+// probeBaseURL 发送 GET 请求检查地址是否可达，收到任意 HTTP 响应（包括 4xx、5xx）都视为可达
+func probeBaseURL(ctx context.Context, baseURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, providerProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -3,132 +3,550 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math/rand"
 	"time"
 
 	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/param"
 )
 
-// QueryHandler 实现 EventHandler 接口，处理查询事件
+// QueryHandler 实现 EventHandler 和 EventHandlerV2 接口，处理查询事件
 type QueryHandler struct{}
 
+// QueryResult 查询结果
+type QueryResult struct {
+	Query        string   `json:"query"`             // 实际发送的查询内容
+	Reply        string   `json:"reply"`             // 模型回复
+	Think        string   `json:"think"`             // 推理过程（部分模型提供）
+	Replies      []string `json:"replies,omitempty"` // 请求多个回复（N > 1）时的全部回复
+	ModelUsed    string   `json:"model_used"`        // 实际使用的模型
+	ProviderUsed string   `json:"provider_used"`     // 实际使用的提供商
+	Attempts     int      `json:"attempts"`          // 尝试次数
+
+	CorrelationID string `json:"correlation_id,omitempty"` // 请求关联ID
+	RequestID     string `json:"request_id,omitempty"`     // Engine.WithRequestID 设置的请求ID
+	APIKeyUsed    string `json:"api_key_used,omitempty"`   // 设置了密钥轮换时成功调用所用的密钥（脱敏，只保留首尾几位）
+	TotalTokens   int64  `json:"total_tokens,omitempty"`   // 成功调用消耗的 token 数，提供商未返回用量时为 0
+	Fallback      bool   `json:"fallback,omitempty"`       // Reply 是否为 QueryWithFallbackContent 的兜底内容
+
+	EffectiveMaxTokens int64 `json:"effective_max_tokens,omitempty"` // 成功调用实际使用的 max_tokens，未限制或模型不支持该参数时为 0，见 Engine.WithMaxResponseTokens
+
+	ToolCalls []ToolCallRecord `json:"tool_calls,omitempty"` // 通过 ToolCallHandler 查询时执行过的工具调用
+
+	CachedQuery     string  `json:"cached_query,omitempty"`     // 结果来自查询结果缓存时，命中的缓存查询
+	CacheSimilarity float64 `json:"cache_similarity,omitempty"` // 结果来自查询结果缓存时，查询与缓存查询的余弦相似度（完全相同时为 1）
+
+	Score          *ResponseScore `json:"score,omitempty"`            // 回复质量评分，需调用方通过 ScoreResponse 填充（如 --score-response）
+	PostHookErrors []string       `json:"post_hook_errors,omitempty"` // 查询后置钩子返回的错误，不影响查询结果
+}
+
+// QueryError 调用过模型但最终失败时 QueryHandler 返回的错误，记录实际的尝试次数
+type QueryError struct {
+	Attempts int   // 实际尝试次数
+	Err      error // 原始错误
+}
+
+// Error 实现 error 接口，与原始错误的信息相同
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误，支持 errors.Is/errors.As
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Query 发送查询并返回结构化结果
+// 参数:
+//   - ctx: 上下文
+//   - query: 查询内容
+// 返回:
+//   - *QueryResult: 查询结果
+//   - error: 错误信息
+func (engine *Engine) Query(ctx context.Context, query string) (*QueryResult, error) {
+	return engine.QueryRequest(ctx, &QueryRequest{Query: query})
+}
+
+// QueryWithFallbackContent 发送查询，所有重试都失败时返回回复为 fallback 的结果而不是错误
+// 适用于不希望因模型不可用而中断的流水线；失败原因会记录到日志
+// 参数:
+//   - ctx: 上下文
+//   - query: 查询内容
+//   - fallback: 失败时返回的兜底回复
+// 返回:
+//   - *QueryResult: 查询结果，失败时 Reply 为 fallback、Fallback 为 true，Attempts 为实际尝试次数
+//   - error: 始终为 nil
+func (engine *Engine) QueryWithFallbackContent(ctx context.Context, query string, fallback string) (*QueryResult, error) {
+	result, err := engine.Query(ctx, query)
+	if err == nil {
+		return result, nil
+	}
+	engine.loggerFrom(ctx).Warn("查询失败，返回兜底内容", "provider", engine.GetCurrentProviderName(), "model", engine.ModelId, "error", err)
+
+	attempts := 0
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		attempts = queryErr.Attempts
+	}
+	return &QueryResult{
+		Query:        query,
+		Reply:        fallback,
+		ModelUsed:    engine.ModelId,
+		ProviderUsed: engine.GetCurrentProviderName(),
+		Attempts:     attempts,
+		Fallback:     true,
+
+		CorrelationID: CorrelationIDFromContext(ctx),
+		RequestID:     RequestIDFromContext(ctx),
+	}, nil
+}
+
+// QueryRequest 发送结构化查询请求并返回结构化结果
+// 参数:
+//   - ctx: 上下文
+//   - req: 查询请求
+// 返回:
+//   - *QueryResult: 查询结果
+//   - error: 错误信息
+func (engine *Engine) QueryRequest(ctx context.Context, req *QueryRequest) (*QueryResult, error) {
+	rsp, _, err := engine.DispatchRequest(ctx, req, "query")
+	if err != nil {
+		return nil, err
+	}
+	result, ok := rsp.(*QueryResult)
+	if !ok {
+		return nil, fmt.Errorf("query 事件返回了非预期的结果类型 %T", rsp)
+	}
+	return result, nil
+}
+
+// Handle 解析字符串参数后交给 HandleRequest 处理
+// 经中间件包装后通过 DispatchRequest 调用时，沿用原始请求中不参与序列化的 RetryPolicy
 func (h *QueryHandler) Handle(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
-	query := ""
-	if json.Valid([]byte(params)) {
-		type QueryReq struct {
-			Query string `json:"query"`
+	req, err := ParseQueryRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	if orig := requestFromContext(ctx); orig != nil && req.RetryPolicy == nil {
+		req.RetryPolicy = orig.RetryPolicy
+	}
+	return h.HandleRequest(ctx, engine, req, event)
+}
+
+// HandleRequest 处理结构化查询请求，调用失败时在当前提供商内自动轮换模型
+// 参数:
+//   - ctx: 上下文
+//   - engine: Engine 实例
+//   - req: 查询请求
+//   - event: 事件类型
+// 返回:
+//   - rsp: *QueryResult 查询结果
+//   - err: 错误信息
+func (h *QueryHandler) HandleRequest(ctx context.Context, engine *Engine, req *QueryRequest, event string) (rsp any, err error) {
+	// 流式请求将回复分片写入 DispatchStream 或 HandleStream 传入的写入目标
+	var (
+		out    *streamWriter
+		stream io.Writer // 与 out 相同，非流式请求时为 nil 接口
+	)
+	if req.Stream {
+		w := StreamWriterFromContext(ctx)
+		if w == nil {
+			return nil, fmt.Errorf("流式输出需要通过 Engine.StreamQuery 或 QueryHandler.HandleStream 调用")
 		}
-		var req QueryReq
-		err = json.Unmarshal([]byte(params), &req)
-		if err != nil {
-			return
+		if req.N > 1 {
+			return nil, fmt.Errorf("流式输出不支持生成多个回复（N > 1）")
 		}
-		query = req.Query
-	} else {
-		query = params
+		out = newStreamWriter(w)
+		stream = out
+	}
+	if engine.throughput != nil {
+		engine.throughput.record(time.Now())
+	}
+
+	query, err := req.RenderQuery()
+	if err != nil {
+		return nil, err
+	}
+	// Engine 带有进行中的对话时，在请求的历史消息之前发送会话的系统提示和历史
+	conversation := engine.conversation
+	if conversation != nil {
+		convReq := *req
+		convReq.History = append(conversation.queryHistory(), req.History...)
+		req = &convReq
+	}
+	messages, err := req.chatMessages(query)
+	if err != nil {
+		return nil, err
 	}
 
-	// 保存原始模型ID，用于失败后恢复
+	logger := LoggerFromContext(ctx).With("handler", "QueryHandler")
+
+	// 保存原始提供商和模型ID，用于失败后恢复
+	originalProvider := engine.GetCurrentProviderName()
 	originalModelId := engine.ModelId
 	defer func() {
-		// 无论成功或失败，都恢复原始模型ID
+		// 无论成功或失败，都恢复原始提供商和模型ID
+		if engine.GetCurrentProviderName() != originalProvider {
+			if err := engine.SwitchProvider(originalProvider, originalModelId); err != nil {
+				logger.Error("恢复提供商失败", "provider", originalProvider, "error", err)
+			}
+		}
 		engine.ModelId = originalModelId
 	}()
 
-	// 获取当前提供商的所有可用模型
-	availableModels, err := engine.GetAvailableModels()
-	if err != nil {
-		return nil, fmt.Errorf("获取可用模型列表失败: %w", err)
+	// 发布查询开始事件，结束时根据结果发布成功或失败事件
+	startedAt := time.Now()
+	attempts := 0
+	// 调用过模型后失败时记录尝试次数（在发布失败事件之后执行，不影响事件中的错误信息）
+	defer func() {
+		if err != nil && attempts > 0 {
+			err = &QueryError{Attempts: attempts, Err: err}
+		}
+	}()
+	queryEvent := func() QueryEventData {
+		return QueryEventData{
+			Query:         query,
+			Provider:      engine.GetCurrentProviderName(),
+			Model:         engine.ModelId,
+			CorrelationID: CorrelationIDFromContext(ctx),
+			Attempts:      attempts,
+		}
 	}
+	engine.publish(EventQueryStarted, queryEvent())
+	defer func() {
+		data := queryEvent()
+		data.Duration = time.Since(startedAt)
+		if err != nil {
+			data.Error = err.Error()
+			engine.publish(EventQueryFailed, data)
+			return
+		}
+		engine.publish(EventQuerySucceeded, data)
+	}()
 
-	// 初始化随机数生成器
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// 请求指定了提供商或模型时直接切换；否则在配置了路由表时，根据查询类型切换到对应的提供商和模型
+	routed := false
+	switchReason := "override"
+	if req.OverrideProvider != "" {
+		if err := engine.SwitchProvider(req.OverrideProvider, req.OverrideModel); err != nil {
+			return nil, err
+		}
+	} else if req.OverrideModel != "" {
+		if err := engine.SwitchModel(req.OverrideModel); err != nil {
+			return nil, err
+		}
+	} else if engine.config != nil && len(engine.config.Classifier.Routes) > 0 {
+		router, err := NewQueryRouter(engine)
+		if err != nil {
+			return nil, fmt.Errorf("创建查询路由器失败: %w", err)
+		}
+		queryType, ok, err := router.Route(ctx, engine, query)
+		if err != nil {
+			logger.Warn("查询路由失败，继续使用当前模型", "error", err)
+		} else if ok {
+			routed = true
+			switchReason = "route"
+			logger.Info("根据查询类型路由", "query_type", queryType.String(), "model", engine.ModelId, "provider", engine.GetCurrentProviderName())
+		}
+	}
 
-	// 记录已尝试过的模型
-	triedModels := make(map[string]bool)
-	triedModels[originalModelId] = true
+	// 未指定模型且未按查询类型路由时，按提供商的负载均衡策略选择初始模型
+	if req.OverrideProvider == "" && req.OverrideModel == "" && !routed {
+		modelId, err := engine.selectBalancedModel()
+		if err != nil {
+			return nil, err
+		}
+		if modelId != engine.ModelId {
+			if err := engine.SwitchModel(modelId); err != nil {
+				return nil, err
+			}
+			logger.Info("按负载均衡策略选择模型", "model", modelId, "provider", engine.GetCurrentProviderName())
+		}
+		switchReason = "load_balancing"
+	}
+	engine.debug(ctx, "已选择初始模型", "reason", switchReason,
+		"from_provider", originalProvider, "from_model", originalModelId,
+		"provider", engine.GetCurrentProviderName(), "model", engine.ModelId)
+	engine.publishSwitch(ctx, EventProviderSwitched, originalProvider, engine.GetCurrentProviderName(), switchReason)
+	engine.publishSwitch(ctx, EventModelSwitched, originalModelId, engine.ModelId, switchReason)
 
-	// 最多尝试3个模型（包括当前模型）
-	maxAttempts := 3
-	if len(availableModels) < maxAttempts {
-		maxAttempts = len(availableModels)
+	// 调用模型前执行前置钩子，任一钩子返回错误时中止查询
+	if err := engine.runPreQueryHooks(ctx, req); err != nil {
+		return nil, err
 	}
 
-	var lastErr error
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		// 第一次尝试使用原始模型，后续尝试随机选择未使用过的模型
-		if attempt > 1 {
-			// 获取未尝试过的模型列表
-			untriedModels := make([]string, 0)
-			for _, model := range availableModels {
-				if !triedModels[model] {
-					untriedModels = append(untriedModels, model)
-				}
+	// 启用了查询结果缓存时，命中的结果直接返回，不调用模型；先按提供商、模型、系统提示和查询内容精确查找（cache_ttl），再按语义查找（cache_type）
+	// 两种缓存的键都按初始的提供商和模型计算，回退到其他模型后的结果也保存在该键下
+	// 声明了工具时回复取决于工具的执行结果，不使用缓存
+	tools := toolCallHandlerFromContext(ctx) != nil
+	cacheable := !tools && engine.cacheableRequest(req)
+	queryKey := ""
+	if cacheable {
+		queryKey = engine.queryCacheKey()
+	}
+	responseKey := ""
+	if !tools && engine.responseCacheable(req) {
+		responseKey = engine.responseCacheKey(query)
+	}
+	var (
+		cached *QueryResult
+		hit    bool
+	)
+	if responseKey != "" {
+		cached, hit = engine.lookupResponseCache(ctx, responseKey, query)
+	}
+	if !hit && cacheable {
+		cached, hit = engine.lookupQueryCache(ctx, queryKey, query)
+	}
+	if hit {
+		logger.Info("查询结果缓存命中", "cached_query", cached.CachedQuery, "similarity", cached.CacheSimilarity)
+		if out != nil {
+			if _, err := io.WriteString(out, cached.Reply); err != nil {
+				return nil, fmt.Errorf("写入流式输出失败: %w", err)
 			}
+		}
+		engine.runPostQueryHooks(ctx, cached)
+		return cached, nil
+	}
 
-			// 如果没有未尝试的模型了，退出循环
-			if len(untriedModels) == 0 {
-				log.Printf("[QueryHandler] 已尝试所有可用模型，无更多模型可轮换")
-				break
+	// 开启跨提供商回退时，当前提供商的模型都失败后按配置顺序依次切换到其余提供商，重新轮换模型
+	fallbackProviders := engine.crossProviderCandidates(req)
+	var lastErr error
+	// 通过 ToolCallHandler 查询时，工具调用循环的进度跨尝试保留，重试不会重复执行已执行过的工具
+	var loop toolLoop
+	for providerIndex := 0; providerIndex <= len(fallbackProviders); providerIndex++ {
+		if providerIndex > 0 {
+			failedProvider, failedModelId := engine.GetCurrentProviderName(), engine.ModelId
+			next := fallbackProviders[providerIndex-1]
+			logger.Info("当前提供商的模型均调用失败，切换到下一个提供商", "from_provider", failedProvider, "provider", next, "reason", lastErr)
+			if err := engine.SwitchProvider(next, ""); err != nil {
+				logger.Error("切换提供商失败", "provider", next, "error", err)
+				continue
 			}
+			engine.publishSwitch(ctx, EventProviderSwitched, failedProvider, next, "failover")
+			engine.publishSwitch(ctx, EventModelSwitched, failedModelId, engine.ModelId, "failover")
+		}
+		// 之前的提供商已用的尝试次数，尝试次数跨提供商累计
+		providerAttempts := attempts
 
-			// 随机选择一个未尝试过的模型
-			newModelId := untriedModels[rnd.Intn(len(untriedModels))]
-			log.Printf("[QueryHandler] 第 %d 次尝试：切换到模型 %s（提供商：%s）", attempt, newModelId, engine.GetCurrentProviderName())
+		// 获取当前提供商的所有可用模型
+		availableModels, err := engine.GetAvailableModels()
+		if err != nil {
+			return nil, fmt.Errorf("获取可用模型列表失败: %w", err)
+		}
 
-			// 切换模型
-			if err := engine.SwitchModel(newModelId); err != nil {
-				log.Printf("[QueryHandler] 切换模型失败: %v", err)
-				continue
-			}
+		// 初始化随机数生成器
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+		// 记录已尝试过的模型
+		triedModels := make(map[string]bool)
+		triedModels[engine.ModelId] = true
 
-			// 标记该模型已尝试
-			triedModels[newModelId] = true
-		} else {
-			log.Printf("[QueryHandler] 第 %d 次尝试：使用当前模型 %s（提供商：%s）", attempt, engine.ModelId, engine.GetCurrentProviderName())
+		// 最多尝试3个模型（包括当前模型）；请求指定了重试策略时使用策略的尝试次数
+		maxAttempts := 3
+		if len(availableModels) < maxAttempts {
+			maxAttempts = len(availableModels)
+		}
+		policy := req.RetryPolicy
+		if policy != nil && policy.MaxAttempts > 0 {
+			maxAttempts = policy.MaxAttempts
 		}
 
-		// 尝试调用模型
-		client := openai.NewClient(option.WithAPIKey(engine.GetApiKey()), option.WithBaseURL(engine.BaseUrl))
-		completion, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(query)},
-			Model:    engine.ModelId,
-		})
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			// 第一次尝试使用原始模型，后续尝试随机选择未使用过的模型
+			if attempt > 1 {
+				// 获取未尝试过的模型列表
+				untriedModels := make([]string, 0)
+				for _, model := range availableModels {
+					if !triedModels[model] {
+						untriedModels = append(untriedModels, model)
+					}
+				}
 
-		if err != nil {
-			lastErr = err
-			log.Printf("[QueryHandler] 模型 %s 调用失败: %v", engine.ModelId, err)
+				if len(untriedModels) > 0 {
+					// 开启智能回退时选择与失败模型最相近的模型，否则随机选择一个未尝试过的模型
+					newModelId := untriedModels[rnd.Intn(len(untriedModels))]
+					fallbackReason := "random"
+					if engine.smartFallback {
+						if suggested, ok := engine.suggestUntriedModel(ctx, engine.ModelId, triedModels); ok {
+							newModelId = suggested
+							fallbackReason = "similarity"
+						}
+					}
+					engine.debug(ctx, "已选择回退模型", "attempt", attempt, "reason", fallbackReason, "model", newModelId, "candidates", untriedModels)
+					logger.Info("切换到未尝试过的模型", "attempt", attempt, "model", newModelId, "provider", engine.GetCurrentProviderName())
 
-			// 如果还有重试机会，继续下一次尝试
-			if attempt < maxAttempts {
-				continue
+					// 切换模型
+					failedModelId := engine.ModelId
+					if err := engine.SwitchModel(newModelId); err != nil {
+						logger.Error("切换模型失败", "model", newModelId, "error", err)
+						continue
+					}
+					engine.publishSwitch(ctx, EventModelSwitched, failedModelId, newModelId, "failover")
+
+					// 标记该模型已尝试
+					triedModels[newModelId] = true
+				} else if policy == nil {
+					// 如果没有未尝试的模型了，退出循环
+					logger.Warn("已尝试所有可用模型，无更多模型可轮换")
+					break
+				} else {
+					// 指定了重试策略时，所有模型都尝试过后继续使用当前模型重试
+					logger.Info("已尝试所有可用模型，使用当前模型重试", "attempt", attempt, "model", engine.ModelId)
+				}
+			} else {
+				logger.Info("使用当前模型", "attempt", attempt, "model", engine.ModelId, "provider", engine.GetCurrentProviderName())
 			}
 
-			// 所有尝试都失败了，返回最后一次的错误
-			return nil, fmt.Errorf("所有模型调用均失败，最后错误: %w", lastErr)
-		}
+			// 尝试调用模型
+			attempts = providerAttempts + attempt
+			// 每次尝试一个 span，频率限制的等待和模型接口的调用都在其下
+			attemptCtx, attemptSpan := engine.startSpan(ctx, "agent_engine.query.attempt", AttrAttemptNumber.Int(attempts))
+			start := time.Now()
+			// 系统提示随当前提供商变化，每次尝试时重新确定
+			params := openai.ChatCompletionNewParams{
+				Messages: engine.withSystemMessage(messages),
+				Model:    engine.ModelId,
+			}
+			if req.N > 1 {
+				params.N = openai.Int(int64(req.N))
+			}
+			engine.applyModelParams(&params)
+			engine.debugJSON(ctx, "模型请求", "request_json", params, "attempt", attempt, "model", engine.ModelId)
+			// 提供商配置了 rate_limit 时等待令牌桶，等待会超过截止时间时直接返回错误
+			if err := engine.waitRateLimit(attemptCtx, req, query); err != nil {
+				endSpan(attemptSpan, err)
+				return nil, err
+			}
+			// 密钥返回 401 时换用下一个密钥重试，不计入尝试次数
+			// 通过 ToolCallHandler 查询时循环执行模型请求的工具调用
+			var completion *openai.ChatCompletion
+			apiKey, err := engine.callWithRotatedKey(func(client openai.Client) (err error) {
+				completion, err = engine.toolChatCompletion(attemptCtx, client, params, stream, &loop)
+				// 模型不支持 max_tokens 参数时去掉限制重新调用，不计入尝试次数
+				if err != nil && params.MaxTokens.Valid() && maxTokensUnsupported(err) {
+					logger.Warn("模型不支持 max_tokens 参数，不限制回复长度重新调用", "model", engine.ModelId, "max_tokens", params.MaxTokens.Value, "error", err)
+					params.MaxTokens = param.Opt[int64]{}
+					completion, err = engine.toolChatCompletion(attemptCtx, client, params, stream, &loop)
+				}
+				return err
+			})
+			if err == nil {
+				attemptSpan.SetAttributes(AttrResponseTokenCount.Int64(completion.Usage.TotalTokens))
+			}
+			endSpan(attemptSpan, err)
+
+			if err != nil {
+				lastErr = err
+				logger.Warn("模型调用失败", "attempt", attempt, "model", engine.ModelId, "error", err)
+				engine.recordError(attempts, err)
+
+				// 已经输出的内容无法撤回，流式输出开始后不再重试
+				if out != nil && out.started() {
+					return nil, fmt.Errorf("流式输出中断: %w", err)
+				}
+
+				// 如果还有重试机会且重试策略允许，继续下一次尝试
+				if attempt < maxAttempts {
+					retry := policy.shouldRetry(ctx, err, attempt)
+					engine.debug(ctx, "重试决策", "attempt", attempt, "max_attempts", maxAttempts, "retry", retry, "status_code", statusCodeOf(err), "retry_policy", policy != nil)
+					if !retry {
+						return nil, fmt.Errorf("重试策略终止了重试（已尝试 %d 次），最后错误: %w", attempts, lastErr)
+					}
+					if err := policy.beforeRetry(ctx, attempt, err); err != nil {
+						return nil, fmt.Errorf("%w，最后错误: %w", err, lastErr)
+					}
+					continue
+				}
+
+				// 当前提供商的尝试次数用完，开启跨提供商回退时切换到下一个提供商
+				break
+			}
+
+			// 调用成功，记录日志并返回结果
+			logger.Info("模型调用成功", "attempt", attempt, "model", engine.ModelId)
+			engine.debugJSON(ctx, "模型原始响应", "raw_json", json.RawMessage(completion.RawJSON()), "attempt", attempt, "model", engine.ModelId)
+			engine.recordUsage(ctx, completion.Usage.TotalTokens)
+			engine.recordResponseLength(ctx, completion.Usage.CompletionTokens)
+			engine.recordRateLimitTokens(completion.Usage.CompletionTokens)
+			if engine.stats != nil {
+				engine.stats.record(engine.GetCurrentProviderName(), engine.ModelId, time.Since(start), completion.Usage.CompletionTokens)
+			}
+
+			if len(completion.Choices) == 0 {
+				return nil, fmt.Errorf("模型 %s 未返回任何结果", engine.ModelId)
+			}
+			if out != nil {
+				if err := out.flush(); err != nil {
+					return nil, fmt.Errorf("写入流式输出失败: %w", err)
+				}
+			}
+			result := &QueryResult{
+				Query:        query,
+				Reply:        completion.Choices[0].Message.Content,
+				Think:        completion.Choices[0].Message.JSON.ExtraFields["reasoning_content"].Raw(),
+				ModelUsed:    engine.ModelId,                  // 记录实际使用的模型
+				ProviderUsed: engine.GetCurrentProviderName(), // 记录使用的提供商
+				Attempts:     attempts,                        // 记录尝试次数
 
-		// 调用成功，记录日志并返回结果
-		log.Printf("[QueryHandler] 模型 %s 调用成功（第 %d 次尝试）", engine.ModelId, attempt)
-		log.Printf("[QueryHandler] raw json: %s", completion.RawJSON())
+				CorrelationID: CorrelationIDFromContext(ctx),
+				RequestID:     RequestIDFromContext(ctx),
+				APIKeyUsed:    engine.apiKeyUsed(apiKey),
+				TotalTokens:   completion.Usage.TotalTokens,
 
-		reply := completion.Choices[0].Message.Content
-		think := completion.Choices[0].Message.JSON.ExtraFields["reasoning_content"].Raw()
+				EffectiveMaxTokens: params.MaxTokens.Value,
 
-		rsp = map[string]interface{}{
-			"query":         query,
-			"reply":         reply,
-			"think":         think,
-			"model_used":    engine.ModelId,                  // 记录实际使用的模型
-			"provider_used": engine.GetCurrentProviderName(), // 记录使用的提供商
-			"attempts":      attempt,                         // 记录尝试次数
+				ToolCalls: loop.records,
+			}
+			if len(completion.Choices) > 1 {
+				for _, choice := range completion.Choices {
+					result.Replies = append(result.Replies, choice.Message.Content)
+				}
+			}
+			if cacheable {
+				if err := engine.queryCache.Put(ctx, queryKey, query, result); err != nil {
+					logger.Warn("写入查询结果缓存失败", "error", err)
+				}
+			}
+			if responseKey != "" {
+				engine.storeResponseCache(responseKey, result)
+			}
+			if conversation != nil {
+				conversation.appendTurn(query, result.Reply)
+			}
+			engine.runPostQueryHooks(ctx, result)
+			rsp = result
+			return rsp, nil
 		}
-		return rsp, nil
 	}
 
+	if lastErr != nil {
+		return nil, fmt.Errorf("所有模型调用均失败，最后错误: %w", lastErr)
+	}
 	// 理论上不会到达这里，但为了安全起见
 	return nil, fmt.Errorf("未知错误: 所有尝试均未成功：%+v", err)
 }
+
+// chatCompletion 调用对话补全接口：提供商配置了 sse_mode 时自行解析 SSE 响应，stream 不为空时以流式请求调用并写入回复分片
+// 每次调用记录一个 span，属性包括提供商、模型和消耗的 token 数
+func (engine *Engine) chatCompletion(ctx context.Context, client openai.Client, params openai.ChatCompletionNewParams, stream io.Writer) (completion *openai.ChatCompletion, err error) {
+	ctx, span := engine.startSpan(ctx, "agent_engine.chat_completion")
+	defer func() {
+		if completion != nil {
+			span.SetAttributes(AttrResponseTokenCount.Int64(completion.Usage.TotalTokens))
+		}
+		endSpan(span, err)
+	}()
+
+	switch {
+	case engine.sseMode():
+		return engine.sseChatCompletion(ctx, client, params, stream)
+	case stream != nil:
+		return engine.streamChatCompletion(ctx, client, params, stream)
+	default:
+		return client.Chat.Completions.New(ctx, params, engine.requestOptions(ctx)...)
+	}
+}
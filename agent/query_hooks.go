@@ -0,0 +1,355 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultHookPriority RegisterPreQueryHook 和 RegisterPostQueryHook 注册钩子时使用的优先级
+const DefaultHookPriority = 100
+
+var (
+	// ErrRateLimited RateLimitHook 在窗口内的查询数达到上限时返回的错误
+	ErrRateLimited = errors.New("超过查询频率限制")
+	// ErrContentBlocked ContentFilterHook 在查询包含被禁止的关键词时返回的错误
+	ErrContentBlocked = errors.New("查询内容包含被禁止的关键词")
+	// ErrCircuitOpen CircuitBreakerHook 在提供商近期失败次数过多时返回的错误
+	ErrCircuitOpen = errors.New("提供商近期失败次数过多，已熔断")
+)
+
+// PreQueryHook 查询发送给模型前调用的钩子，返回错误时中止查询
+// 钩子在选择提供商和模型之后、调用模型之前执行，可以修改 req 中尚未使用的字段（如 RetryPolicy）
+type PreQueryHook func(ctx context.Context, req *QueryRequest) error
+
+// prioritizedHook 带优先级的查询钩子
+type prioritizedHook[F any] struct {
+	priority int
+	fn       F
+}
+
+// addHook 返回插入新钩子后按优先级排列的新切片，相同优先级保持注册顺序；不修改原切片，避免影响已创建的副本
+func addHook[F any](hooks []prioritizedHook[F], priority int, fn F) []prioritizedHook[F] {
+	hooks = append(slices.Clone(hooks), prioritizedHook[F]{priority: priority, fn: fn})
+	slices.SortStableFunc(hooks, func(a, b prioritizedHook[F]) int {
+		return a.priority - b.priority
+	})
+	return hooks
+}
+
+// RegisterPreQueryHook 以 DefaultHookPriority 注册查询前置钩子，见 RegisterPreQueryHookWithPriority
+// 参数:
+//   - fn: 钩子函数
+func (engine *Engine) RegisterPreQueryHook(fn func(ctx context.Context, req *QueryRequest) error) {
+	engine.RegisterPreQueryHookWithPriority(DefaultHookPriority, fn)
+}
+
+// RegisterPreQueryHookWithPriority 注册查询前置钩子，每次查询按优先级依次调用，任一钩子返回错误时中止查询
+// 优先级数值越小越先执行，相同优先级按注册顺序执行；注册前创建的 Engine 副本不受影响
+// 参数:
+//   - priority: 优先级
+//   - fn: 钩子函数，内置钩子见 RateLimitHook、QuotaBudgetHook、ContentFilterHook、CircuitBreakerHook
+func (engine *Engine) RegisterPreQueryHookWithPriority(priority int, fn func(ctx context.Context, req *QueryRequest) error) {
+	if fn == nil {
+		return
+	}
+	engine.preQueryHooks = addHook(engine.preQueryHooks, priority, PreQueryHook(fn))
+}
+
+// ClearPreQueryHooks 移除所有查询前置钩子
+func (engine *Engine) ClearPreQueryHooks() {
+	engine.preQueryHooks = nil
+}
+
+// runPreQueryHooks 依次调用查询前置钩子，钩子可以通过上下文获取处理查询的 Engine
+func (engine *Engine) runPreQueryHooks(ctx context.Context, req *QueryRequest) error {
+	if len(engine.preQueryHooks) == 0 {
+		return nil
+	}
+	ctx = context.WithValue(ctx, engineContextKey, engine)
+	for i, hook := range engine.preQueryHooks {
+		if err := hook.fn(ctx, req); err != nil {
+			engine.debug(ctx, "查询前置钩子中止了查询", "hook", i, "priority", hook.priority, "error", err)
+			return fmt.Errorf("查询前置钩子中止了查询: %w", err)
+		}
+	}
+	return nil
+}
+
+// hookEngine 获取处理查询的 Engine，只在钩子中可用
+func hookEngine(ctx context.Context) (*Engine, bool) {
+	engine, ok := ctx.Value(engineContextKey).(*Engine)
+	return engine, ok && engine != nil
+}
+
+// RateLimitHook 返回限制查询频率的前置钩子：任意 window 时长内最多 limit 次查询，超过时返回 ErrRateLimited
+// 计数在同一个钩子的所有调用之间共享，被中止的查询不计数
+// 参数:
+//   - limit: 窗口内最多允许的查询数，小于等于 0 时不限制
+//   - window: 窗口时长，如 time.Minute
+// 返回:
+//   - PreQueryHook: 前置钩子
+func RateLimitHook(limit int, window time.Duration) PreQueryHook {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	return func(ctx context.Context, req *QueryRequest) error {
+		if limit <= 0 {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		cutoff := now.Add(-window)
+		times = slices.DeleteFunc(times, func(t time.Time) bool { return !t.After(cutoff) })
+		if len(times) >= limit {
+			return fmt.Errorf("%w: %s 内最多 %d 次，%s 后可重试", ErrRateLimited, window, limit, times[0].Add(window).Sub(now).Round(time.Millisecond))
+		}
+		times = append(times, now)
+		return nil
+	}
+}
+
+// QuotaBudgetHook 返回检查 token 预算的前置钩子：当前提供商配置了 monthly_token_budget 时，
+// 按查询内容预估的 token 数超过剩余预算则返回 ErrTokenBudgetExceeded；未配置预算时不检查
+// 返回:
+//   - PreQueryHook: 前置钩子
+func QuotaBudgetHook() PreQueryHook {
+	return func(ctx context.Context, req *QueryRequest) error {
+		engine, ok := hookEngine(ctx)
+		if !ok {
+			return nil
+		}
+		query, err := req.RenderQuery()
+		if err != nil {
+			return err
+		}
+		return engine.checkBatchBudget([]string{query})
+	}
+}
+
+// ContentFilterHook 返回过滤查询内容的前置钩子：查询（模板渲染后）包含任一关键词时返回 ErrContentBlocked，关键词不区分大小写
+// 参数:
+//   - blocked: 被禁止的关键词，空字符串会被忽略
+// 返回:
+//   - PreQueryHook: 前置钩子
+func ContentFilterHook(blocked ...string) PreQueryHook {
+	keywords := make([]string, 0, len(blocked))
+	for _, k := range blocked {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return func(ctx context.Context, req *QueryRequest) error {
+		query, err := req.RenderQuery()
+		if err != nil {
+			return err
+		}
+		lower := strings.ToLower(query)
+		for _, k := range keywords {
+			if strings.Contains(lower, k) {
+				return fmt.Errorf("%w: %s", ErrContentBlocked, k)
+			}
+		}
+		return nil
+	}
+}
+
+// CircuitBreakerHook 返回熔断前置钩子：当前提供商在最近 cooldown 时长内的模型调用失败次数（见 ListRecentErrors）
+// 达到 threshold 时返回 ErrCircuitOpen，最早的失败超出 cooldown 后自动恢复
+// 参数:
+//   - threshold: 触发熔断的失败次数，小于等于 0 时不熔断
+//   - cooldown: 统计失败次数的时长，如 30 * time.Second
+// 返回:
+//   - PreQueryHook: 前置钩子
+func CircuitBreakerHook(threshold int, cooldown time.Duration) PreQueryHook {
+	return func(ctx context.Context, req *QueryRequest) error {
+		engine, ok := hookEngine(ctx)
+		if !ok || threshold <= 0 || engine.errorLog == nil {
+			return nil
+		}
+		provider := engine.GetCurrentProviderName()
+		if failures := engine.recentFailures(provider, time.Now().Add(-cooldown)); failures >= threshold {
+			return fmt.Errorf("%w: %s 在 %s 内失败 %d 次", ErrCircuitOpen, provider, cooldown, failures)
+		}
+		return nil
+	}
+}
+
+// ErrInvalidResponse ResponseValidatorHook 在回复不满足要求时返回的错误
+var ErrInvalidResponse = errors.New("模型回复未通过校验")
+
+// PostQueryHook 查询成功后调用的钩子，可以读取或修改结果
+// 钩子返回的错误不会使查询失败，只记录日志并写入 QueryResult.PostHookErrors
+type PostQueryHook func(ctx context.Context, result *QueryResult) error
+
+// RegisterPostQueryHook 以 DefaultHookPriority 注册查询后置钩子，见 RegisterPostQueryHookWithPriority
+// 参数:
+//   - fn: 钩子函数
+func (engine *Engine) RegisterPostQueryHook(fn func(ctx context.Context, result *QueryResult) error) {
+	engine.RegisterPostQueryHookWithPriority(DefaultHookPriority, fn)
+}
+
+// RegisterPostQueryHookWithPriority 注册查询后置钩子，每次查询成功后按优先级依次调用
+// 优先级数值越小越先执行，相同优先级按注册顺序执行；某个钩子返回错误时继续执行后续钩子；注册前创建的 Engine 副本不受影响
+// 参数:
+//   - priority: 优先级
+//   - fn: 钩子函数，内置钩子见 AuditLogHook、CacheWriteHook、UsageTrackingHook、MetricsRecordHook、ResponseValidatorHook
+func (engine *Engine) RegisterPostQueryHookWithPriority(priority int, fn func(ctx context.Context, result *QueryResult) error) {
+	if fn == nil {
+		return
+	}
+	engine.postQueryHooks = addHook(engine.postQueryHooks, priority, PostQueryHook(fn))
+}
+
+// ClearPostQueryHooks 移除所有查询后置钩子
+func (engine *Engine) ClearPostQueryHooks() {
+	engine.postQueryHooks = nil
+}
+
+// runPostQueryHooks 依次调用查询后置钩子，错误记录日志并写入结果的 PostHookErrors
+func (engine *Engine) runPostQueryHooks(ctx context.Context, result *QueryResult) {
+	if len(engine.postQueryHooks) == 0 {
+		return
+	}
+	ctx = context.WithValue(ctx, engineContextKey, engine)
+	for i, hook := range engine.postQueryHooks {
+		if err := hook.fn(ctx, result); err != nil {
+			engine.loggerFrom(ctx).Warn("查询后置钩子执行失败", "hook", i, "priority", hook.priority, "error", err)
+			result.PostHookErrors = append(result.PostHookErrors, err.Error())
+		}
+	}
+}
+
+// auditLogEntry AuditLogHook 写入的审计记录
+type auditLogEntry struct {
+	Time          time.Time `json:"time"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	RequestID     string    `json:"request_id,omitempty"`
+	Label         string    `json:"label,omitempty"`
+	Provider      string    `json:"provider"`
+	Model         string    `json:"model"`
+	Attempts      int       `json:"attempts"`
+	Query         string    `json:"query"`
+	Reply         string    `json:"reply"`
+}
+
+// AuditLogHook 返回记录审计日志的后置钩子：每次查询成功后以 JSONL 格式向 w 写入一条记录，
+// 包括时间、关联ID、请求ID、MultiQuery 标签、提供商、模型、尝试次数、查询和回复
+// 参数:
+//   - w: 输出目标，如打开的文件；并发查询的写入会串行进行
+// 返回:
+//   - PostQueryHook: 后置钩子
+func AuditLogHook(w io.Writer) PostQueryHook {
+	var mu sync.Mutex
+	return func(ctx context.Context, result *QueryResult) error {
+		line, err := json.Marshal(auditLogEntry{
+			Time:          time.Now(),
+			CorrelationID: result.CorrelationID,
+			RequestID:     result.RequestID,
+			Label:         queryLabelFromContext(ctx),
+			Provider:      result.ProviderUsed,
+			Model:         result.ModelUsed,
+			Attempts:      result.Attempts,
+			Query:         result.Query,
+			Reply:         result.Reply,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化审计日志失败: %w", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("写入审计日志失败: %w", err)
+		}
+		return nil
+	}
+}
+
+// CacheWriteHook 返回缓存查询结果的后置钩子：以查询内容为键，将结果的副本写入 cache
+// 参数:
+//   - cache: 缓存，键为 string（查询内容），值为 *QueryResult
+// 返回:
+//   - PostQueryHook: 后置钩子
+func CacheWriteHook(cache *sync.Map) PostQueryHook {
+	return func(ctx context.Context, result *QueryResult) error {
+		cached := *result
+		cache.Store(result.Query, &cached)
+		return nil
+	}
+}
+
+// UsageTrackingHook 返回记录 token 用量的后置钩子：每次查询成功后以实际使用的提供商、模型和消耗的 token 数调用 track
+// 提供商未返回用量时 totalTokens 为 0
+// 参数:
+//   - track: 记录函数，并发查询时会被并发调用
+// 返回:
+//   - PostQueryHook: 后置钩子
+func UsageTrackingHook(track func(provider, model string, totalTokens int64)) PostQueryHook {
+	return func(ctx context.Context, result *QueryResult) error {
+		track(result.ProviderUsed, result.ModelUsed, result.TotalTokens)
+		return nil
+	}
+}
+
+// MetricsRecordHook 返回记录 Prometheus 指标的后置钩子：按提供商和模型统计成功查询消耗的 token 数
+// （agent_engine_query_tokens_total）和尝试次数（agent_engine_query_attempts）
+// 参数:
+//   - reg: 指标注册器，如 prometheus.DefaultRegisterer；已注册过相同指标时复用
+// 返回:
+//   - PostQueryHook: 后置钩子，注册指标失败时 panic（与 MetricsMiddleware 一致）
+func MetricsRecordHook(reg prometheus.Registerer) PostQueryHook {
+	tokens, err := registerCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_engine_query_tokens_total",
+		Help: "成功查询消耗的 token 数",
+	}, []string{"provider", "model"}))
+	if err != nil {
+		panic(err)
+	}
+	attempts, err := registerCollector(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agent_engine_query_attempts",
+		Help:    "成功查询的尝试次数",
+		Buckets: []float64{1, 2, 3, 5},
+	}, []string{"provider", "model"}))
+	if err != nil {
+		panic(err)
+	}
+	return func(ctx context.Context, result *QueryResult) error {
+		tokens.WithLabelValues(result.ProviderUsed, result.ModelUsed).Add(float64(result.TotalTokens))
+		attempts.WithLabelValues(result.ProviderUsed, result.ModelUsed).Observe(float64(result.Attempts))
+		return nil
+	}
+}
+
+// ResponseValidatorHook 返回校验回复的后置钩子：回复（去掉首尾空白后）少于 minLength 个字符，
+// 或不匹配任一正则表达式时返回 ErrInvalidResponse
+// 参数:
+//   - minLength: 回复的最少字符数，小于等于 0 时只要求回复非空
+//   - patterns: 回复必须匹配的正则表达式
+// 返回:
+//   - PostQueryHook: 后置钩子
+func ResponseValidatorHook(minLength int, patterns ...*regexp.Regexp) PostQueryHook {
+	return func(ctx context.Context, result *QueryResult) error {
+		reply := strings.TrimSpace(result.Reply)
+		if n := utf8.RuneCountInString(reply); n == 0 || n < minLength {
+			return fmt.Errorf("%w: 回复长度 %d 小于 %d", ErrInvalidResponse, n, max(minLength, 1))
+		}
+		for _, re := range patterns {
+			if !re.MatchString(reply) {
+				return fmt.Errorf("%w: 回复不匹配 %s", ErrInvalidResponse, re)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"agent_engine/conf"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiters 按提供商的 rate_limit 配置限制调用频率，由 Engine 及其所有副本共享
+// 每个提供商一组令牌桶，按调用时的当前提供商选择，SwitchProvider 后自动使用新提供商的限制
+type rateLimiters struct {
+	mu        sync.Mutex
+	providers map[string]*providerLimiter // 提供商名称 -> 令牌桶
+}
+
+// providerLimiter 单个提供商的令牌桶，limits 为创建时的配置，配置变化（如热重载）后重新创建
+type providerLimiter struct {
+	limits   conf.RateLimit
+	requests *rate.Limiter // 未配置 requests_per_minute 时为空
+	tokens   *rate.Limiter // 未配置 tokens_per_minute 时为空
+}
+
+// newRateLimiters 创建频率限制器
+func newRateLimiters() *rateLimiters {
+	return &rateLimiters{providers: make(map[string]*providerLimiter)}
+}
+
+// get 返回提供商的令牌桶，未配置限制时返回 nil
+func (l *rateLimiters) get(provider string, limits conf.RateLimit) *providerLimiter {
+	if limits.RequestsPerMinute <= 0 && limits.TokensPerMinute <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p, ok := l.providers[provider]; ok && p.limits == limits {
+		return p
+	}
+	p := &providerLimiter{limits: limits}
+	if n := limits.RequestsPerMinute; n > 0 {
+		p.requests = rate.NewLimiter(rate.Limit(float64(n)/60), 1)
+	}
+	if n := limits.TokensPerMinute; n > 0 {
+		p.tokens = rate.NewLimiter(rate.Limit(float64(n)/60), n)
+	}
+	l.providers[provider] = p
+	return p
+}
+
+// providerRateLimiter 返回当前提供商的令牌桶，未配置限制时返回 nil
+func (engine *Engine) providerRateLimiter() *providerLimiter {
+	if engine.rateLimiters == nil || engine.config == nil {
+		return nil
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return nil
+	}
+	return engine.rateLimiters.get(provider.Name, provider.RateLimit)
+}
+
+// waitRateLimit 调用模型前等待当前提供商的频率限制，配置了 tokens_per_minute 时按请求内容预估输入 token 数
+// 返回:
+//   - error: 等待时间会超过上下文截止时间（ErrRateLimited）或上下文被取消时返回错误，此时不占用配额
+func (engine *Engine) waitRateLimit(ctx context.Context, req *QueryRequest, query string) error {
+	limiter := engine.providerRateLimiter()
+	if limiter == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	var delay time.Duration
+	if limiter.requests != nil {
+		r := limiter.requests.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		delay = max(delay, r.DelayFrom(now))
+	}
+	tokens := 0
+	if limiter.tokens != nil {
+		tokens = min(engine.estimateRequestTokens(req, query), limiter.limits.TokensPerMinute)
+		r := limiter.tokens.ReserveN(now, tokens)
+		reservations = append(reservations, r)
+		delay = max(delay, r.DelayFrom(now))
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	provider := engine.GetCurrentProviderName()
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		cancel()
+		return fmt.Errorf("%w: 提供商 %s 需要等待 %s 才能发送请求（requests_per_minute=%d，tokens_per_minute=%d，预估 %d tokens），超过上下文截止时间（剩余 %s）",
+			ErrRateLimited, provider, delay.Round(time.Millisecond), limiter.limits.RequestsPerMinute, limiter.limits.TokensPerMinute, tokens, deadline.Sub(now).Round(time.Millisecond))
+	}
+	engine.debug(ctx, "等待提供商频率限制", "provider", provider, "delay", delay, "estimated_tokens", tokens)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("等待提供商 %s 的频率限制时上下文结束: %w", provider, ctx.Err())
+	}
+}
+
+// recordRateLimitTokens 调用成功后从当前提供商的 token 令牌桶中扣除生成的 token 数，之后的请求会相应等待
+func (engine *Engine) recordRateLimitTokens(completionTokens int64) {
+	limiter := engine.providerRateLimiter()
+	if limiter == nil || limiter.tokens == nil || completionTokens <= 0 {
+		return
+	}
+	limiter.tokens.ReserveN(time.Now(), min(int(completionTokens), limiter.limits.TokensPerMinute))
+}
+
+// estimateRequestTokens 预估一次查询的输入 token 数：系统提示、历史消息和查询内容
+func (engine *Engine) estimateRequestTokens(req *QueryRequest, query string) int {
+	var sb strings.Builder
+	sb.WriteString(engine.GetSystemPrompt())
+	for _, m := range req.History {
+		sb.WriteString(m.Content)
+	}
+	sb.WriteString(query)
+	return engine.estimateTokens(sb.String())
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"agent_engine/conf"
+)
+
+// 10 个 goroutine 各调用 100 次，每分钟 60 次的限制下实际速率与限制的误差不超过 5%
+const (
+	rateLimitWorkers = 10
+	rateLimitCalls   = 100
+	rateLimitRPM     = 60
+	rateLimitMargin  = 0.05
+)
+
+// checkRequestRate 检查调用时间满足每分钟 rpm 次的限制：整体速率的误差不超过 rateLimitMargin，任意一分钟内的调用次数也不超过该误差
+func checkRequestRate(tb testing.TB, times []time.Time, rpm int) float64 {
+	tb.Helper()
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	span := times[len(times)-1].Sub(times[0])
+	if span <= 0 {
+		tb.Fatalf("%d 次调用在同一时刻发生，没有被限制", len(times))
+	}
+	// 令牌桶容量为 1，第一次调用不等待，之后每次调用间隔 1/rpm 分钟
+	got := float64(len(times)-1) / span.Minutes()
+	if diff := got/float64(rpm) - 1; diff > rateLimitMargin || diff < -rateLimitMargin {
+		tb.Errorf("实际速率为每分钟 %.2f 次，与限制 %d 的误差 %.1f%% 超过 %.0f%%", got, rpm, diff*100, rateLimitMargin*100)
+	}
+	limit := int(float64(rpm) * (1 + rateLimitMargin))
+	for i, j := 0, 0; i < len(times); i++ {
+		for times[i].Sub(times[j]) >= time.Minute {
+			j++
+		}
+		if n := i - j + 1; n > limit {
+			tb.Fatalf("%s 开始的一分钟内调用了 %d 次，超过 %d 次", times[j].Format(time.TimeOnly), n, limit)
+		}
+	}
+	return got
+}
+
+// TestWaitRateLimitConcurrent 并发调用 waitRateLimit，使用 synctest 的虚拟时钟，不需要真的等待约 17 分钟
+func TestWaitRateLimitConcurrent(t *testing.T) {
+	config := newTestConfig(t, testProvider("mock", "http://127.0.0.1", "m1"))
+	config.Provider[0].RateLimit = conf.RateLimit{RequestsPerMinute: rateLimitRPM}
+	engine := newTestEngine(t, config)
+
+	synctest.Test(t, func(t *testing.T) {
+		var (
+			mu    sync.Mutex
+			times []time.Time
+			wg    sync.WaitGroup
+		)
+		for range rateLimitWorkers {
+			wg.Go(func() {
+				for range rateLimitCalls {
+					if err := engine.waitRateLimit(t.Context(), &QueryRequest{}, "ping"); err != nil {
+						t.Errorf("等待频率限制失败: %v", err)
+						return
+					}
+					mu.Lock()
+					times = append(times, time.Now())
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+		if len(times) != rateLimitWorkers*rateLimitCalls {
+			t.Fatalf("完成 %d 次调用，期望 %d 次", len(times), rateLimitWorkers*rateLimitCalls)
+		}
+		checkRequestRate(t, times, rateLimitRPM)
+	})
+}
+
+// BenchmarkRateLimiterConcurrent 10 个 goroutine 同时从令牌桶各预约 100 次调用，检查得到的调用时间满足限制
+// 预约在同一时刻进行，调用时间为预约时刻加上需要等待的时间，与 waitRateLimit 的行为一致
+func BenchmarkRateLimiterConcurrent(b *testing.B) {
+	limits := conf.RateLimit{RequestsPerMinute: rateLimitRPM}
+	var rpm float64
+	for b.Loop() {
+		limiter := newRateLimiters().get("mock", limits)
+		start := time.Now()
+		times := make([]time.Time, rateLimitWorkers*rateLimitCalls)
+		var wg sync.WaitGroup
+		for w := range rateLimitWorkers {
+			wg.Go(func() {
+				for i := range rateLimitCalls {
+					r := limiter.requests.ReserveN(start, 1)
+					times[w*rateLimitCalls+i] = start.Add(r.DelayFrom(start))
+				}
+			})
+		}
+		wg.Wait()
+		rpm = checkRequestRate(b, times, rateLimitRPM)
+	}
+	b.ReportMetric(rpm, "calls/min")
+}
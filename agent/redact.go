@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"slices"
+)
+
+// RedactAPIKeys 返回所有 API 密钥均已脱敏的 Engine 副本，可以安全地记录日志、序列化或输出配置
+// 当前密钥和配置中每个提供商的密钥替换为 "sk-***...{后4位}"，配置会被复制，不影响原 Engine；
+// 副本不包含 SetAPIKeyRotation 设置的密钥轮换。副本的密钥无效，不应用于发送查询
+// 返回:
+//   - *Engine: 脱敏后的 Engine 副本指针
+func (engine *Engine) RedactAPIKeys() *Engine {
+	clone := engine.Clone()
+	clone.apiKey = redactAPIKey(engine.apiKey)
+	clone.keyRotation = nil
+	if engine.config != nil {
+		config := *engine.config
+		config.Provider = slices.Clone(engine.config.Provider)
+		for i := range config.Provider {
+			config.Provider[i].ApiKey = redactAPIKey(config.Provider[i].ApiKey)
+		}
+		clone.config = &config
+	}
+	return clone
+}
+
+// GetConfig 获取当前配置，调用方不应修改返回的配置；需要输出配置时先调用 RedactAPIKeys
+// 返回:
+//   - *conf.Config: 配置对象，配置未加载时为 nil
+func (engine *Engine) GetConfig() *conf.Config {
+	return engine.config
+}
+
+// redactAPIKey 将密钥替换为 "sk-***...{后4位}"，不超过 8 位的密钥不保留任何字符，空字符串保持不变
+func redactAPIKey(key string) string {
+	switch {
+	case key == "":
+		return ""
+	case len(key) <= 8:
+		return "sk-***"
+	default:
+		return "sk-***..." + key[len(key)-4:]
+	}
+}
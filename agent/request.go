@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// QueryRequest 结构化的查询请求，替代直接传递字符串参数
+type QueryRequest struct {
+	Query            string         `json:"query"`                       // 查询内容，可包含 Go 模板占位符
+	TemplateVars     map[string]any `json:"template_vars,omitempty"`     // 模板变量，非空时使用其渲染 Query
+	OverrideModel    string         `json:"override_model,omitempty"`    // 本次请求使用的模型（为空则使用 Engine 当前模型）
+	OverrideProvider string         `json:"override_provider,omitempty"` // 本次请求使用的提供商（为空则使用 Engine 当前提供商）
+	N                int            `json:"n,omitempty"`                 // 生成的回复数量（小于等于 1 时只生成一个）
+	Stream           bool           `json:"stream,omitempty"`            // 是否流式输出
+	Images           []string       `json:"images,omitempty"`            // 随查询发送的图片 URL 或本地文件路径（以 / 或 ./ 开头），见 Engine.QueryWithImages
+	RetryPolicy      *RetryPolicy   `json:"-"`                           // 本次请求的重试策略（为空时使用默认行为），不参与序列化
+	History          []ChatMessage  `json:"history,omitempty"`           // 查询之前的对话消息（系统提示和历史轮次），按顺序发送
+}
+
+// 对话消息的角色
+const (
+	RoleSystem    = "system"    // 系统提示
+	RoleUser      = "user"      // 用户消息
+	RoleAssistant = "assistant" // 模型回复
+)
+
+// ChatMessage 对话中的一条消息
+type ChatMessage struct {
+	Role    string `json:"role"`    // 角色: system、user、assistant
+	Content string `json:"content"` // 消息内容
+}
+
+// chatMessages 将历史消息和本次查询转换为模型接口的消息列表
+func (req *QueryRequest) chatMessages(query string) ([]openai.ChatCompletionMessageParamUnion, error) {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(req.History)+1)
+	for _, m := range req.History {
+		switch m.Role {
+		case RoleSystem:
+			messages = append(messages, openai.SystemMessage(m.Content))
+		case RoleUser:
+			messages = append(messages, openai.UserMessage(m.Content))
+		case RoleAssistant:
+			messages = append(messages, openai.AssistantMessage(m.Content))
+		default:
+			return nil, fmt.Errorf("不支持的消息角色 %s", m.Role)
+		}
+	}
+	user, err := req.userMessage(query)
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, user), nil
+}
+
+// EventHandlerV2 定义接收结构化请求的事件处理接口
+type EventHandlerV2 interface {
+	HandleRequest(ctx context.Context, engine *Engine, req *QueryRequest, event string) (rsp any, err error)
+}
+
+// ParseQueryRequest 将字符串参数解析为结构化请求
+// JSON 对象会按 QueryRequest 的字段解析，其他内容整体作为查询内容
+// 参数:
+//   - params: 参数字符串
+// 返回:
+//   - *QueryRequest: 结构化请求
+//   - error: JSON 解析失败时返回错误
+func ParseQueryRequest(params string) (*QueryRequest, error) {
+	trimmed := strings.TrimSpace(params)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		var req QueryRequest
+		if err := json.Unmarshal([]byte(trimmed), &req); err != nil {
+			return nil, fmt.Errorf("解析请求参数失败: %w", err)
+		}
+		return &req, nil
+	}
+	return &QueryRequest{Query: params}, nil
+}
+
+// RenderQuery 使用 TemplateVars 渲染查询内容
+// 返回:
+//   - string: 渲染后的查询内容，TemplateVars 为空时原样返回
+//   - error: 模板解析或执行失败时返回错误
+func (req *QueryRequest) RenderQuery() (string, error) {
+	if len(req.TemplateVars) == 0 {
+		return req.Query, nil
+	}
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(req.Query)
+	if err != nil {
+		return "", fmt.Errorf("解析查询模板失败: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, req.TemplateVars); err != nil {
+		return "", fmt.Errorf("渲染查询模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// eventHandlerAdapter 将旧的 EventHandler 适配为 EventHandlerV2
+type eventHandlerAdapter struct {
+	handler EventHandler
+}
+
+// AdaptEventHandler 将旧的 EventHandler 适配为 EventHandlerV2
+// 请求会序列化为 JSON 字符串传给原处理器
+// 参数:
+//   - handler: 旧的事件处理器
+// 返回:
+//   - EventHandlerV2: 适配后的处理器，handler 本身已实现 EventHandlerV2 时直接返回
+func AdaptEventHandler(handler EventHandler) EventHandlerV2 {
+	if v2, ok := handler.(EventHandlerV2); ok {
+		return v2
+	}
+	return &eventHandlerAdapter{handler: handler}
+}
+
+// HandleRequest 将结构化请求序列化后交给原处理器
+// 不参与序列化的字段（如 RetryPolicy）通过上下文传递，见 requestFromContext
+func (a *eventHandlerAdapter) HandleRequest(ctx context.Context, engine *Engine, req *QueryRequest, event string) (rsp any, err error) {
+	params, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求参数失败: %w", err)
+	}
+	return a.handler.Handle(context.WithValue(ctx, requestContextKey, req), engine, string(params), event)
+}
+
+// requestFromContext 获取 eventHandlerAdapter 序列化前的原始请求，不是经适配器调用时返回 nil
+func requestFromContext(ctx context.Context) *QueryRequest {
+	req, _ := ctx.Value(requestContextKey).(*QueryRequest)
+	return req
+}
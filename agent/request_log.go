@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultRequestLogFile 默认的请求日志文件
+const DefaultRequestLogFile = "./agent_engine_logs/requests.jsonl"
+
+// RequestLogEntry 一次事件请求的日志记录
+type RequestLogEntry struct {
+	Time          time.Time `json:"time"`            // 请求开始时间
+	CorrelationID string    `json:"correlation_id"`  // 关联ID
+	Event         string    `json:"event"`           // 事件类型
+	Provider      string    `json:"provider"`        // 请求开始时的提供商名称
+	Model         string    `json:"model"`           // 请求开始时的模型ID
+	Label         string    `json:"label,omitempty"` // MultiQuery 中查询的标签
+	DurationMs    int64     `json:"duration_ms"`     // 处理耗时（毫秒）
+	Error         string    `json:"error,omitempty"` // 错误信息，成功时为空
+}
+
+// requestLog 以 JSONL 格式追加写入请求日志，由 Engine 及其所有副本共享
+// 写入持有写锁，导出持有读锁，导出期间的写入会等待导出完成，不会读到写了一半的记录
+type requestLog struct {
+	mu   sync.RWMutex
+	path string
+}
+
+// newRequestLog 创建请求日志
+func newRequestLog(path string) *requestLog {
+	if path == "" {
+		path = DefaultRequestLogFile
+	}
+	return &requestLog{path: path}
+}
+
+// add 追加一条记录
+func (l *requestLog) add(entry RequestLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化请求日志失败: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("创建请求日志目录失败: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开请求日志失败: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("写入请求日志失败: %w", err)
+	}
+	return f.Close()
+}
+
+// flush 等待进行中的写入完成后将日志文件同步到磁盘
+func (l *requestLog) flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := syncFile(l.path); err != nil {
+		return fmt.Errorf("同步请求日志失败: %w", err)
+	}
+	return nil
+}
+
+// dump 将 since 之后（含）的记录原样写入 w，文件不存在时不写入任何内容
+func (l *requestLog) dump(w io.Writer, since time.Time) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开请求日志失败: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry struct {
+				Time time.Time `json:"time"`
+			}
+			// 跳过无法解析的行（例如其他进程写入中断留下的不完整记录）
+			if json.Unmarshal(line, &entry) == nil && !entry.Time.Before(since) {
+				if line[len(line)-1] != '\n' {
+					line = append(line, '\n')
+				}
+				if _, werr := w.Write(line); werr != nil {
+					return fmt.Errorf("写出请求日志失败: %w", werr)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取请求日志失败: %w", err)
+		}
+	}
+}
+
+// startRequestLog 在请求开始时创建日志记录，记录当时的提供商和模型
+func (engine *Engine) startRequestLog(ctx context.Context, event string) RequestLogEntry {
+	return RequestLogEntry{
+		Time:          time.Now(),
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Event:         event,
+		Provider:      engine.GetCurrentProviderName(),
+		Model:         engine.ModelId,
+		Label:         queryLabelFromContext(ctx),
+	}
+}
+
+// logRequest 补充耗时和错误后写入请求日志，失败时只记录日志
+func (engine *Engine) logRequest(ctx context.Context, entry RequestLogEntry, err error) {
+	if engine.requestLog == nil {
+		return
+	}
+	entry.DurationMs = time.Since(entry.Time).Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if werr := engine.requestLog.add(entry); werr != nil {
+		LoggerFromContext(ctx).Warn("记录请求日志失败", "error", werr)
+	}
+}
+
+// DumpRequestLog 以 JSONL 格式导出指定时间之后的请求日志，每行一条 RequestLogEntry
+// 导出期间可以继续处理请求，新的记录会在导出完成后写入
+// 参数:
+//   - w: 输出目标
+//   - since: 起始时间（含），零值表示导出全部记录
+// 返回:
+//   - error: 读取日志文件或写出失败时返回错误
+func (engine *Engine) DumpRequestLog(w io.Writer, since time.Time) error {
+	if engine.requestLog == nil {
+		return fmt.Errorf("请求日志未启用")
+	}
+	return engine.requestLog.dump(w, since)
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// 请求队列的默认设置
+const (
+	DefaultQueueMaxSize = 100 // 默认最多排队的请求数
+	DefaultQueueWorkers = 1   // 默认并发处理请求的 worker 数
+)
+
+var (
+	// ErrQueueFull 队列已满时 Enqueue 返回的错误
+	ErrQueueFull = errors.New("请求队列已满")
+	// ErrQueueClosed 队列关闭后 Enqueue 返回的错误
+	ErrQueueClosed = errors.New("请求队列已关闭")
+)
+
+// QueuedRequest 排队等待处理的查询请求
+type QueuedRequest struct {
+	Priority   int                 // 优先级，数值越小越先处理，相同优先级按入队顺序处理
+	Query      string              // 查询内容
+	Context    context.Context     // 请求上下文，排队期间取消会将请求移出队列；为空时使用 context.Background()
+	ResultChan chan<- *QueryResult // 接收结果，每个处理过的请求恰好发送一次，失败时发送 nil（原因见 Err）
+
+	Err error // 处理失败的原因，在向 ResultChan 发送前设置，从 ResultChan 收到结果后读取
+
+	index int    // 在堆中的下标，不在队列中时为 -1
+	seq   uint64 // 入队序号，相同优先级时先入队的先处理
+	stop  func() bool
+}
+
+// requestHeap 按优先级和入队顺序排列的最小堆，实现 heap.Interface
+type requestHeap []*QueuedRequest
+
+func (h requestHeap) Len() int { return len(h) }
+
+func (h requestHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority < h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h requestHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *requestHeap) Push(x any) {
+	req := x.(*QueuedRequest)
+	req.index = len(*h)
+	*h = append(*h, req)
+}
+
+func (h *requestHeap) Pop() any {
+	old := *h
+	n := len(old)
+	req := old[n-1]
+	old[n-1] = nil
+	req.index = -1
+	*h = old[:n-1]
+	return req
+}
+
+// RequestQueue 按优先级处理查询请求的队列，适用于需要限制并发的服务场景
+// 首次 Enqueue 时启动 Workers 个 worker 调用 Engine.Query 处理请求
+type RequestQueue struct {
+	MaxSize int // 最多排队的请求数（不含正在处理的请求），小于等于 0 时使用 DefaultQueueMaxSize
+	Workers int // 并发处理请求的 worker 数，小于等于 0 时使用 DefaultQueueWorkers
+
+	engine *Engine
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  requestHeap
+	seq    uint64
+	start  sync.Once
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewRequestQueue 创建使用指定 Engine 处理请求的优先级队列
+// 参数:
+//   - engine: Engine 实例
+//   - maxSize: 最多排队的请求数，小于等于 0 时使用 DefaultQueueMaxSize
+//   - workers: 并发处理请求的 worker 数，小于等于 0 时使用 DefaultQueueWorkers
+// 返回:
+//   - *RequestQueue: 请求队列指针
+func NewRequestQueue(engine *Engine, maxSize int, workers int) *RequestQueue {
+	q := &RequestQueue{MaxSize: maxSize, Workers: workers, engine: engine}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Enqueue 将请求加入队列
+// 参数:
+//   - req: 查询请求，ResultChan 不能为空
+// 返回:
+//   - error: 队列已满（ErrQueueFull）、已关闭（ErrQueueClosed）、请求无效或上下文已取消时返回错误
+func (q *RequestQueue) Enqueue(req *QueuedRequest) error {
+	if req == nil || req.ResultChan == nil {
+		return fmt.Errorf("请求和 ResultChan 不能为空")
+	}
+	if req.Context == nil {
+		req.Context = context.Background()
+	}
+	if err := req.Context.Err(); err != nil {
+		return fmt.Errorf("请求已取消: %w", context.Cause(req.Context))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	maxSize := q.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultQueueMaxSize
+	}
+	if q.items.Len() >= maxSize {
+		return fmt.Errorf("%w（最多 %d 个）", ErrQueueFull, maxSize)
+	}
+
+	q.start.Do(q.startWorkers)
+	q.seq++
+	req.seq = q.seq
+	req.Err = nil
+	heap.Push(&q.items, req)
+	// 排队期间上下文取消时移出队列
+	req.stop = context.AfterFunc(req.Context, func() { q.remove(req) })
+	q.cond.Signal()
+	return nil
+}
+
+// Len 获取正在排队的请求数（不含正在处理的请求）
+func (q *RequestQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+// Close 停止接受新请求，等待已排队的请求处理完成；重复调用无副作用
+func (q *RequestQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// startWorkers 启动 worker，调用方需持有锁
+func (q *RequestQueue) startWorkers() {
+	workers := q.Workers
+	if workers <= 0 {
+		workers = DefaultQueueWorkers
+	}
+	for range workers {
+		q.wg.Go(q.work)
+	}
+}
+
+// remove 将请求移出队列，请求已被取出时不做任何事
+func (q *RequestQueue) remove(req *QueuedRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if req.index >= 0 && req.index < q.items.Len() && q.items[req.index] == req {
+		heap.Remove(&q.items, req.index)
+	}
+}
+
+// work 依次取出优先级最高的请求并处理，队列关闭且为空时退出
+func (q *RequestQueue) work() {
+	for {
+		q.mu.Lock()
+		for q.items.Len() == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.items.Len() == 0 {
+			q.mu.Unlock()
+			return
+		}
+		req := heap.Pop(&q.items).(*QueuedRequest)
+		req.stop()
+		q.mu.Unlock()
+
+		// worker 并发处理请求，QueryHandler 处理过程中会切换模型，每个请求使用独立的副本
+		result, err := q.engine.Clone().Query(req.Context, req.Query)
+		req.Err = err
+		// 调用方不再等待（上下文已取消）时放弃发送，避免阻塞 worker
+		select {
+		case req.ResultChan <- result:
+		case <-req.Context.Done():
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultArchiveExt SaveResponse 的目标为目录时生成的文件格式
+const DefaultArchiveExt = ".json"
+
+// archiveTimeLayout 自动生成的文件名中时间戳的格式
+const archiveTimeLayout = "20060102-150405"
+
+// archiveFrontMatter Markdown 归档文件的 YAML front matter
+type archiveFrontMatter struct {
+	Query         string         `yaml:"query"`
+	Model         string         `yaml:"model"`
+	Provider      string         `yaml:"provider"`
+	Attempts      int            `yaml:"attempts"`
+	CorrelationID string         `yaml:"correlation_id,omitempty"`
+	RequestID     string         `yaml:"request_id,omitempty"`
+	Fallback      bool           `yaml:"fallback,omitempty"`
+	Score         *ResponseScore `yaml:"score,omitempty"`
+	SavedAt       time.Time      `yaml:"saved_at"`
+}
+
+// SaveResponse 将查询结果保存到文件，格式由扩展名决定：
+// .json 为缩进的 JSON，.md 为带 YAML front matter（查询、模型、提供商等元数据）的 Markdown，.txt 为纯文本回复
+// path 为已存在的目录或以路径分隔符结尾时，在该目录下生成 {时间戳}-{模型}-{查询哈希前8位}.json 文件
+// 参数:
+//   - ctx: 上下文，用于记录日志
+//   - result: 查询结果
+//   - path: 文件路径或目录，父目录不存在时自动创建，文件已存在时覆盖
+// 返回:
+//   - error: 扩展名不支持、序列化或写入失败时返回错误
+func (engine *Engine) SaveResponse(ctx context.Context, result *QueryResult, path string) error {
+	if result == nil {
+		return fmt.Errorf("查询结果为空")
+	}
+	if path == "" {
+		return fmt.Errorf("保存路径不能为空")
+	}
+
+	now := time.Now()
+	if info, err := os.Stat(path); (err == nil && info.IsDir()) || os.IsPathSeparator(path[len(path)-1]) {
+		path = filepath.Join(path, archiveFileName(result, now, DefaultArchiveExt))
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		data, err = json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化查询结果失败: %w", err)
+		}
+		data = append(data, '\n')
+	case ".md":
+		data, err = archiveMarkdown(result, now)
+		if err != nil {
+			return err
+		}
+	case ".txt":
+		data = []byte(strings.Join(resultReplies(result), "\n\n") + "\n")
+	default:
+		return fmt.Errorf("不支持的文件格式 %q，可选值: .json、.md、.txt", ext)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	engine.loggerFrom(ctx).Info("已保存查询结果", "path", path, "model", result.ModelUsed)
+	return nil
+}
+
+// archiveFileName 生成 {时间戳}-{模型}-{查询哈希前8位}{ext} 格式的文件名，模型ID中的路径分隔符替换为 _
+func archiveFileName(result *QueryResult, now time.Time, ext string) string {
+	sum := sha256.Sum256([]byte(result.Query))
+	model := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(result.ModelUsed)
+	if model == "" {
+		model = "unknown"
+	}
+	return fmt.Sprintf("%s-%s-%s%s", now.Format(archiveTimeLayout), model, hex.EncodeToString(sum[:])[:8], ext)
+}
+
+// archiveMarkdown 生成带 YAML front matter 的 Markdown 文档
+func archiveMarkdown(result *QueryResult, now time.Time) ([]byte, error) {
+	meta, err := yaml.Marshal(archiveFrontMatter{
+		Query:         result.Query,
+		Model:         result.ModelUsed,
+		Provider:      result.ProviderUsed,
+		Attempts:      result.Attempts,
+		CorrelationID: result.CorrelationID,
+		RequestID:     result.RequestID,
+		Fallback:      result.Fallback,
+		Score:         result.Score,
+		SavedAt:       now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化元数据失败: %w", err)
+	}
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	sb.Write(meta)
+	sb.WriteString("---\n\n")
+	sb.WriteString(markdownBody(result))
+	return []byte(sb.String()), nil
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Cache 按键缓存任意值的缓存接口，Engine 用它缓存查询结果（见 WithResponseCache），实现需并发安全
+type Cache interface {
+	// Get 获取未过期的缓存值
+	Get(key string) (any, bool)
+	// Set 写入缓存值，ttl 后过期；ttl 小于等于 0 时不过期
+	Set(key string, val any, ttl time.Duration)
+}
+
+// MemoryCache 基于 sync.Map 的内存缓存，过期的条目由 time.AfterFunc 定时删除。并发安全
+type MemoryCache struct {
+	entries sync.Map // 键 -> *memoryCacheEntry
+}
+
+// memoryCacheEntry 一条缓存值
+type memoryCacheEntry struct {
+	val     any
+	expires time.Time   // 过期时间，零值表示不过期
+	timer   *time.Timer // 到期后删除条目的定时器，不过期时为空
+}
+
+// NewMemoryCache 创建内存缓存
+// 返回:
+//   - *MemoryCache: 内存缓存指针
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{}
+}
+
+// Get 获取未过期的缓存值
+// 参数:
+//   - key: 键
+// 返回:
+//   - any: 缓存值
+//   - bool: 是否命中
+func (c *MemoryCache) Get(key string) (any, bool) {
+	v, ok := c.entries.Load(key)
+	if !ok {
+		return nil, false
+	}
+	entry := v.(*memoryCacheEntry)
+	// 定时器可能还未执行，读取时再检查一次过期时间
+	if !entry.expires.IsZero() && !time.Now().Before(entry.expires) {
+		c.entries.CompareAndDelete(key, entry)
+		return nil, false
+	}
+	return entry.val, true
+}
+
+// Set 写入缓存值，替换同一键的旧值
+// 参数:
+//   - key: 键
+//   - val: 缓存值
+//   - ttl: 有效期，小于等于 0 时不过期
+func (c *MemoryCache) Set(key string, val any, ttl time.Duration) {
+	entry := &memoryCacheEntry{val: val}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+		// 只删除本次写入的条目，之后写入的新值不受旧定时器影响
+		entry.timer = time.AfterFunc(ttl, func() { c.entries.CompareAndDelete(key, entry) })
+	}
+	if old, loaded := c.entries.Swap(key, entry); loaded {
+		old.(*memoryCacheEntry).stop()
+	}
+}
+
+// Len 返回缓存的条目数（包括已过期但尚未删除的条目）
+func (c *MemoryCache) Len() int {
+	n := 0
+	c.entries.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Clear 清空缓存
+func (c *MemoryCache) Clear() {
+	c.entries.Range(func(key, v any) bool {
+		if c.entries.CompareAndDelete(key, v) {
+			v.(*memoryCacheEntry).stop()
+		}
+		return true
+	})
+}
+
+// stop 停止条目的过期定时器
+func (e *memoryCacheEntry) stop() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+}
+
+// WithResponseCache 返回使用指定缓存保存查询结果的 Engine 副本，原 Engine 不受影响
+// 提供商、模型、系统提示和查询内容都相同的查询在有效期内直接返回缓存的结果，不调用模型；
+// 只缓存不带历史、图片且只生成一个回复的查询。配置了 cache_ttl 时 Engine 默认使用 MemoryCache
+// 参数:
+//   - cache: 缓存，为 nil 时不缓存（如 --no-cache）
+//   - ttl: 缓存结果的有效期，小于等于 0 时不过期
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithResponseCache(cache Cache, ttl time.Duration) *Engine {
+	clone := engine.Clone()
+	clone.responseCache = cache
+	clone.responseCacheTTL = ttl
+	return clone
+}
+
+// GetResponseCache 获取保存查询结果的缓存
+// 返回:
+//   - Cache: 缓存，未启用时为 nil
+func (engine *Engine) GetResponseCache() Cache {
+	return engine.responseCache
+}
+
+// responseCacheKey 返回查询结果缓存的键：当前提供商、模型、系统提示和查询内容的 SHA-256
+func (engine *Engine) responseCacheKey(query string) string {
+	h := sha256.New()
+	for _, part := range []string{engine.GetCurrentProviderName(), engine.ModelId, engine.GetSystemPrompt(), query} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCacheable 请求的结果是否可以按 responseCacheKey 缓存：启用了缓存且不带历史、图片，只生成一个回复
+func (engine *Engine) responseCacheable(req *QueryRequest) bool {
+	return engine.responseCache != nil && len(req.History) == 0 && len(req.Images) == 0 && req.N <= 1
+}
+
+// lookupResponseCache 查找查询结果缓存，命中时返回结果的副本并关联到本次请求
+func (engine *Engine) lookupResponseCache(ctx context.Context, key string, query string) (*QueryResult, bool) {
+	v, ok := engine.responseCache.Get(key)
+	if !ok {
+		engine.debug(ctx, "查询结果缓存未命中", "key", key)
+		return nil, false
+	}
+	result, ok := v.(QueryResult)
+	if !ok {
+		return nil, false
+	}
+	result.Replies = slices.Clone(result.Replies)
+	result.CachedQuery = query
+	result.CacheSimilarity = 1
+	return engine.cachedResult(ctx, &result, query), true
+}
+
+// storeResponseCache 缓存查询结果的副本，不包括后置钩子的错误和评分
+func (engine *Engine) storeResponseCache(key string, result *QueryResult) {
+	cached := *result
+	cached.Replies = slices.Clone(result.Replies)
+	cached.PostHookErrors = nil
+	cached.Score = nil
+	engine.responseCache.Set(key, cached, engine.responseCacheTTL)
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheConcurrentGetSet(t *testing.T) {
+	cache := NewMemoryCache()
+	const (
+		workers = 16
+		rounds  = 500
+		keys    = 8
+	)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				key := fmt.Sprintf("key-%d", (w+i)%keys)
+				// 一半条目不过期，一半条目很快过期，读写和定时删除同时发生
+				ttl := time.Duration(0)
+				if i%2 == 1 {
+					ttl = time.Millisecond
+				}
+				cache.Set(key, key, ttl)
+				if v, ok := cache.Get(key); ok && v != key {
+					t.Errorf("Get(%q) = %v，期望 %q", key, v, key)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 最后写入不过期的值后，所有键都应命中
+	for i := range keys {
+		key := fmt.Sprintf("key-%d", i)
+		cache.Set(key, key, 0)
+	}
+	time.Sleep(10 * time.Millisecond)
+	for i := range keys {
+		key := fmt.Sprintf("key-%d", i)
+		if v, ok := cache.Get(key); !ok || v != key {
+			t.Errorf("Get(%q) = %v, %v，期望 %q, true", key, v, ok, key)
+		}
+	}
+	if n := cache.Len(); n != keys {
+		t.Errorf("Len() = %d，期望 %d", n, keys)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.Set("short", "v", 20*time.Millisecond)
+	cache.Set("forever", "v", 0)
+	if _, ok := cache.Get("short"); !ok {
+		t.Fatal("未过期的条目没有命中")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := cache.Get("short"); ok {
+		t.Error("过期的条目仍然命中")
+	}
+	if _, ok := cache.Get("forever"); !ok {
+		t.Error("不过期的条目没有命中")
+	}
+	// 定时器删除过期条目，不依赖 Get
+	cache.Set("timer", "v", 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if n := cache.Len(); n != 1 {
+		t.Errorf("过期条目被删除后 Len() = %d，期望 1", n)
+	}
+}
+
+func TestMemoryCacheReplaceStopsOldTimer(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.Set("k", "old", 10*time.Millisecond)
+	cache.Set("k", "new", 0)
+	time.Sleep(50 * time.Millisecond)
+	if v, ok := cache.Get("k"); !ok || v != "new" {
+		t.Errorf("Get(k) = %v, %v，旧值的定时器不应删除新值", v, ok)
+	}
+}
+
+func TestMemoryCacheConcurrentExpiry(t *testing.T) {
+	cache := NewMemoryCache()
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				key := fmt.Sprintf("%d-%d", w, i)
+				cache.Set(key, i, 5*time.Millisecond)
+				cache.Get(key)
+			}
+		}()
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := cache.Len(); n != 0 {
+		t.Errorf("全部过期后 Len() = %d，期望 0", n)
+	}
+}
+
+func TestQueryHandlerResponseCacheHit(t *testing.T) {
+	server := newChatServer(t, func(call int) (int, string) {
+		return http.StatusOK, fmt.Sprintf("回复 %d", call)
+	})
+	config := newTestConfig(t, testProvider("mock", server.URL, "m1"))
+	config.CacheTTL = time.Minute
+	engine := newTestEngine(t, config)
+
+	first, err := engine.QueryRequest(t.Context(), &QueryRequest{Query: "你好"})
+	if err != nil {
+		t.Fatalf("第一次查询失败: %v", err)
+	}
+	if first.CachedQuery != "" {
+		t.Errorf("第一次查询不应命中缓存，CachedQuery = %q", first.CachedQuery)
+	}
+
+	second, err := engine.QueryRequest(t.Context(), &QueryRequest{Query: "你好"})
+	if err != nil {
+		t.Fatalf("第二次查询失败: %v", err)
+	}
+	if got := server.calls(); got != 1 {
+		t.Errorf("相同查询调用模型 %d 次，期望 1 次", got)
+	}
+	if second.Reply != first.Reply {
+		t.Errorf("缓存命中的回复 = %q，期望 %q", second.Reply, first.Reply)
+	}
+	if second.CachedQuery != "你好" || second.CacheSimilarity != 1 {
+		t.Errorf("缓存命中的 CachedQuery = %q、CacheSimilarity = %v，期望 %q、1", second.CachedQuery, second.CacheSimilarity, "你好")
+	}
+
+	// 查询内容不同时不命中
+	if _, err := engine.QueryRequest(t.Context(), &QueryRequest{Query: "再见"}); err != nil {
+		t.Fatalf("第三次查询失败: %v", err)
+	}
+	if got := server.calls(); got != 2 {
+		t.Errorf("不同查询后调用模型 %d 次，期望 2 次", got)
+	}
+}
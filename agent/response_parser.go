@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"strings"
+)
+
+// CodeBlock 模型回复中的一个围栏代码块
+type CodeBlock struct {
+	Language  string `json:"language"`   // 语言标识（开始围栏后的第一个词），未标注时为空
+	Code      string `json:"code"`       // 代码内容，不含围栏
+	StartLine int    `json:"start_line"` // 开始围栏所在的行号（从 1 开始）
+}
+
+// ExtractCodeBlocks 提取回复中所有 Markdown 围栏代码块（``` 或 ~~~）
+// 结束围栏须使用相同字符且长度不小于开始围栏；缺少结束围栏的代码块延续到回复末尾
+// 参数:
+//   - reply: 模型回复
+// 返回:
+//   - []CodeBlock: 按出现顺序排列的代码块
+func ExtractCodeBlocks(reply string) []CodeBlock {
+	lines := strings.Split(strings.ReplaceAll(reply, "\r\n", "\n"), "\n")
+
+	var blocks []CodeBlock
+	for i := 0; i < len(lines); i++ {
+		fenceChar, fenceLen, info, ok := parseFence(lines[i])
+		if !ok {
+			continue
+		}
+		// 反引号围栏的语言标识中不能包含反引号（否则是行内代码）
+		if fenceChar == '`' && strings.Contains(info, "`") {
+			continue
+		}
+
+		block := CodeBlock{StartLine: i + 1}
+		if fields := strings.Fields(info); len(fields) > 0 {
+			block.Language = fields[0]
+		}
+
+		var code []string
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if c, n, rest, ok := parseFence(lines[j]); ok && c == fenceChar && n >= fenceLen && strings.TrimSpace(rest) == "" {
+				break
+			}
+			code = append(code, lines[j])
+		}
+		block.Code = strings.Join(code, "\n")
+		blocks = append(blocks, block)
+		i = j
+	}
+	return blocks
+}
+
+// parseFence 判断一行是否为围栏（最多 3 个空格缩进，至少 3 个 ` 或 ~），返回围栏字符、长度和其后的内容
+func parseFence(line string) (fenceChar byte, fenceLen int, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return 0, 0, "", false
+	}
+	fenceChar = trimmed[0]
+	if fenceChar != '`' && fenceChar != '~' {
+		return 0, 0, "", false
+	}
+	for fenceLen < len(trimmed) && trimmed[fenceLen] == fenceChar {
+		fenceLen++
+	}
+	if fenceLen < 3 {
+		return 0, 0, "", false
+	}
+	return fenceChar, fenceLen, strings.TrimSpace(trimmed[fenceLen:]), true
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 句子平均长度（词数）在该范围内时认为连贯性最好，中文每个汉字计为一个词
+const (
+	coherentSentenceMinWords = 5
+	coherentSentenceMaxWords = 30
+)
+
+// coherentSentenceCount 句子数达到该值时句子数得分为满分
+const coherentSentenceCount = 3
+
+// markdownPattern 匹配常见的 Markdown 语法：标题、列表、引用、表格、粗体、链接和行内代码
+var markdownPattern = regexp.MustCompile("(?m)^\\s{0,3}(#{1,6}\\s|[-*+]\\s|\\d+[.)]\\s|>\\s|\\|.*\\|)|\\*\\*[^*\\n]+\\*\\*|\\[[^\\]\\n]+\\]\\([^)\\n]+\\)|`[^`\\n]+`")
+
+// ResponseScore 基于启发式规则的回复质量评分，各分值范围为 0~1
+type ResponseScore struct {
+	Relevance    float64 `json:"relevance" yaml:"relevance"`         // 查询与回复词集合的 Jaccard 相似度
+	Coherence    float64 `json:"coherence" yaml:"coherence"`         // 根据句子数和平均句子长度估算的连贯性
+	Length       int     `json:"length" yaml:"length"`               // 回复的字符数
+	HasCode      bool    `json:"has_code" yaml:"has_code"`           // 回复是否包含围栏代码块
+	HasMarkdown  bool    `json:"has_markdown" yaml:"has_markdown"`   // 回复是否包含 Markdown 语法
+	OverallScore float64 `json:"overall_score" yaml:"overall_score"` // Relevance 和 Coherence 的平均值
+}
+
+// ScoreResponse 使用启发式规则为模型回复评分，不调用模型
+// 参数:
+//   - query: 查询内容
+//   - reply: 模型回复
+// 返回:
+//   - ResponseScore: 评分结果，回复为空时各分值均为 0
+func ScoreResponse(query, reply string) ResponseScore {
+	score := ResponseScore{
+		Length:      utf8.RuneCountInString(reply),
+		HasCode:     len(ExtractCodeBlocks(reply)) > 0,
+		HasMarkdown: markdownPattern.MatchString(reply),
+	}
+	score.Relevance = jaccard(toSet(scoreWords(query)), toSet(scoreWords(reply)))
+	score.Coherence = coherence(reply)
+	score.OverallScore = (score.Relevance + score.Coherence) / 2
+	return score
+}
+
+// scoreWords 将文本拆分为小写的词：连续的字母和数字为一个词，每个汉字为一个词
+func scoreWords(text string) []string {
+	var (
+		words []string
+		word  strings.Builder
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			words = append(words, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// coherence 根据句子数和平均句子长度估算连贯性：句子数得分和平均长度得分各占一半
+// 句子数达到 coherentSentenceCount 时句子数得分为 1；平均长度在合理范围内时长度得分为 1，过短或过长按比例降低
+func coherence(reply string) float64 {
+	sentences := strings.FieldsFunc(reply, func(r rune) bool {
+		return strings.ContainsRune(".!?。！？\n", r)
+	})
+	count, words := 0, 0
+	for _, s := range sentences {
+		if n := len(scoreWords(s)); n > 0 {
+			count++
+			words += n
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+
+	countScore := min(float64(count)/coherentSentenceCount, 1)
+	avg := float64(words) / float64(count)
+	lengthScore := 1.0
+	switch {
+	case avg < coherentSentenceMinWords:
+		lengthScore = avg / coherentSentenceMinWords
+	case avg > coherentSentenceMaxWords:
+		lengthScore = coherentSentenceMaxWords / avg
+	}
+	return (countScore + lengthScore) / 2
+}
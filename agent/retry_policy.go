@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// BackoffStrategy 根据失败的尝试次数（从 1 开始）计算下一次尝试前的等待时间
+type BackoffStrategy func(attempt int) time.Duration
+
+// NoBackoff 不等待，立即重试
+func NoBackoff() BackoffStrategy {
+	return func(int) time.Duration { return 0 }
+}
+
+// ConstantBackoff 每次重试前等待固定时间
+// 参数:
+//   - d: 等待时间
+func ConstantBackoff(d time.Duration) BackoffStrategy {
+	return func(int) time.Duration { return d }
+}
+
+// ExponentialBackoff 第 n 次失败后等待 base * 2^(n-1)，最长 maxDelay
+// 参数:
+//   - base: 第一次重试前的等待时间
+//   - maxDelay: 最长等待时间
+func ExponentialBackoff(base, maxDelay time.Duration) BackoffStrategy {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		return min(delay, maxDelay)
+	}
+}
+
+// RetryPolicy 单次查询的重试策略，覆盖 QueryHandler 默认的重试行为
+// 与默认行为一样，每次重试优先切换到未尝试过的模型；所有模型都尝试过后继续使用当前模型重试
+type RetryPolicy struct {
+	MaxAttempts        int                               // 最大尝试次数（包括第一次），小于等于 0 时使用默认值
+	BackoffStrategy    BackoffStrategy                   // 重试前的等待时间，为空时立即重试
+	RetryOnStatusCodes []int                             // 只在这些 HTTP 状态码时重试，为空时不按状态码限制
+	ShouldRetry        func(err error, attempt int) bool // 自定义是否重试，参数为最近的错误和已失败的尝试次数；设置后优先于 RetryOnStatusCodes
+	OnRetry            func(attempt int, err error)      // 每次重试前调用，参数为即将进行的尝试次数和最近的错误
+}
+
+// QueryWithRetryPolicy 使用指定的重试策略发送查询，只对本次调用生效
+// 参数:
+//   - ctx: 上下文，取消后不再重试
+//   - query: 查询内容
+//   - policy: 重试策略
+// 返回:
+//   - *QueryResult: 查询结果
+//   - error: 错误信息
+func (engine *Engine) QueryWithRetryPolicy(ctx context.Context, query string, policy RetryPolicy) (*QueryResult, error) {
+	return engine.QueryRequest(ctx, &QueryRequest{Query: query, RetryPolicy: &policy})
+}
+
+// shouldRetry 判断第 attempt 次尝试失败后是否重试，未设置策略时总是重试
+func (p *RetryPolicy) shouldRetry(ctx context.Context, err error, attempt int) bool {
+	if p == nil {
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(err, attempt)
+	}
+	if len(p.RetryOnStatusCodes) > 0 {
+		return slices.Contains(p.RetryOnStatusCodes, statusCodeOf(err))
+	}
+	return true
+}
+
+// beforeRetry 调用 OnRetry 并按退避策略等待，等待期间 ctx 结束时返回错误
+func (p *RetryPolicy) beforeRetry(ctx context.Context, attempt int, err error) error {
+	if p == nil {
+		return nil
+	}
+	if p.OnRetry != nil {
+		p.OnRetry(attempt+1, err)
+	}
+	if p.BackoffStrategy == nil {
+		return nil
+	}
+	delay := p.BackoffStrategy(attempt)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待重试时上下文结束: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+// GenerateSchema 根据函数的参数类型生成工具定义使用的 JSON Schema
+// 函数可以有一个可选的 context.Context 首参数，其余参数至多一个且必须是结构体（或结构体指针），生成 object 类型的 schema；
+// 没有其余参数时生成没有属性的 object schema
+// 结构体字段使用 json 标签作为属性名（json:"-" 的字段会被忽略），没有 omitempty 的字段为必填；
+// schema 标签可设置描述和枚举值，例如 schema:"description=温度单位,enum=celsius|fahrenheit"
+// 参数:
+//   - fn: 工具函数
+// 返回:
+//   - json.RawMessage: JSON Schema
+//   - error: fn 不是函数或参数不受支持时返回错误
+func GenerateSchema(fn any) (json.RawMessage, error) {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("生成 schema 失败: %T 不是函数", fn)
+	}
+
+	params := make([]reflect.Type, 0, t.NumIn())
+	for i := range t.NumIn() {
+		if i == 0 && t.In(i) == contextType {
+			continue
+		}
+		params = append(params, t.In(i))
+	}
+
+	var schema map[string]any
+	switch len(params) {
+	case 0:
+		schema = map[string]any{"type": "object", "properties": map[string]any{}}
+	case 1:
+		param := params[0]
+		if param.Kind() == reflect.Pointer {
+			param = param.Elem()
+		}
+		if param.Kind() != reflect.Struct || param == timeType {
+			return nil, fmt.Errorf("生成 schema 失败: 参数类型 %s 不是结构体", params[0])
+		}
+		var err error
+		if schema, err = typeSchema(param, map[reflect.Type]bool{}); err != nil {
+			return nil, fmt.Errorf("生成 schema 失败: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("生成 schema 失败: 函数有 %d 个参数，至多支持一个结构体参数", len(params))
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 schema 失败: %w", err)
+	}
+	return data, nil
+}
+
+// typeSchema 生成类型的 schema，visiting 记录正在生成的结构体，遇到递归引用时生成不含属性的 object
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) (map[string]any, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		// encoding/json 将 []byte 编码为 base64 字符串
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}, nil
+		}
+		items, err := typeSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("不支持键类型为 %s 的映射", t.Key())
+		}
+		values, err := typeSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{"type": "object"}, nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]any{}
+		required := []string{}
+		if err := structProperties(t, visiting, properties, &required); err != nil {
+			return nil, err
+		}
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema, nil
+	}
+	return nil, fmt.Errorf("不支持的类型 %s", t)
+}
+
+// structProperties 收集结构体字段的属性，没有 json 名称的匿名结构体字段与 encoding/json 一样展开
+func structProperties(t reflect.Type, visiting map[reflect.Type]bool, properties map[string]any, required *[]string) error {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, omitempty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			if err := structProperties(fieldType, visiting, properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop, err := typeSchema(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("字段 %s.%s: %w", t.Name(), field.Name, err)
+		}
+		if err := applySchemaTag(prop, field.Tag.Get("schema"), fieldType); err != nil {
+			return fmt.Errorf("字段 %s.%s: %w", t.Name(), field.Name, err)
+		}
+		properties[name] = prop
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// jsonFieldName 解析 json 标签，返回属性名（未指定时为空）、是否 omitempty 以及是否忽略该字段
+func jsonFieldName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// applySchemaTag 将 schema 标签中的 description 和 enum 写入属性
+// 标签格式为逗号分隔的 key=value，enum 的取值用 | 分隔；description 中可以包含逗号
+func applySchemaTag(prop map[string]any, tag string, t reflect.Type) error {
+	if tag == "" {
+		return nil
+	}
+
+	// 不以 key= 开头的片段属于上一项的值（例如描述中的逗号）
+	var pairs []string
+	for part := range strings.SplitSeq(tag, ",") {
+		if key, _, ok := strings.Cut(part, "="); ok && (key == "description" || key == "enum") {
+			pairs = append(pairs, part)
+		} else if len(pairs) > 0 {
+			pairs[len(pairs)-1] += "," + part
+		} else {
+			return fmt.Errorf("无效的 schema 标签 %q", tag)
+		}
+	}
+
+	for _, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		switch key {
+		case "description":
+			prop["description"] = value
+		case "enum":
+			values, err := enumValues(strings.Split(value, "|"), t)
+			if err != nil {
+				return err
+			}
+			prop["enum"] = values
+		}
+	}
+	return nil
+}
+
+// enumValues 按字段类型转换枚举值
+func enumValues(raw []string, t reflect.Type) ([]any, error) {
+	values := make([]any, 0, len(raw))
+	for _, v := range raw {
+		var (
+			value any = v
+			err   error
+		)
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value, err = strconv.ParseInt(v, 10, 64)
+		case reflect.Float32, reflect.Float64:
+			value, err = strconv.ParseFloat(v, 64)
+		case reflect.Bool:
+			value, err = strconv.ParseBool(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("枚举值 %q 与类型 %s 不匹配: %w", v, t, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"agent_engine/conf"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// selfTestMaxTokens 自检测试查询最多生成的 token 数
+const selfTestMaxTokens = 10
+
+func init() {
+	conf.RegisterProviderTester(newSelfTest)
+}
+
+// newSelfTest 创建 conf.Config.SelfTest 使用的自检函数
+// 所有提供商的自检共用一个 Engine（及其用量统计等共享状态），每次自检在切换到该提供商的副本上执行
+func newSelfTest(ctx context.Context, c *conf.Config) (func(ctx context.Context, providerName string) error, func(ctx context.Context) error, error) {
+	engine, err := newEngine(c, "", &c.Provider[0], "")
+	if err != nil {
+		return nil, nil, err
+	}
+	test := func(ctx context.Context, providerName string) error {
+		return engine.selfTestProvider(ctx, providerName)
+	}
+	return test, engine.Shutdown, nil
+}
+
+// selfTestProvider 在切换到指定提供商默认模型的副本上执行端到端自检
+// 依次使用 ValidateProviderConfig 校验配置、使用 TestAllProviders 的检查项测试连通性和密钥，并以默认模型发送一次测试查询
+func (engine *Engine) selfTestProvider(ctx context.Context, providerName string) error {
+	engine = engine.Clone()
+	if err := engine.SwitchProvider(providerName, ""); err != nil {
+		return err
+	}
+	ctx = withCorrelation(engine.withLogger(ctx))
+
+	if errs := engine.ValidateProviderConfig(providerName); len(errs) > 0 {
+		return fmt.Errorf("配置校验失败: %w", errors.Join(errs...))
+	}
+
+	check := engine.testProvider(ctx, providerName)
+	if !check.Reachable || !check.AuthValid {
+		return fmt.Errorf("连通性检查失败: %s", check.Error)
+	}
+
+	client := engine.newClient()
+	completion, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Messages:  []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Reply with OK.")},
+		Model:     engine.ModelId,
+		MaxTokens: openai.Int(selfTestMaxTokens),
+	}, append(engine.requestOptions(ctx), option.WithMaxRetries(0))...)
+	if err != nil {
+		engine.recordError(1, err)
+		return fmt.Errorf("测试查询失败: %w", err)
+	}
+	engine.recordUsage(ctx, completion.Usage.TotalTokens)
+	// 推理模型可能把 token 全部用于推理而回复为空，只要求返回结果
+	if len(completion.Choices) == 0 {
+		return fmt.Errorf("测试查询失败: 模型 %s 未返回任何结果", engine.ModelId)
+	}
+	return nil
+}
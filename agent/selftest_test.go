@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"agent_engine/conf"
+)
+
+func TestRunSelfTestSharesUsage(t *testing.T) {
+	var providers []conf.ProviderConfig
+	for i := range 4 {
+		server := newChatServer(t, func(call int) (int, string) { return http.StatusOK, "OK" })
+		providers = append(providers, testProvider(fmt.Sprintf("p%d", i), server.URL, "m1"))
+	}
+	config := newTestConfig(t, providers...)
+
+	results, err := config.RunSelfTest(t.Context())
+	if err != nil {
+		t.Fatalf("自检未通过: %v", err)
+	}
+	for i, r := range results {
+		if r.Provider != providers[i].Name || !r.Passed {
+			t.Errorf("results[%d] = %+v，期望 %s 通过", i, r, providers[i].Name)
+		}
+	}
+
+	// 所有提供商的用量都记录在同一个用量文件中，并发写入不会丢失
+	usage := newUsageTracker(config.UsageFile)
+	for _, p := range providers {
+		used, err := usage.used(p.Name, time.Now())
+		if err != nil {
+			t.Fatalf("读取用量失败: %v", err)
+		}
+		if used == 0 {
+			t.Errorf("提供商 %s 的用量为 0", p.Name)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 语义缓存的默认设置
+const (
+	DefaultSemanticCacheThreshold = 0.95 // 默认的最低余弦相似度
+	semanticCacheSize             = 1000 // 最多缓存的查询结果数，超出时淘汰最早写入的条目
+)
+
+// SemanticCache 按查询语义缓存查询结果
+// 条目按键（提供商、模型和系统提示）分组，只在键相同的条目中查找。
+// 查询内容完全相同时直接命中；否则获取查询的嵌入向量，与已缓存查询的嵌入向量比较，
+// 余弦相似度最高且不低于阈值的条目视为命中。并发安全
+type SemanticCache struct {
+	mu        sync.Mutex
+	threshold float64
+	embed     func(ctx context.Context, text string) ([]float64, error)
+	entries   []*semanticCacheEntry // 按写入顺序排列
+}
+
+// semanticCacheEntry 一条缓存的查询结果
+type semanticCacheEntry struct {
+	key     string // 见 Engine.queryCacheKey
+	query   string
+	vector  []float64
+	result  QueryResult
+	created time.Time
+}
+
+// NewSemanticCache 创建语义缓存
+// 参数:
+//   - threshold: 命中所需的最低余弦相似度，小于等于 0 时使用 DefaultSemanticCacheThreshold
+//   - embed: 获取嵌入向量的函数，通常为 Engine.GetEmbedding
+// 返回:
+//   - *SemanticCache: 语义缓存指针
+func NewSemanticCache(threshold float64, embed func(ctx context.Context, text string) ([]float64, error)) *SemanticCache {
+	if threshold <= 0 {
+		threshold = DefaultSemanticCacheThreshold
+	}
+	return &SemanticCache{threshold: threshold, embed: embed}
+}
+
+// Get 查找与查询语义相同的缓存结果
+// 参数:
+//   - ctx: 上下文
+//   - key: 缓存键，只查找键相同的条目
+//   - query: 查询内容
+// 返回:
+//   - *QueryResult: 缓存结果的副本，未命中时为 nil；CachedQuery 为命中的缓存查询，CacheSimilarity 为相似度
+//   - error: 获取嵌入向量失败时返回错误
+func (c *SemanticCache) Get(ctx context.Context, key string, query string) (*QueryResult, error) {
+	c.mu.Lock()
+	empty := true
+	for _, e := range c.entries {
+		if e.key != key {
+			continue
+		}
+		if e.query == query {
+			result := e.copyResult(1)
+			c.mu.Unlock()
+			return result, nil
+		}
+		empty = false
+	}
+	c.mu.Unlock()
+	if empty {
+		return nil, nil
+	}
+
+	vector, err := c.embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var (
+		best           *semanticCacheEntry
+		bestSimilarity float64
+	)
+	for _, e := range c.entries {
+		if e.key != key {
+			continue
+		}
+		similarity, err := cosineSimilarity(vector, e.vector)
+		if err != nil {
+			continue // 嵌入模型变更后维度不一致的条目无法比较
+		}
+		if similarity >= c.threshold && (best == nil || similarity > bestSimilarity) {
+			best, bestSimilarity = e, similarity
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best.copyResult(bestSimilarity), nil
+}
+
+// Put 获取查询的嵌入向量后缓存查询结果，键和查询都相同的旧条目会被替换
+// 参数:
+//   - ctx: 上下文
+//   - key: 缓存键
+//   - query: 查询内容
+//   - result: 查询结果，缓存的是副本
+// 返回:
+//   - error: 获取嵌入向量失败时返回错误
+func (c *SemanticCache) Put(ctx context.Context, key string, query string, result *QueryResult) error {
+	vector, err := c.embed(ctx, query)
+	if err != nil {
+		return err
+	}
+	entry := &semanticCacheEntry{key: key, query: query, vector: vector, result: *result, created: time.Now()}
+	entry.result.Replies = slices.Clone(result.Replies)
+	entry.result.PostHookErrors = nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = slices.DeleteFunc(c.entries, func(e *semanticCacheEntry) bool { return e.key == key && e.query == query })
+	if len(c.entries) >= semanticCacheSize {
+		c.entries = slices.Delete(c.entries, 0, len(c.entries)-semanticCacheSize+1)
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+// Len 返回缓存的条目数
+func (c *SemanticCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear 清空缓存
+func (c *SemanticCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// copyResult 返回缓存结果的副本，并标记命中的缓存查询和相似度
+func (e *semanticCacheEntry) copyResult(similarity float64) *QueryResult {
+	result := e.result
+	result.Replies = slices.Clone(e.result.Replies)
+	result.CachedQuery = e.query
+	result.CacheSimilarity = similarity
+	return &result
+}
+
+// GetQueryCache 获取查询结果缓存（配置了 cache_type 时启用，所有副本共享）
+// 返回:
+//   - *SemanticCache: 语义缓存指针，未启用时为 nil
+func (engine *Engine) GetQueryCache() *SemanticCache {
+	return engine.queryCache
+}
+
+// queryCacheKey 返回查询结果缓存的键：当前提供商、模型和系统提示的 SHA-256，不同模型或系统提示的结果互不命中
+func (engine *Engine) queryCacheKey() string {
+	return engine.responseCacheKey("")
+}
+
+// lookupQueryCache 在键为 key 的条目中查找查询结果缓存，命中时将结果关联到本次请求；获取嵌入向量失败时视为未命中
+func (engine *Engine) lookupQueryCache(ctx context.Context, key string, query string) (*QueryResult, bool) {
+	cached, err := engine.queryCache.Get(ctx, key, query)
+	if err != nil {
+		engine.loggerFrom(ctx).Warn("查找查询结果缓存失败", "error", err)
+		return nil, false
+	}
+	if cached == nil {
+		engine.debug(ctx, "查询结果缓存未命中")
+		return nil, false
+	}
+	engine.debug(ctx, "查询结果缓存命中", "cached_query", cached.CachedQuery, "similarity", cached.CacheSimilarity)
+	return engine.cachedResult(ctx, cached, query), true
+}
+
+// cachedResult 将缓存结果的副本关联到本次请求，清除只对原调用有意义的字段
+func (engine *Engine) cachedResult(ctx context.Context, cached *QueryResult, query string) *QueryResult {
+	cached.Query = query
+	cached.Attempts = 0
+	cached.TotalTokens = 0
+	cached.EffectiveMaxTokens = 0
+	cached.APIKeyUsed = ""
+	cached.CorrelationID = CorrelationIDFromContext(ctx)
+	cached.RequestID = RequestIDFromContext(ctx)
+	return cached
+}
+
+// cacheableRequest 请求的结果是否可以缓存：只缓存不带历史、图片，不指定提供商、模型且只生成一个回复的查询
+func (engine *Engine) cacheableRequest(req *QueryRequest) bool {
+	return engine.queryCache != nil && len(req.History) == 0 && len(req.Images) == 0 && req.N <= 1 &&
+		req.OverrideProvider == "" && req.OverrideModel == ""
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSemanticCacheScopedByKey(t *testing.T) {
+	// 所有查询的嵌入向量相同，只有键能区分条目
+	embed := func(ctx context.Context, text string) ([]float64, error) {
+		return []float64{1, 0}, nil
+	}
+	cache := NewSemanticCache(0, embed)
+	ctx := context.Background()
+	if err := cache.Put(ctx, "key-a", "你好", &QueryResult{Reply: "a"}); err != nil {
+		t.Fatalf("Put 失败: %v", err)
+	}
+	if err := cache.Put(ctx, "key-b", "你好", &QueryResult{Reply: "b"}); err != nil {
+		t.Fatalf("Put 失败: %v", err)
+	}
+	if n := cache.Len(); n != 2 {
+		t.Errorf("Len() = %d，期望 2", n)
+	}
+
+	for key, want := range map[string]string{"key-a": "a", "key-b": "b"} {
+		// 完全相同的查询和语义相近的查询都只命中同一键下的条目
+		for _, query := range []string{"你好", "您好"} {
+			result, err := cache.Get(ctx, key, query)
+			if err != nil {
+				t.Fatalf("Get 失败: %v", err)
+			}
+			if result == nil || result.Reply != want {
+				t.Errorf("Get(%q, %q) = %+v，期望 Reply %q", key, query, result, want)
+			}
+		}
+	}
+
+	result, err := cache.Get(ctx, "key-c", "你好")
+	if err != nil {
+		t.Fatalf("Get 失败: %v", err)
+	}
+	if result != nil {
+		t.Errorf("Get(key-c) = %+v，期望未命中", result)
+	}
+}
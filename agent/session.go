@@ -0,0 +1,410 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// summarizePrompt 生成会话摘要时追加在对话历史后的指令
+const summarizePrompt = "Summarize this conversation in 3-5 sentences."
+
+// defaultCompressionPrompt 未配置 compression_prompt 时压缩会话历史使用的指令
+const defaultCompressionPrompt = "Summarize the conversation above into a concise context summary. Preserve key facts, decisions, user preferences and open questions so the conversation can continue without the original messages."
+
+// compressedSummaryPrefix 压缩生成的摘要消息的前缀
+const compressedSummaryPrefix = "以下是之前对话的摘要：\n"
+
+var (
+	// ErrEmptySession 会话没有历史消息时返回的错误
+	ErrEmptySession = errors.New("会话没有历史消息")
+	// ErrSessionNotFound 会话ID不存在或会话已关闭时返回的错误
+	ErrSessionNotFound = errors.New("会话不存在")
+)
+
+// ConversationSession 多轮对话会话，自动维护对话历史
+// 每次 Send 都会把系统提示和全部历史轮次一起发送给模型；同一会话的 Send 串行执行
+// 会话创建后计入 Engine 的活跃会话，调用 Close 后移除
+type ConversationSession struct {
+	mu           sync.Mutex
+	id           string
+	engine       *Engine
+	systemPrompt string
+	history      []ChatMessage // 用户和模型的历史轮次，不含系统提示
+	summary      string        // 最近一次 Summarize 生成的摘要
+	maxTurns     int           // 保留的最多轮数，见配置文件的 max_turns
+}
+
+// NewSession 创建多轮对话会话
+// 会话使用 Engine 的副本，之后对原 Engine 切换提供商或模型不影响会话；不再使用时应调用 Close
+// 会话最多保留配置文件中 max_turns 轮，超出时丢弃最早的轮次
+// 参数:
+//   - systemPrompt: 系统提示，为空时不发送
+// 返回:
+//   - *ConversationSession: 会话指针
+func (engine *Engine) NewSession(systemPrompt string) *ConversationSession {
+	return engine.newSession(NewCorrelationID(), systemPrompt)
+}
+
+// newSession 以指定ID创建会话并计入活跃会话
+func (engine *Engine) newSession(id string, systemPrompt string) *ConversationSession {
+	clone := engine.Clone()
+	clone.conversation = nil // 会话自行维护历史
+	s := &ConversationSession{
+		id:           id,
+		engine:       clone,
+		systemPrompt: systemPrompt,
+		maxTurns:     engine.conversationMaxTurns(),
+	}
+	if engine.sessions != nil {
+		engine.sessions.Store(s.id, s)
+	}
+	return s
+}
+
+// GetActiveSessionCount 获取活跃会话数量（所有副本创建的会话）
+// 返回:
+//   - int: 已创建且未 Close 的会话数量
+func (engine *Engine) GetActiveSessionCount() int {
+	return len(engine.GetActiveSessions())
+}
+
+// GetActiveSessions 获取活跃会话的ID（所有副本创建的会话）
+// 返回:
+//   - []string: 已创建且未 Close 的会话ID，按字典序排列
+func (engine *Engine) GetActiveSessions() []string {
+	if engine.sessions == nil {
+		return nil
+	}
+	var ids []string
+	engine.sessions.Range(func(key, _ any) bool {
+		ids = append(ids, key.(string))
+		return true
+	})
+	slices.Sort(ids)
+	return ids
+}
+
+// ID 获取会话ID
+func (s *ConversationSession) ID() string {
+	return s.id
+}
+
+// Close 结束会话，将其从 Engine 的活跃会话中移除；重复调用无副作用
+func (s *ConversationSession) Close() {
+	if s.engine.sessions != nil {
+		s.engine.sessions.Delete(s.id)
+	}
+}
+
+// Send 发送一条用户消息，成功后将该消息和模型回复追加到历史，超过最多轮数时丢弃最早的轮次
+// 参数:
+//   - ctx: 上下文
+//   - message: 用户消息
+// 返回:
+//   - *QueryResult: 查询结果
+//   - error: 调用失败时返回错误，此时历史不变
+func (s *ConversationSession) Send(ctx context.Context, message string) (*QueryResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.engine.QueryRequest(ctx, &QueryRequest{Query: message, History: s.fullHistory()})
+	if err != nil {
+		return nil, err
+	}
+
+	s.history = append(s.history,
+		ChatMessage{Role: RoleUser, Content: message},
+		ChatMessage{Role: RoleAssistant, Content: result.Reply},
+	)
+	s.trim()
+	return result, nil
+}
+
+// Summarize 将完整对话历史发送给模型生成 3-5 句话的摘要
+// 摘要请求不会追加到对话历史，生成的摘要会保存在会话中并随 Export 导出
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - string: 摘要内容
+//   - error: 会话为空（ErrEmptySession）或调用失败时返回错误
+func (s *ConversationSession) Summarize(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.history) == 0 {
+		return "", ErrEmptySession
+	}
+	result, err := s.engine.QueryRequest(ctx, &QueryRequest{Query: summarizePrompt, History: s.fullHistory()})
+	if err != nil {
+		return "", fmt.Errorf("生成会话摘要失败: %w", err)
+	}
+	s.summary = result.Reply
+	return s.summary, nil
+}
+
+// CompressConversation 压缩活跃会话的历史：历史消息超过 targetMessages 条时，
+// 使用模型将最早的若干条消息总结为一条摘要消息并替换它们，压缩后历史恰好为 targetMessages 条
+// 与直接删除旧消息不同，摘要保留了旧消息的主要内容；压缩指令可通过配置文件的 compression_prompt 设置
+// 参数:
+//   - ctx: 上下文
+//   - sessionId: 会话ID，见 ConversationSession.ID
+//   - targetMessages: 压缩后保留的消息数量（含摘要消息），至少为 2
+// 返回:
+//   - error: 会话不存在（ErrSessionNotFound）、参数无效或调用失败时返回错误，失败时历史不变
+func (engine *Engine) CompressConversation(ctx context.Context, sessionId string, targetMessages int) error {
+	s, err := engine.lookupSession(sessionId)
+	if err != nil {
+		return err
+	}
+	return s.Compress(ctx, targetMessages)
+}
+
+// SummarizeConversation 为活跃会话生成 3-5 句话的摘要，见 ConversationSession.Summarize
+// 摘要保存在会话中，随 Export 和 SaveConversation 一起写出
+// 参数:
+//   - ctx: 上下文
+//   - sessionId: 会话ID，见 ConversationSession.ID；--session 使用的会话需先通过 ResumeConversation 加载
+// 返回:
+//   - string: 摘要内容
+//   - error: 会话不存在（ErrSessionNotFound）、会话为空（ErrEmptySession）或调用失败时返回错误
+func (engine *Engine) SummarizeConversation(ctx context.Context, sessionId string) (string, error) {
+	s, err := engine.lookupSession(sessionId)
+	if err != nil {
+		return "", err
+	}
+	return s.Summarize(ctx)
+}
+
+// Reset 清空对话历史和摘要，保留系统提示
+func (s *ConversationSession) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = nil
+	s.summary = ""
+}
+
+// History 获取对话历史的副本（不含系统提示）
+// 返回:
+//   - []ChatMessage: 按时间顺序排列的历史消息
+func (s *ConversationSession) History() []ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ChatMessage(nil), s.history...)
+}
+
+// sessionExport 会话导出的 JSON 结构
+type sessionExport struct {
+	ID           string        `json:"id"`                // 会话ID
+	SystemPrompt string        `json:"system_prompt"`     // 系统提示
+	Provider     string        `json:"provider"`          // 会话使用的提供商
+	Model        string        `json:"model"`             // 会话使用的模型
+	Summary      string        `json:"summary,omitempty"` // 最近一次生成的摘要
+	Messages     []ChatMessage `json:"messages"`          // 历史消息
+}
+
+// Export 将会话序列化为 JSON 写入 w
+// 参数:
+//   - w: 输出目标
+// 返回:
+//   - error: 序列化或写入失败时返回错误
+func (s *ConversationSession) Export(w io.Writer) error {
+	s.mu.Lock()
+	data := sessionExport{
+		ID:           s.id,
+		SystemPrompt: s.systemPrompt,
+		Provider:     s.engine.GetCurrentProviderName(),
+		Model:        s.engine.ModelId,
+		Summary:      s.summary,
+		Messages:     append([]ChatMessage{}, s.history...),
+	}
+	s.mu.Unlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("导出会话失败: %w", err)
+	}
+	return nil
+}
+
+// lookupSession 按ID查找会话
+func (engine *Engine) lookupSession(sessionId string) (*ConversationSession, error) {
+	if engine.sessions == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionId)
+	}
+	v, ok := engine.sessions.Load(sessionId)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionId)
+	}
+	return v.(*ConversationSession), nil
+}
+
+// Compress 压缩会话历史，见 Engine.CompressConversation
+// 参数:
+//   - ctx: 上下文
+//   - targetMessages: 压缩后保留的消息数量（含摘要消息），至少为 2
+// 返回:
+//   - error: 参数无效或调用失败时返回错误，失败时历史不变
+func (s *ConversationSession) Compress(ctx context.Context, targetMessages int) error {
+	if targetMessages < 2 {
+		return fmt.Errorf("targetMessages 至少为 2，实际为 %d", targetMessages)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.history) <= targetMessages {
+		return nil
+	}
+	// 摘要消息占用一条，最早的 n 条消息被替换
+	n := len(s.history) - targetMessages + 1
+
+	summary, err := s.summarizeMessages(ctx, s.history[:n])
+	if err != nil {
+		return fmt.Errorf("压缩会话历史失败: %w", err)
+	}
+
+	history := make([]ChatMessage, 0, targetMessages)
+	history = append(history, summary)
+	s.history = append(history, s.history[n:]...)
+	return nil
+}
+
+// PruneConversation 删除会话中较早的轮次，只保留最近的 maxMessages 条消息
+// 保留部分从用户消息开始，不会拆开一问一答，因此可能少于 maxMessages 条；系统提示和历史开头的摘要消息始终保留。
+// 被删除的内容不再发送给模型，需要保留其要点时使用 PruneConversationWithSummary；
+// 配置文件的 prune_after 大于 0 时，会话历史超过该条数后会在每轮结束时自动删除
+// 参数:
+//   - sessionId: 会话ID，见 ConversationSession.ID
+//   - maxMessages: 保留的最近消息数量（不含摘要消息），不能为负数
+// 返回:
+//   - error: 会话不存在（ErrSessionNotFound）或参数无效时返回错误
+func (engine *Engine) PruneConversation(sessionId string, maxMessages int) error {
+	s, err := engine.lookupSession(sessionId)
+	if err != nil {
+		return err
+	}
+	return s.Prune(maxMessages)
+}
+
+// PruneConversationWithSummary 与 PruneConversation 相同，但先使用模型将被删除的消息（含之前的摘要消息）
+// 总结为一条新的摘要消息放在历史开头，之后随系统提示一起发送
+// 参数:
+//   - ctx: 上下文
+//   - sessionId: 会话ID，见 ConversationSession.ID
+//   - maxMessages: 保留的最近消息数量（不含摘要消息），不能为负数
+// 返回:
+//   - error: 会话不存在（ErrSessionNotFound）、参数无效或调用失败时返回错误，失败时历史不变
+func (engine *Engine) PruneConversationWithSummary(ctx context.Context, sessionId string, maxMessages int) error {
+	s, err := engine.lookupSession(sessionId)
+	if err != nil {
+		return err
+	}
+	return s.PruneWithSummary(ctx, maxMessages)
+}
+
+// Prune 删除较早的轮次，见 Engine.PruneConversation
+// 参数:
+//   - maxMessages: 保留的最近消息数量（不含摘要消息），不能为负数
+// 返回:
+//   - error: 参数无效时返回错误
+func (s *ConversationSession) Prune(maxMessages int) error {
+	if maxMessages < 0 {
+		return fmt.Errorf("maxMessages 不能为负数，实际为 %d", maxMessages)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(maxMessages)
+	return nil
+}
+
+// PruneWithSummary 删除较早的轮次并生成被删除部分的摘要，见 Engine.PruneConversationWithSummary
+// 参数:
+//   - ctx: 上下文
+//   - maxMessages: 保留的最近消息数量（不含摘要消息），不能为负数
+// 返回:
+//   - error: 参数无效或调用失败时返回错误，失败时历史不变
+func (s *ConversationSession) PruneWithSummary(ctx context.Context, maxMessages int) error {
+	if maxMessages < 0 {
+		return fmt.Errorf("maxMessages 不能为负数，实际为 %d", maxMessages)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cut := s.pruneIndex(maxMessages)
+	if cut == 0 {
+		return nil
+	}
+	summary, err := s.summarizeMessages(ctx, s.history[:cut])
+	if err != nil {
+		return fmt.Errorf("生成被删除消息的摘要失败: %w", err)
+	}
+	s.history = append([]ChatMessage{summary}, s.history[cut:]...)
+	return nil
+}
+
+// prune 删除较早的轮次，保留历史开头的摘要消息，调用方需持有锁
+func (s *ConversationSession) prune(maxMessages int) {
+	cut := s.pruneIndex(maxMessages)
+	if cut == 0 {
+		return
+	}
+	summaries := s.leadingSummaries()
+	s.history = append(slices.Clone(s.history[:summaries]), s.history[cut:]...)
+}
+
+// pruneIndex 返回保留最近 maxMessages 条消息时保留部分的起始位置，保留部分从用户消息开始；
+// 不需要删除时返回 0，调用方需持有锁
+func (s *ConversationSession) pruneIndex(maxMessages int) int {
+	summaries := s.leadingSummaries()
+	if len(s.history)-summaries <= maxMessages {
+		return 0
+	}
+	cut := len(s.history) - maxMessages
+	for cut < len(s.history) && s.history[cut].Role != RoleUser {
+		cut++
+	}
+	return cut
+}
+
+// leadingSummaries 返回历史开头的摘要消息（见 PruneWithSummary）数量，调用方需持有锁
+func (s *ConversationSession) leadingSummaries() int {
+	n := 0
+	for n < len(s.history) && s.history[n].Role == RoleSystem {
+		n++
+	}
+	return n
+}
+
+// summarizeMessages 使用模型将消息总结为一条摘要消息，调用方需持有锁
+func (s *ConversationSession) summarizeMessages(ctx context.Context, messages []ChatMessage) (ChatMessage, error) {
+	prompt := defaultCompressionPrompt
+	if s.engine.config != nil && s.engine.config.CompressionPrompt != "" {
+		prompt = s.engine.config.CompressionPrompt
+	}
+	history := make([]ChatMessage, 0, len(messages)+1)
+	if s.systemPrompt != "" {
+		history = append(history, ChatMessage{Role: RoleSystem, Content: s.systemPrompt})
+	}
+	result, err := s.engine.QueryRequest(ctx, &QueryRequest{Query: prompt, History: append(history, messages...)})
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	return ChatMessage{Role: RoleSystem, Content: compressedSummaryPrefix + result.Reply}, nil
+}
+
+// fullHistory 返回发送给模型的历史消息：系统提示加全部历史轮次，调用方需持有锁
+func (s *ConversationSession) fullHistory() []ChatMessage {
+	history := make([]ChatMessage, 0, len(s.history)+1)
+	if s.systemPrompt != "" {
+		history = append(history, ChatMessage{Role: RoleSystem, Content: s.systemPrompt})
+	}
+	return append(history, s.history...)
+}
@@ -0,0 +1,336 @@
+package agent
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// SSE 读取的默认设置
+const (
+	sseMaxReconnects     = 3                      // 连接中断后最多重新连接的次数
+	sseDefaultRetry      = 500 * time.Millisecond // 服务端未通过 retry 字段指定时重新连接前的等待时间
+	sseMaxLineSize       = 1 << 20                // 单行最大长度
+	sseDoneData          = "[DONE]"               // 表示流结束的 data 内容
+	sseLastEventIDHeader = "Last-Event-ID"        // 重新连接时携带最后收到的事件ID的请求头
+)
+
+// sseEvent 一个 Server-Sent Events 事件
+type sseEvent struct {
+	ID    string // 事件ID，未指定时沿用上一个事件的ID
+	Event string // 事件类型，未指定时为空（即 message）
+	Data  string // 多行 data 以换行连接
+}
+
+// sseReader 按 text/event-stream 格式逐个读取事件
+// 读取中断（非正常结束）时，如果收到过事件ID，则携带 Last-Event-ID 重新连接并继续读取；
+// 没有事件ID时无法从断点继续，直接返回错误，避免重复内容
+type sseReader struct {
+	ctx         context.Context
+	connect     func(lastEventID string) (io.ReadCloser, error) // 建立（重新）连接
+	body        io.ReadCloser
+	scanner     *bufio.Scanner
+	lastEventID string
+	retry       time.Duration
+	reconnects  int
+	done        bool
+}
+
+// newSSEReader 建立连接并创建 SSE 读取器
+func newSSEReader(ctx context.Context, connect func(lastEventID string) (io.ReadCloser, error)) (*sseReader, error) {
+	body, err := connect("")
+	if err != nil {
+		return nil, err
+	}
+	r := &sseReader{ctx: ctx, connect: connect, retry: sseDefaultRetry}
+	r.reset(body)
+	return r, nil
+}
+
+// reset 使用新的连接继续读取
+func (r *sseReader) reset(body io.ReadCloser) {
+	r.body = body
+	r.scanner = bufio.NewScanner(body)
+	r.scanner.Buffer(make([]byte, 0, 64*1024), sseMaxLineSize)
+}
+
+// Close 关闭当前连接
+func (r *sseReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
+
+// Next 读取下一个事件，收到 data: [DONE] 或连接正常结束时返回 io.EOF
+func (r *sseReader) Next() (sseEvent, error) {
+	if r.done {
+		return sseEvent{}, io.EOF
+	}
+	var (
+		event sseEvent
+		data  []string
+	)
+	for {
+		if !r.scanner.Scan() {
+			err := r.scanner.Err()
+			if err == nil {
+				// 连接正常结束，最后一个事件缺少结尾空行时仍然分发
+				r.done = true
+				if len(data) > 0 {
+					event.Data = strings.Join(data, "\n")
+					return r.dispatch(event)
+				}
+				return sseEvent{}, io.EOF
+			}
+			if rerr := r.reconnect(err); rerr != nil {
+				return sseEvent{}, rerr
+			}
+			// 重新连接后丢弃未完成的事件，服务端会从 Last-Event-ID 之后重新发送
+			event, data = sseEvent{}, nil
+			continue
+		}
+
+		line := r.scanner.Text()
+		if line == "" {
+			if len(data) == 0 {
+				event = sseEvent{}
+				continue
+			}
+			event.Data = strings.Join(data, "\n")
+			return r.dispatch(event)
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // 注释，通常用于保持连接
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			event.Event = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				event.ID = value
+				r.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				r.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// dispatch 返回完整的事件，data 为 [DONE] 时结束读取
+func (r *sseReader) dispatch(event sseEvent) (sseEvent, error) {
+	if event.ID == "" {
+		event.ID = r.lastEventID
+	}
+	if strings.TrimSpace(event.Data) == sseDoneData {
+		r.done = true
+		return sseEvent{}, io.EOF
+	}
+	return event, nil
+}
+
+// reconnect 读取出错后等待 retry 时长并携带 Last-Event-ID 重新连接
+func (r *sseReader) reconnect(cause error) error {
+	r.body.Close()
+	if r.lastEventID == "" {
+		return fmt.Errorf("读取 SSE 流失败（服务端未提供事件ID，无法重新连接）: %w", cause)
+	}
+	if r.reconnects >= sseMaxReconnects {
+		return fmt.Errorf("读取 SSE 流失败（已重新连接 %d 次）: %w", r.reconnects, cause)
+	}
+	r.reconnects++
+	LoggerFromContext(r.ctx).Warn("SSE 连接中断，重新连接", "last_event_id", r.lastEventID, "attempt", r.reconnects, "error", cause)
+
+	timer := time.NewTimer(r.retry)
+	defer timer.Stop()
+	select {
+	case <-r.ctx.Done():
+		return context.Cause(r.ctx)
+	case <-timer.C:
+	}
+
+	body, err := r.connect(r.lastEventID)
+	if err != nil {
+		return fmt.Errorf("重新连接 SSE 流失败: %w", err)
+	}
+	r.reset(body)
+	return nil
+}
+
+// sseChunk 流式响应中一个对话补全分片，只解析组装结果需要的字段
+type sseChunk struct {
+	ID      string `json:"id"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage json.RawMessage `json:"usage"`
+	Error json.RawMessage `json:"error"`
+}
+
+// sseChoice 组装中的一个回复
+type sseChoice struct {
+	content      strings.Builder
+	reasoning    strings.Builder
+	finishReason string
+}
+
+// completionAssembler 将流式响应的分片组装为完整的 ChatCompletion
+type completionAssembler struct {
+	id, model string
+	created   int64
+	usage     json.RawMessage
+	choices   map[int]*sseChoice
+}
+
+// newCompletionAssembler 创建分片组装器
+func newCompletionAssembler() *completionAssembler {
+	return &completionAssembler{choices: make(map[int]*sseChoice)}
+}
+
+// add 合并一个分片，返回第一个回复（index 0）新增的内容；分片携带错误时返回错误
+func (a *completionAssembler) add(chunk sseChunk) (string, error) {
+	if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+		return "", fmt.Errorf("提供商在流式响应中返回错误: %s", chunk.Error)
+	}
+	a.id, a.model = cmp.Or(chunk.ID, a.id), cmp.Or(chunk.Model, a.model)
+	a.created = max(a.created, chunk.Created)
+	if len(chunk.Usage) > 0 && string(chunk.Usage) != "null" {
+		a.usage = chunk.Usage
+	}
+	var delta strings.Builder
+	for _, c := range chunk.Choices {
+		choice := a.choices[c.Index]
+		if choice == nil {
+			choice = &sseChoice{}
+			a.choices[c.Index] = choice
+		}
+		choice.content.WriteString(c.Delta.Content)
+		choice.reasoning.WriteString(c.Delta.ReasoningContent)
+		if c.FinishReason != "" {
+			choice.finishReason = c.FinishReason
+		}
+		if c.Index == 0 {
+			delta.WriteString(c.Delta.Content)
+		}
+	}
+	return delta.String(), nil
+}
+
+// completion 按非流式响应的格式组装后反序列化，使 RawJSON 和 reasoning_content 等扩展字段与非流式调用一致
+// 参数:
+//   - model: 分片中没有模型ID时使用的模型
+func (a *completionAssembler) completion(model string) (*openai.ChatCompletion, error) {
+	indexes := slices.Sorted(maps.Keys(a.choices))
+	assembled := map[string]any{
+		"id":      a.id,
+		"object":  "chat.completion",
+		"created": a.created,
+		"model":   cmp.Or(a.model, model),
+	}
+	list := make([]any, 0, len(indexes))
+	for _, i := range indexes {
+		message := map[string]any{"role": "assistant", "content": a.choices[i].content.String()}
+		if a.choices[i].reasoning.Len() > 0 {
+			message["reasoning_content"] = a.choices[i].reasoning.String()
+		}
+		list = append(list, map[string]any{"index": i, "message": message, "finish_reason": cmp.Or(a.choices[i].finishReason, "stop")})
+	}
+	assembled["choices"] = list
+	if a.usage != nil {
+		assembled["usage"] = a.usage
+	}
+	data, err := json.Marshal(assembled)
+	if err != nil {
+		return nil, fmt.Errorf("组装流式响应失败: %w", err)
+	}
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return nil, fmt.Errorf("组装流式响应失败: %w", err)
+	}
+	return &completion, nil
+}
+
+// sseMode 当前提供商是否配置了 sse_mode
+func (engine *Engine) sseMode() bool {
+	if engine.config == nil {
+		return false
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	return err == nil && provider.SSEMode
+}
+
+// sseChatCompletion 以流式请求调用对话补全接口，按 SSE 格式自行解析响应并组装为完整的 ChatCompletion
+// 用于只支持 SSE 流式输出、其流格式与 openai-go 的流式客户端不兼容的提供商；组装结果与非流式响应的结构相同
+// w 不为空时将第一个回复的内容分片依次写入 w（见 Engine.StreamQuery）
+func (engine *Engine) sseChatCompletion(ctx context.Context, client openai.Client, params openai.ChatCompletionNewParams, w io.Writer) (*openai.ChatCompletion, error) {
+	connect := func(lastEventID string) (io.ReadCloser, error) {
+		opts := append(engine.requestOptions(ctx),
+			option.WithJSONSet("stream", true),
+			option.WithJSONSet("stream_options", map[string]any{"include_usage": true}),
+			option.WithHeader("Accept", "text/event-stream"))
+		if lastEventID != "" {
+			opts = append(opts, option.WithHeader(sseLastEventIDHeader, lastEventID))
+		}
+		var res *http.Response
+		if err := client.Post(ctx, "chat/completions", params, &res, opts...); err != nil {
+			return nil, err
+		}
+		return res.Body, nil
+	}
+	reader, err := newSSEReader(ctx, connect)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	assembler := newCompletionAssembler()
+	for {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var chunk sseChunk
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			return nil, fmt.Errorf("解析 SSE 数据失败: %w", err)
+		}
+		delta, err := assembler.add(chunk)
+		if err != nil {
+			return nil, err
+		}
+		if w != nil && delta != "" {
+			if _, err := io.WriteString(w, delta); err != nil {
+				return nil, fmt.Errorf("写入流式输出失败: %w", err)
+			}
+		}
+	}
+	return assembler.completion(string(params.Model))
+}
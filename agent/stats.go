@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyWindowSize 计算滚动平均延迟时保留的最近样本数
+const latencyWindowSize = 20
+
+// ErrNoTokensPerSecond 模型还没有可用于计算生成速度的成功调用记录
+var ErrNoTokensPerSecond = errors.New("模型没有生成速度记录")
+
+// latencyStats 按提供商/模型记录最近成功调用的延迟和生成速度，以及每个提供商和模型最近调用的成败，由 Engine 及其所有副本共享
+type latencyStats struct {
+	mu          sync.Mutex
+	samples     map[string][]time.Duration // 键为 provider + "/" + model
+	tps         map[string][]float64       // 键为 provider + "/" + model，每秒生成的 completion token 数
+	outcomes    map[string][]bool          // 键为提供商名称，最近调用是否成功
+	modelCalls  map[string][]bool          // 键为 provider + "/" + model，最近调用是否成功
+	lastSuccess map[string]time.Time       // 键为提供商名称，最近一次成功调用的时间
+}
+
+// newLatencyStats 创建延迟统计
+func newLatencyStats() *latencyStats {
+	return &latencyStats{
+		samples:     make(map[string][]time.Duration),
+		tps:         make(map[string][]float64),
+		outcomes:    make(map[string][]bool),
+		modelCalls:  make(map[string][]bool),
+		lastSuccess: make(map[string]time.Time),
+	}
+}
+
+// record 记录一次成功调用的延迟和生成的 completion token 数，超过窗口大小时丢弃最旧的样本
+// completionTokens 小于等于 0（提供商未返回用量）时不记录生成速度
+func (s *latencyStats) record(provider, model string, d time.Duration, completionTokens int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := provider + "/" + model
+	s.samples[key] = appendWindow(s.samples[key], d)
+	if completionTokens > 0 && d > 0 {
+		s.tps[key] = appendWindow(s.tps[key], float64(completionTokens)/d.Seconds())
+	}
+	s.outcomes[provider] = appendWindow(s.outcomes[provider], true)
+	s.modelCalls[key] = appendWindow(s.modelCalls[key], true)
+	s.lastSuccess[provider] = time.Now()
+}
+
+// recordFailure 记录提供商和模型的一次失败调用
+func (s *latencyStats) recordFailure(provider, model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outcomes[provider] = appendWindow(s.outcomes[provider], false)
+	key := provider + "/" + model
+	s.modelCalls[key] = appendWindow(s.modelCalls[key], false)
+}
+
+// successRate 返回模型最近调用的成功率（0~1），没有记录时 ok 为 false
+func (s *latencyStats) successRate(provider, model string) (rate float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.modelCalls[provider+"/"+model]
+	if len(calls) == 0 {
+		return 0, false
+	}
+	successes := 0
+	for _, ok := range calls {
+		if ok {
+			successes++
+		}
+	}
+	return float64(successes) / float64(len(calls)), true
+}
+
+// providerHealth 返回提供商所有模型的平均延迟、最近调用的错误率和最近一次成功调用的时间，没有记录时均为零值
+func (s *latencyStats) providerHealth(provider string) (latency time.Duration, errorRate float64, lastSuccess time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		total time.Duration
+		count int
+	)
+	for key, samples := range s.samples {
+		if p, _, _ := strings.Cut(key, "/"); p != provider {
+			continue
+		}
+		for _, d := range samples {
+			total += d
+		}
+		count += len(samples)
+	}
+	if count > 0 {
+		latency = total / time.Duration(count)
+	}
+	if outcomes := s.outcomes[provider]; len(outcomes) > 0 {
+		failures := 0
+		for _, ok := range outcomes {
+			if !ok {
+				failures++
+			}
+		}
+		errorRate = float64(failures) / float64(len(outcomes))
+	}
+	return latency, errorRate, s.lastSuccess[provider]
+}
+
+// appendWindow 追加样本，超过 latencyWindowSize 时丢弃最旧的样本
+func appendWindow[T any](samples []T, v T) []T {
+	samples = append(samples, v)
+	if len(samples) > latencyWindowSize {
+		samples = samples[len(samples)-latencyWindowSize:]
+	}
+	return samples
+}
+
+// averageTPS 返回提供商下模型的滚动平均生成速度，没有样本时 ok 为 false
+func (s *latencyStats) averageTPS(provider, model string) (avg float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return mean(s.tps[provider+"/"+model])
+}
+
+// modelAverageTPS 返回所有提供商的同名模型的平均生成速度，没有样本时 ok 为 false
+func (s *latencyStats) modelAverageTPS(model string) (avg float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var samples []float64
+	for key, v := range s.tps {
+		// 提供商名称不含 /，模型ID可能含 /（如 deepseek/deepseek-chat）
+		if _, m, _ := strings.Cut(key, "/"); m == model {
+			samples = append(samples, v...)
+		}
+	}
+	return mean(samples)
+}
+
+// mean 返回样本的平均值，没有样本时 ok 为 false
+func mean(samples []float64) (avg float64, ok bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	total := 0.0
+	for _, v := range samples {
+		total += v
+	}
+	return total / float64(len(samples)), true
+}
+
+// average 返回滚动平均延迟，没有样本时 ok 为 false
+func (s *latencyStats) average(provider, model string) (avg time.Duration, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.samples[provider+"/"+model]
+	if len(samples) == 0 {
+		return 0, false
+	}
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	return total / time.Duration(len(samples)), true
+}
+
+// GetAverageLatency 获取模型最近成功调用的滚动平均延迟（最多统计最近 20 次）
+// 参数:
+//   - provider: 提供商名称
+//   - model: 模型ID
+// 返回:
+//   - time.Duration: 平均延迟
+//   - bool: 是否有延迟记录
+func (engine *Engine) GetAverageLatency(provider, model string) (time.Duration, bool) {
+	if engine.stats == nil {
+		return 0, false
+	}
+	return engine.stats.average(provider, model)
+}
+
+// GetTokensPerSecond 获取模型最近成功调用的滚动平均生成速度（最多统计最近 20 次，包括所有提供商的同名模型）
+// 生成速度为 completion token 数除以调用耗时，耗时包含首 token 前的等待时间
+// 参数:
+//   - modelId: 模型ID
+// 返回:
+//   - float64: 每秒生成的 completion token 数
+//   - error: 没有记录时返回 ErrNoTokensPerSecond
+func (engine *Engine) GetTokensPerSecond(modelId string) (float64, error) {
+	if engine.stats != nil {
+		if tps, ok := engine.stats.modelAverageTPS(modelId); ok {
+			return tps, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrNoTokensPerSecond, modelId)
+}
+
+// throughputWindowSize 计算吞吐量时保留的最近查询时间戳数量
+const throughputWindowSize = 4096
+
+// throughputCounter 用环形缓冲区记录最近查询的开始时间，由 Engine 及其所有副本共享
+type throughputCounter struct {
+	mu    sync.Mutex
+	times [throughputWindowSize]time.Time
+	next  int // 下一次写入的位置
+	count int // 已记录的时间戳数量，最多为 throughputWindowSize
+}
+
+// newThroughputCounter 创建吞吐量统计
+func newThroughputCounter() *throughputCounter {
+	return &throughputCounter{}
+}
+
+// record 记录一次查询，缓冲区已满时覆盖最旧的时间戳
+func (c *throughputCounter) record(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.times[c.next] = t
+	c.next = (c.next + 1) % throughputWindowSize
+	c.count = min(c.count+1, throughputWindowSize)
+}
+
+// countSince 统计 since 之后（含）的查询数，从最新的时间戳向前查找
+func (c *throughputCounter) countSince(since time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for i := 1; i <= c.count; i++ {
+		t := c.times[(c.next-i+throughputWindowSize)%throughputWindowSize]
+		if t.Before(since) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// GetQueryThroughput 获取最近一段时间内的查询吞吐量（所有副本的 query 事件，包括失败的查询）
+// 最多统计最近 4096 次查询，窗口内查询数超过该数量时结果偏低
+// 参数:
+//   - window: 统计窗口，如 time.Minute
+// 返回:
+//   - float64: 每秒查询数，window 小于等于 0 时为 0
+func (engine *Engine) GetQueryThroughput(window time.Duration) float64 {
+	if engine.throughput == nil || window <= 0 {
+		return 0
+	}
+	n := engine.throughput.countSince(time.Now().Add(-window))
+	return float64(n) / window.Seconds()
+}
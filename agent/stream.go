@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// StreamingEventHandler 定义支持流式输出的事件处理接口
+// 处理器在生成回复的过程中将内容分片依次写入 w，处理结束后返回完整结果；
+// 经中间件包装后通过 DispatchStream 调用时，请求的 Stream 为 true，w 通过上下文传递给 Handle/HandleRequest，见 StreamWriterFromContext
+type StreamingEventHandler interface {
+	HandleStream(ctx context.Context, engine *Engine, req *QueryRequest, event string, w io.Writer) (rsp any, err error)
+}
+
+// StreamQuery 发送查询，并在模型生成回复的过程中将内容分片依次写入 w
+// 开始输出后调用失败时不再重试或轮换模型；上下文取消时关闭连接并返回错误，已写入的内容保留
+// 参数:
+//   - ctx: 上下文
+//   - query: 查询内容
+//   - w: 回复内容的写入目标，如 os.Stdout
+// 返回:
+//   - *QueryResult: 完整的查询结果
+//   - error: 错误信息
+func (engine *Engine) StreamQuery(ctx context.Context, query string, w io.Writer) (*QueryResult, error) {
+	return engine.StreamRequest(ctx, &QueryRequest{Query: query}, w)
+}
+
+// StreamRequest 发送结构化查询请求，并将回复内容分片依次写入 w，见 StreamQuery
+// 参数:
+//   - ctx: 上下文
+//   - req: 查询请求，不支持 N > 1
+//   - w: 回复内容的写入目标
+// 返回:
+//   - *QueryResult: 完整的查询结果
+//   - error: 错误信息
+func (engine *Engine) StreamRequest(ctx context.Context, req *QueryRequest, w io.Writer) (*QueryResult, error) {
+	rsp, _, err := engine.DispatchStream(ctx, req, "query", w)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := rsp.(*QueryResult)
+	if !ok {
+		return nil, fmt.Errorf("query 事件返回了非预期的结果类型 %T", rsp)
+	}
+	return result, nil
+}
+
+// DispatchStream 使用结构化请求分发流式处理
+// 事件的处理器未实现 StreamingEventHandler 时返回错误；添加了中间件时经中间件调用处理器，写入目标通过上下文传递
+// 参数:
+//   - ctx: 上下文
+//   - req: 结构化请求
+//   - event: 事件类型
+//   - w: 回复内容的写入目标
+// 返回:
+//   - rsp: 响应数据
+//   - match: 是否匹配到处理器
+//   - err: 错误信息
+func (engine *Engine) DispatchStream(ctx context.Context, req *QueryRequest, event string, w io.Writer) (rsp any, match bool, err error) {
+	handler, ok := lookupEventHandler(event)
+	if !ok {
+		return nil, false, nil
+	}
+	if _, ok := handler.(StreamingEventHandler); !ok {
+		return nil, true, fmt.Errorf("事件 %s 不支持流式输出", event)
+	}
+	streamReq := *req
+	streamReq.Stream = true
+	return engine.dispatch(ctx, event, func(ctx context.Context, handler EventHandler) (any, error) {
+		if streaming, ok := handler.(StreamingEventHandler); ok {
+			return streaming.HandleStream(ctx, engine, &streamReq, event, w)
+		}
+		return AdaptEventHandler(handler).HandleRequest(context.WithValue(ctx, streamWriterContextKey, w), engine, &streamReq, event)
+	})
+}
+
+// StreamWriterFromContext 获取 DispatchStream 传递给处理器的写入目标
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - io.Writer: 写入目标，不是流式请求时为 nil
+func StreamWriterFromContext(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(streamWriterContextKey).(io.Writer)
+	return w
+}
+
+// HandleStream 以流式输出处理结构化查询请求，回复内容分片依次写入 w
+// 参数:
+//   - ctx: 上下文
+//   - engine: Engine 实例
+//   - req: 查询请求
+//   - event: 事件类型
+//   - w: 回复内容的写入目标
+// 返回:
+//   - rsp: *QueryResult 完整的查询结果
+//   - err: 错误信息
+func (h *QueryHandler) HandleStream(ctx context.Context, engine *Engine, req *QueryRequest, event string, w io.Writer) (rsp any, err error) {
+	streamReq := *req
+	streamReq.Stream = true
+	return h.HandleRequest(context.WithValue(ctx, streamWriterContextKey, w), engine, &streamReq, event)
+}
+
+// streamChatCompletion 以流式请求调用对话补全接口，将第一个回复的内容分片依次写入 w，并将分片组装为完整的 ChatCompletion
+func (engine *Engine) streamChatCompletion(ctx context.Context, client openai.Client, params openai.ChatCompletionNewParams, w io.Writer) (*openai.ChatCompletion, error) {
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+	stream := client.Chat.Completions.NewStreaming(ctx, params, engine.requestOptions(ctx)...)
+	defer stream.Close()
+
+	assembler := newCompletionAssembler()
+	for stream.Next() {
+		// 按原始 JSON 解析，保留 reasoning_content 等扩展字段
+		var chunk sseChunk
+		if err := json.Unmarshal([]byte(stream.Current().RawJSON()), &chunk); err != nil {
+			return nil, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		delta, err := assembler.add(chunk)
+		if err != nil {
+			return nil, err
+		}
+		if delta != "" {
+			if _, err := io.WriteString(w, delta); err != nil {
+				return nil, fmt.Errorf("写入流式输出失败: %w", err)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return assembler.completion(string(params.Model))
+}
+
+// streamWriter 将回复分片写入目标，分片末尾不完整的 UTF-8 字符留到下一个分片一起写入，避免输出半个字符
+type streamWriter struct {
+	w       io.Writer
+	pending []byte // 尚未写入的不完整字符
+	written int64  // 已写入的字节数
+}
+
+// newStreamWriter 创建流式输出的写入器
+func newStreamWriter(w io.Writer) *streamWriter {
+	return &streamWriter{w: w}
+}
+
+// Write 写入分片中完整的字符，实现 io.Writer 接口
+func (s *streamWriter) Write(p []byte) (int, error) {
+	data := append(s.pending, p...)
+	cut := completeUTF8Prefix(data)
+	s.pending = slices.Clone(data[cut:])
+	if cut == 0 {
+		return len(p), nil
+	}
+	n, err := s.w.Write(data[:cut])
+	s.written += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush 写入剩余的字节，回复结束时调用
+func (s *streamWriter) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	n, err := s.w.Write(s.pending)
+	s.written += int64(n)
+	s.pending = nil
+	return err
+}
+
+// started 是否已经开始输出，开始输出后调用失败不能再重试
+func (s *streamWriter) started() bool {
+	return s.written > 0 || len(s.pending) > 0
+}
+
+// completeUTF8Prefix 返回 data 中以完整字符结尾的最长前缀的长度
+func completeUTF8Prefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
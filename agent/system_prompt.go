@@ -0,0 +1,40 @@
+package agent
+
+import "github.com/openai/openai-go/v3"
+
+// WithSystemPrompt 返回使用指定系统提示的 Engine 副本，覆盖提供商配置的 system_prompt
+// 参数:
+//   - prompt: 系统提示，为空时使用提供商配置的 system_prompt
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithSystemPrompt(prompt string) *Engine {
+	clone := engine.Clone()
+	clone.systemPrompt = prompt
+	return clone
+}
+
+// GetSystemPrompt 获取查询使用的系统提示：WithSystemPrompt 设置的系统提示优先，否则为当前提供商配置的 system_prompt
+// 返回:
+//   - string: 系统提示，为空时查询不发送系统提示
+func (engine *Engine) GetSystemPrompt() string {
+	if engine.systemPrompt != "" {
+		return engine.systemPrompt
+	}
+	if engine.config == nil {
+		return ""
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return ""
+	}
+	return provider.SystemPrompt
+}
+
+// withSystemMessage 在消息列表最前面加上系统提示，得到 [system, ...history, user]；系统提示为空时原样返回
+func (engine *Engine) withSystemMessage(messages []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	prompt := engine.GetSystemPrompt()
+	if prompt == "" {
+		return messages
+	}
+	return append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(prompt)}, messages...)
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// sentMessages 返回最后一次请求中消息的角色和内容
+func sentMessages(t *testing.T, server *chatServer) (roles, contents []string) {
+	t.Helper()
+	messages, _ := server.lastBody(t)["messages"].([]any)
+	for _, m := range messages {
+		message, _ := m.(map[string]any)
+		role, _ := message["role"].(string)
+		content, _ := message["content"].(string)
+		roles = append(roles, role)
+		contents = append(contents, content)
+	}
+	return roles, contents
+}
+
+// querySystemPrompt 以提供商配置的系统提示 configured 和 WithSystemPrompt 的 override 发送带历史的查询，返回发送的消息
+func querySystemPrompt(t *testing.T, configured, override string) (roles, contents []string) {
+	t.Helper()
+	server := newChatServer(t, func(int) (int, string) { return http.StatusOK, "ok" })
+	provider := testProvider("mock", server.URL, "m1")
+	provider.SystemPrompt = configured
+	engine := newTestEngine(t, newTestConfig(t, provider)).WithSystemPrompt(override)
+
+	req := &QueryRequest{
+		Query: "第二个问题",
+		History: []ChatMessage{
+			{Role: RoleUser, Content: "第一个问题"},
+			{Role: RoleAssistant, Content: "第一个回答"},
+		},
+	}
+	if _, err := engine.QueryRequest(t.Context(), req); err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	return sentMessages(t, server)
+}
+
+func TestSystemPromptMessageOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		override   string
+		want       string
+	}{
+		{name: "配置的系统提示", configured: "你是翻译助手", want: "你是翻译助手"},
+		{name: "覆盖配置的系统提示", configured: "你是翻译助手", override: "你是代码助手", want: "你是代码助手"},
+		{name: "只设置覆盖的系统提示", override: "你是代码助手", want: "你是代码助手"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles, contents := querySystemPrompt(t, tt.configured, tt.override)
+			wantRoles := []string{RoleSystem, RoleUser, RoleAssistant, RoleUser}
+			if !slices.Equal(roles, wantRoles) {
+				t.Fatalf("消息角色为 %v，期望 %v", roles, wantRoles)
+			}
+			wantContents := []string{tt.want, "第一个问题", "第一个回答", "第二个问题"}
+			if !slices.Equal(contents, wantContents) {
+				t.Errorf("消息内容为 %q，期望 %q", contents, wantContents)
+			}
+		})
+	}
+}
+
+func TestEmptySystemPromptAddsNoMessage(t *testing.T) {
+	roles, _ := querySystemPrompt(t, "", "")
+	wantRoles := []string{RoleUser, RoleAssistant, RoleUser}
+	if !slices.Equal(roles, wantRoles) {
+		t.Errorf("消息角色为 %v，期望 %v", roles, wantRoles)
+	}
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context 请求头
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// traceContext 上游服务传入的 W3C Trace Context
+type traceContext struct {
+	parent string
+	state  string
+}
+
+// WithTraceContext 返回携带 W3C Trace Context 的上下文，之后通过该上下文调用模型接口时会转发这两个请求头
+// 参数:
+//   - ctx: 父上下文
+//   - traceparent: traceparent 请求头的值，格式无效时忽略（同时忽略 tracestate）
+//   - tracestate: tracestate 请求头的值，可为空
+// 返回:
+//   - context.Context: 新的上下文
+func WithTraceContext(ctx context.Context, traceparent, tracestate string) context.Context {
+	traceparent = strings.TrimSpace(traceparent)
+	if !validTraceParent(traceparent) {
+		return ctx
+	}
+	return context.WithValue(ctx, traceContextKey, traceContext{parent: traceparent, state: strings.TrimSpace(tracestate)})
+}
+
+// ExtractTraceContext 从 HTTP 请求头中读取 traceparent/tracestate，返回携带它们的请求上下文
+// 在 HTTP 服务的处理函数中使用，将返回的上下文传给 DispatchAndHandle 等方法
+// 参数:
+//   - r: 上游服务的 HTTP 请求
+// 返回:
+//   - context.Context: r.Context() 派生的上下文
+func ExtractTraceContext(r *http.Request) context.Context {
+	return WithTraceContext(r.Context(), r.Header.Get(TraceParentHeader), r.Header.Get(TraceStateHeader))
+}
+
+// InjectTraceContext 将上下文中的 traceparent/tracestate 写入请求头，上下文中没有时不修改
+// 参数:
+//   - ctx: 上下文
+//   - headers: 目标请求头
+func InjectTraceContext(ctx context.Context, headers http.Header) {
+	if ctx == nil {
+		return
+	}
+	tc, ok := ctx.Value(traceContextKey).(traceContext)
+	if !ok {
+		return
+	}
+	headers.Set(TraceParentHeader, tc.parent)
+	if tc.state != "" {
+		headers.Set(TraceStateHeader, tc.state)
+	}
+}
+
+// traceTransport 在发出的请求中注入请求上下文携带的 Trace Context
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Value(traceContextKey).(traceContext); ok {
+		// RoundTripper 不能修改原请求
+		req = req.Clone(req.Context())
+		InjectTraceContext(req.Context(), req.Header)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// traceHTTPClient 调用模型接口使用的 HTTP 客户端
+var traceHTTPClient = &http.Client{Transport: traceTransport{base: http.DefaultTransport}}
+
+// validTraceParent 检查 traceparent 是否符合 W3C 格式: version-trace_id-parent_id-flags
+// trace_id 和 parent_id 不能全为 0，version 不能为 ff
+func validTraceParent(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return false
+	}
+	// version 00 只有 4 段，更高版本允许在末尾追加字段
+	if parts[0] == "00" && len(parts) != 4 {
+		return false
+	}
+	for i, n := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != n || !isLowerHex(parts[i]) {
+			return false
+		}
+	}
+	return parts[0] != "ff" && strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
+
+// isLowerHex 检查字符串是否只包含小写十六进制字符
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
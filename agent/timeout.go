@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// SetGlobalTimeout 设置每次分发请求（DispatchAndHandle、DispatchRequest 等）的默认超时时间
+// 调用上下文已设置截止时间时以调用上下文为准
+// 参数:
+//   - d: 超时时间，小于等于 0 时不设置默认超时
+func (engine *Engine) SetGlobalTimeout(d time.Duration) {
+	engine.globalTimeout = d
+}
+
+// GetGlobalTimeout 获取每次分发请求的默认超时时间
+// 返回:
+//   - time.Duration: 超时时间，0 表示未设置
+func (engine *Engine) GetGlobalTimeout() time.Duration {
+	return max(engine.globalTimeout, 0)
+}
+
+// withGlobalTimeout 调用上下文没有截止时间时为其添加默认超时
+func (engine *Engine) withGlobalTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if engine.globalTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, engine.globalTimeout)
+}
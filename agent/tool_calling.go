@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"agent_engine/model"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+	_ "modernc.org/sqlite"
+)
+
+// DefaultMaxToolRounds ToolCallHandler 未设置 MaxToolRounds 时最多执行工具调用的轮数
+const DefaultMaxToolRounds = 5
+
+// ToolStatusDisabled t_tool 表中已停用工具的状态，LoadTableTools 不加载这些工具
+const ToolStatusDisabled = "disabled"
+
+// ToolCallFunc 执行模型请求的工具调用
+// 参数:
+//   - name: 工具名称
+//   - args: 模型生成的 JSON 参数
+// 返回:
+//   - string: 工具输出，作为工具消息发送给模型
+//   - error: 执行失败时返回错误，错误信息会作为工具输出发送给模型
+type ToolCallFunc func(name, args string) (string, error)
+
+// ToolCallHandler 支持工具调用的查询处理器
+// 向模型声明 Tools，模型返回工具调用时通过 Executor 执行，将结果作为工具消息追加后再次调用模型，
+// 直到模型返回文本回复；执行 MaxToolRounds 轮后要求模型不再调用工具直接回复。
+// 模型调用失败重试（包括切换模型或提供商）时从已完成的轮次继续，已执行的工具不会重复执行。
+// 其余行为（路由、模型轮换、钩子等）与 QueryHandler 相同，但不使用查询结果缓存，也不支持流式输出；
+// 配置了 sse_mode 的提供商返回的工具调用会被忽略
+type ToolCallHandler struct {
+	Tools         []openai.ChatCompletionToolUnionParam // 声明的工具，见 NewFunctionTool、TableToolParams
+	Executor      ToolCallFunc                          // 执行工具调用，为空时所有工具调用都返回错误
+	MaxToolRounds int                                   // 最多执行工具调用的轮数，小于等于 0 时使用 DefaultMaxToolRounds
+}
+
+// ToolCallRecord 查询过程中执行的一次工具调用
+type ToolCallRecord struct {
+	Name      string `json:"name"`            // 工具名称
+	Arguments string `json:"arguments"`       // 模型生成的 JSON 参数
+	Output    string `json:"output"`          // 工具输出
+	Error     string `json:"error,omitempty"` // 执行失败时的错误信息
+}
+
+// Handle 解析字符串参数后交给 HandleRequest 处理
+func (h *ToolCallHandler) Handle(ctx context.Context, engine *Engine, params string, event string) (rsp any, err error) {
+	req, err := ParseQueryRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	if orig := requestFromContext(ctx); orig != nil && req.RetryPolicy == nil {
+		req.RetryPolicy = orig.RetryPolicy
+	}
+	return h.HandleRequest(ctx, engine, req, event)
+}
+
+// HandleRequest 处理结构化查询请求，模型返回工具调用时执行工具后继续调用模型
+// 参数:
+//   - ctx: 上下文
+//   - engine: Engine 实例
+//   - req: 查询请求，不能为流式请求
+//   - event: 事件类型
+// 返回:
+//   - rsp: *QueryResult 查询结果，ToolCalls 记录执行过的工具调用
+//   - err: 错误信息
+func (h *ToolCallHandler) HandleRequest(ctx context.Context, engine *Engine, req *QueryRequest, event string) (rsp any, err error) {
+	if req.Stream {
+		return nil, fmt.Errorf("工具调用不支持流式输出")
+	}
+	if len(h.Tools) > 0 {
+		ctx = context.WithValue(ctx, toolCallContextKey, h)
+	}
+	return (&QueryHandler{}).HandleRequest(ctx, engine, req, event)
+}
+
+// toolCallHandlerFromContext 获取上下文中声明了工具的 ToolCallHandler
+func toolCallHandlerFromContext(ctx context.Context) *ToolCallHandler {
+	h, _ := ctx.Value(toolCallContextKey).(*ToolCallHandler)
+	return h
+}
+
+// execute 执行一次工具调用，返回发送给模型的工具输出和调用记录
+func (h *ToolCallHandler) execute(name, args string) (string, ToolCallRecord) {
+	record := ToolCallRecord{Name: name, Arguments: args}
+	if h.Executor == nil {
+		record.Error = fmt.Sprintf("工具 %s 没有执行器", name)
+		return "错误: " + record.Error, record
+	}
+	output, err := h.Executor(name, args)
+	record.Output = output
+	if err != nil {
+		record.Error = err.Error()
+		if output == "" {
+			return "错误: " + record.Error, record
+		}
+		return output + "\n错误: " + record.Error, record
+	}
+	return output, record
+}
+
+// toolLoop 一次查询中工具调用循环的进度，跨模型调用的重试保留
+// 重试时从已完成的轮次继续，已执行的工具不会重复执行，其调用记录和用量也不会丢失
+type toolLoop struct {
+	messages []openai.ChatCompletionMessageParamUnion // 已完成轮次的模型消息和工具消息，追加在查询消息之后发送
+	records  []ToolCallRecord                         // 已执行的工具调用
+	usage    openai.CompletionUsage                   // 已完成轮次的用量合计
+	rounds   int                                      // 已完成的轮数
+}
+
+// toolChatCompletion 调用对话补全接口，上下文中有 ToolCallHandler 时声明工具并循环执行模型请求的工具调用
+// 循环的进度记录在 loop 中，返回的用量为所有轮次的合计
+func (engine *Engine) toolChatCompletion(ctx context.Context, client openai.Client, params openai.ChatCompletionNewParams, stream io.Writer, loop *toolLoop) (*openai.ChatCompletion, error) {
+	h := toolCallHandlerFromContext(ctx)
+	if h == nil {
+		return engine.chatCompletion(ctx, client, params, stream)
+	}
+	maxRounds := h.MaxToolRounds
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxToolRounds
+	}
+
+	params.Tools = h.Tools
+	params.Messages = append(slices.Clip(params.Messages), loop.messages...)
+	for {
+		// 达到最大轮数后要求模型直接回复
+		if loop.rounds == maxRounds {
+			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("none")}
+		}
+		completion, err := engine.chatCompletion(ctx, client, params, stream)
+		if err != nil {
+			return nil, err
+		}
+		if loop.rounds == maxRounds || len(completion.Choices) == 0 || len(completion.Choices[0].Message.ToolCalls) == 0 {
+			completion.Usage.PromptTokens += loop.usage.PromptTokens
+			completion.Usage.CompletionTokens += loop.usage.CompletionTokens
+			completion.Usage.TotalTokens += loop.usage.TotalTokens
+			return completion, nil
+		}
+		loop.usage.PromptTokens += completion.Usage.PromptTokens
+		loop.usage.CompletionTokens += completion.Usage.CompletionTokens
+		loop.usage.TotalTokens += completion.Usage.TotalTokens
+
+		message := completion.Choices[0].Message
+		round := []openai.ChatCompletionMessageParamUnion{message.ToParam()}
+		for _, call := range message.ToolCalls {
+			engine.debug(ctx, "执行工具调用", "round", loop.rounds+1, "tool", call.Function.Name, "arguments", call.Function.Arguments)
+			output, record := h.execute(call.Function.Name, call.Function.Arguments)
+			if record.Error != "" {
+				LoggerFromContext(ctx).Warn("工具调用失败", "tool", call.Function.Name, "error", record.Error)
+			}
+			loop.records = append(loop.records, record)
+			round = append(round, openai.ToolMessage(output, call.ID))
+		}
+		loop.messages = append(loop.messages, round...)
+		params.Messages = append(params.Messages, round...)
+		loop.rounds++
+	}
+}
+
+// NewFunctionTool 创建向模型声明的函数工具
+// 参数:
+//   - name: 工具名称
+//   - description: 工具说明
+//   - parameters: 参数的 JSON Schema，为空时不接受参数
+// 返回:
+//   - openai.ChatCompletionToolUnionParam: 工具声明
+//   - error: parameters 不是 JSON 对象时返回错误
+func NewFunctionTool(name, description string, parameters json.RawMessage) (openai.ChatCompletionToolUnionParam, error) {
+	schema := shared.FunctionParameters{"type": "object", "properties": map[string]any{}}
+	if len(parameters) > 0 {
+		schema = nil
+		if err := json.Unmarshal(parameters, &schema); err != nil || schema == nil {
+			return openai.ChatCompletionToolUnionParam{}, fmt.Errorf("工具 %s 的参数 schema 不是 JSON 对象", name)
+		}
+	}
+	function := shared.FunctionDefinitionParam{Name: name, Parameters: schema}
+	if description != "" {
+		function.Description = openai.String(description)
+	}
+	return openai.ChatCompletionFunctionTool(function), nil
+}
+
+// LoadTableTools 从 SQLite 数据库的 t_tool 表读取未停用的工具
+// 参数:
+//   - ctx: 上下文
+//   - path: SQLite 数据库文件路径
+// 返回:
+//   - []model.TableTool: 工具记录，按 id 排序
+//   - error: 打开数据库或查询失败时返回错误
+func LoadTableTools(ctx context.Context, path string) ([]model.TableTool, error) {
+	// 以只读的 SQLite URI 打开：路径中的 ?、#、% 等字符需要转义，省略 // 以免相对路径被当作主机名
+	dsn := (&url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro", OmitHost: true}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开工具数据库失败: %w", err)
+	}
+	defer db.Close()
+
+	query := fmt.Sprintf("SELECT id, tool_id, COALESCE(tool_name, ''), COALESCE(description, ''), COALESCE(document, ''), COALESCE(example, ''), COALESCE(status, '') FROM %s ORDER BY id", (&model.TableTool{}).TableName())
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("查询工具表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tools []model.TableTool
+	for rows.Next() {
+		var t model.TableTool
+		if err := rows.Scan(&t.ID, &t.ToolID, &t.ToolName, &t.Description, &t.Document, &t.Example, &t.Status); err != nil {
+			return nil, fmt.Errorf("读取工具记录失败: %w", err)
+		}
+		if strings.EqualFold(t.Status, ToolStatusDisabled) {
+			continue
+		}
+		tools = append(tools, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取工具记录失败: %w", err)
+	}
+	return tools, nil
+}
+
+// TableToolParams 将 t_tool 表的工具记录转换为工具声明
+// 工具名称为 tool_id；document 为 JSON 对象时作为参数的 JSON Schema，否则与 tool_name、description、example 一起写入工具说明
+// 参数:
+//   - tools: 工具记录
+// 返回:
+//   - []openai.ChatCompletionToolUnionParam: 工具声明
+func TableToolParams(tools []model.TableTool) []openai.ChatCompletionToolUnionParam {
+	params := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		var (
+			parts  []string
+			schema json.RawMessage
+		)
+		for _, part := range []string{t.ToolName, t.Description} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		if doc := strings.TrimSpace(t.Document); strings.HasPrefix(doc, "{") && json.Valid([]byte(doc)) {
+			schema = json.RawMessage(doc)
+		} else if doc != "" {
+			parts = append(parts, doc)
+		}
+		if t.Example != "" {
+			parts = append(parts, "示例: "+t.Example)
+		}
+		// schema 已校验为 JSON 对象，不会失败
+		tool, _ := NewFunctionTool(t.ToolID, strings.Join(parts, "\n"), schema)
+		params = append(params, tool)
+	}
+	return params
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestToolCallNotReplayedOnRetry(t *testing.T) {
+	// 第一次请求返回工具调用，第二次返回 503，第三次返回文本回复
+	var (
+		mu     sync.Mutex
+		bodies [][]byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		call := len(bodies)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		message := map[string]any{"role": "assistant", "content": "北京今天晴"}
+		switch call {
+		case 1:
+			message = map[string]any{"role": "assistant", "content": "", "tool_calls": []map[string]any{{
+				"id":       "call_1",
+				"type":     "function",
+				"function": map[string]any{"name": "weather", "arguments": `{"city":"北京"}`},
+			}}}
+		case 2:
+			w.Header().Set("x-should-retry", "false")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": "service unavailable", "type": "server_error"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": 0,
+			"model":   "m1",
+			"choices": []map[string]any{{"index": 0, "message": message, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5},
+		})
+	}))
+	t.Cleanup(server.Close)
+	engine := newTestEngine(t, newTestConfig(t, testProvider("mock", server.URL, "m1", "m2")))
+
+	executed := 0
+	tool, err := NewFunctionTool("weather", "查询天气", nil)
+	if err != nil {
+		t.Fatalf("创建工具失败: %v", err)
+	}
+	handler := &ToolCallHandler{
+		Tools: []openai.ChatCompletionToolUnionParam{tool},
+		Executor: func(name, args string) (string, error) {
+			executed++
+			return "晴", nil
+		},
+	}
+	rsp, err := handler.HandleRequest(t.Context(), engine, &QueryRequest{Query: "北京天气怎么样？"}, "query")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	result := rsp.(*QueryResult)
+
+	if executed != 1 {
+		t.Errorf("工具执行了 %d 次，期望 1 次", executed)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("服务器收到 %d 次请求，期望 3 次", len(bodies))
+	}
+	if result.Reply != "北京今天晴" {
+		t.Errorf("Reply = %q，期望 %q", result.Reply, "北京今天晴")
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Name != "weather" || result.ToolCalls[0].Output != "晴" {
+		t.Errorf("ToolCalls = %+v，期望一次 weather 调用", result.ToolCalls)
+	}
+	// 用量包括工具调用轮次和最后的回复
+	if result.TotalTokens != 10 {
+		t.Errorf("TotalTokens = %d，期望 10", result.TotalTokens)
+	}
+
+	// 重试的请求带上了之前的工具调用和工具输出
+	var last struct {
+		Messages []struct {
+			Role       string `json:"role"`
+			ToolCallID string `json:"tool_call_id"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(bodies[2], &last); err != nil {
+		t.Fatalf("解析请求体失败: %v", err)
+	}
+	var roles []string
+	for _, m := range last.Messages {
+		roles = append(roles, m.Role)
+	}
+	if len(last.Messages) != 3 || roles[1] != "assistant" || roles[2] != "tool" || last.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("重试请求的消息角色为 %v，期望 [user assistant tool] 且工具消息对应 call_1", roles)
+	}
+}
+
+func TestLoadTableToolsSpecialPath(t *testing.T) {
+	// 文件名中的 ?、#、% 和空格不能被当作 URI 的参数、片段或转义
+	path := filepath.Join(t.TempDir(), "tools ?#%.db")
+	db, err := sql.Open("sqlite", (&url.URL{Scheme: "file", Path: path, OmitHost: true}).String())
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE t_tool (id INTEGER PRIMARY KEY, tool_id TEXT NOT NULL, tool_name TEXT, description TEXT, document TEXT, example TEXT, status TEXT);
+		INSERT INTO t_tool (tool_id, tool_name) VALUES ('weather', '天气');
+		INSERT INTO t_tool (tool_id, status) VALUES ('old', 'disabled');`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("创建工具表失败: %v", err)
+	}
+
+	tools, err := LoadTableTools(t.Context(), path)
+	if err != nil {
+		t.Fatalf("读取工具失败: %v", err)
+	}
+	if len(tools) != 1 || tools[0].ToolID != "weather" || tools[0].ToolName != "天气" {
+		t.Errorf("tools = %+v，期望只有 weather", tools)
+	}
+}
@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 内置工具名称，需在配置文件的 tool_whitelist 中启用
+const (
+	ToolShellExec = "shell_exec" // 在工作目录中执行 shell 命令
+	ToolReadFile  = "read_file"  // 读取工作目录内的文件
+	ToolWriteFile = "write_file" // 写入工作目录内的文件
+)
+
+// DefaultToolTimeout 未配置 tool_timeout_s 时工具执行的超时时间
+const DefaultToolTimeout = 30 * time.Second
+
+// ErrToolNotAllowed 工具不在 tool_whitelist 中时返回的错误
+var ErrToolNotAllowed = errors.New("工具未在 tool_whitelist 中启用")
+
+// ToolExecutor 内置本地工具的执行器
+// 只执行 tool_whitelist 中启用的工具；read_file、write_file 只能访问工作目录内的路径，
+// shell_exec 在工作目录中执行但不限制命令本身能访问的路径
+type ToolExecutor struct {
+	whitelist map[string]bool
+	workDir   string        // 工作目录（绝对路径，已解析符号链接）
+	timeout   time.Duration // 默认超时时间，也是 shell_exec 可指定的最大超时时间
+}
+
+// shellExecArgs shell_exec 的参数
+type shellExecArgs struct {
+	Command  string `json:"command" schema:"description=要执行的 shell 命令"`
+	TimeoutS int    `json:"timeout_s,omitempty" schema:"description=超时时间（秒），不超过配置的 tool_timeout_s"`
+}
+
+// readFileArgs read_file 的参数
+type readFileArgs struct {
+	Path string `json:"path" schema:"description=文件路径，相对路径基于工作目录"`
+}
+
+// writeFileArgs write_file 的参数
+type writeFileArgs struct {
+	Path    string `json:"path" schema:"description=文件路径，相对路径基于工作目录"`
+	Content string `json:"content" schema:"description=写入的内容"`
+}
+
+// NewToolExecutor 根据 Engine 的配置（tool_whitelist、tool_work_dir、tool_timeout_s）创建工具执行器
+// 参数:
+//   - engine: Engine 实例
+// 返回:
+//   - *ToolExecutor: 工具执行器指针
+//   - error: 配置未加载、白名单包含未知工具或工作目录无效时返回错误
+func NewToolExecutor(engine *Engine) (*ToolExecutor, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	cfg := engine.config
+
+	whitelist := make(map[string]bool, len(cfg.ToolWhitelist))
+	for _, name := range cfg.ToolWhitelist {
+		switch name {
+		case ToolShellExec, ToolReadFile, ToolWriteFile:
+			whitelist[name] = true
+		default:
+			return nil, fmt.Errorf("tool_whitelist 中的工具 %s 不存在，可选值: %s、%s、%s", name, ToolShellExec, ToolReadFile, ToolWriteFile)
+		}
+	}
+
+	workDir := cfg.ToolWorkDir
+	if workDir == "" {
+		workDir = "."
+	}
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("解析工具工作目录失败: %w", err)
+	}
+	if workDir, err = filepath.EvalSymlinks(workDir); err != nil {
+		return nil, fmt.Errorf("解析工具工作目录失败: %w", err)
+	}
+
+	timeout := DefaultToolTimeout
+	if cfg.ToolTimeoutS > 0 {
+		timeout = time.Duration(cfg.ToolTimeoutS) * time.Second
+	}
+
+	return &ToolExecutor{whitelist: whitelist, workDir: workDir, timeout: timeout}, nil
+}
+
+// Allowed 检查工具是否在白名单中
+func (t *ToolExecutor) Allowed(name string) bool {
+	return t.whitelist[name]
+}
+
+// WorkDir 返回工具的工作目录
+func (t *ToolExecutor) WorkDir() string {
+	return t.workDir
+}
+
+// ToolSchemas 返回白名单中工具的参数 JSON Schema，可用于向模型声明工具
+// 返回:
+//   - map[string]json.RawMessage: 工具名称 -> 参数 schema
+func (t *ToolExecutor) ToolSchemas() map[string]json.RawMessage {
+	fns := map[string]any{
+		ToolShellExec: func(shellExecArgs) {},
+		ToolReadFile:  func(readFileArgs) {},
+		ToolWriteFile: func(writeFileArgs) {},
+	}
+	schemas := make(map[string]json.RawMessage, len(t.whitelist))
+	for name := range t.whitelist {
+		// 参数类型固定，生成不会失败
+		schemas[name], _ = GenerateSchema(fns[name])
+	}
+	return schemas
+}
+
+// Execute 按名称执行工具，参数为模型工具调用中的 JSON 参数
+// 参数:
+//   - ctx: 上下文
+//   - name: 工具名称
+//   - arguments: JSON 格式的参数
+// 返回:
+//   - string: 工具输出，write_file 成功时为 true
+//   - error: 工具未启用（ErrToolNotAllowed）、参数无效或执行失败时返回错误
+func (t *ToolExecutor) Execute(ctx context.Context, name string, arguments string) (string, error) {
+	switch name {
+	case ToolShellExec:
+		var args shellExecArgs
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("解析工具 %s 的参数失败: %w", name, err)
+		}
+		return t.ShellExec(ctx, args.Command, args.TimeoutS)
+	case ToolReadFile:
+		var args readFileArgs
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("解析工具 %s 的参数失败: %w", name, err)
+		}
+		return t.ReadFile(args.Path)
+	case ToolWriteFile:
+		var args writeFileArgs
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("解析工具 %s 的参数失败: %w", name, err)
+		}
+		ok, err := t.WriteFile(args.Path, args.Content)
+		return fmt.Sprint(ok), err
+	default:
+		return "", fmt.Errorf("工具 %s 不存在", name)
+	}
+}
+
+// ShellExec 在工作目录中通过 sh -c 执行命令
+// 参数:
+//   - ctx: 上下文
+//   - command: shell 命令
+//   - timeoutS: 超时时间（秒），小于等于 0 或超过配置的超时时间时使用配置的超时时间
+// 返回:
+//   - string: 标准输出和标准错误的合并内容
+//   - error: 工具未启用、超时或命令以非零状态退出时返回错误（输出仍会返回）
+func (t *ToolExecutor) ShellExec(ctx context.Context, command string, timeoutS int) (string, error) {
+	if !t.whitelist[ToolShellExec] {
+		return "", fmt.Errorf("%w: %s", ErrToolNotAllowed, ToolShellExec)
+	}
+	if strings.TrimSpace(command) == "" {
+		return "", fmt.Errorf("命令不能为空")
+	}
+
+	timeout := t.timeout
+	if timeoutS > 0 {
+		timeout = min(time.Duration(timeoutS)*time.Second, t.timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = t.workDir
+	// 命令启动的子进程可能在超时后仍持有输出管道，最多再等待 1 秒
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), fmt.Errorf("执行命令超时（%s）: %w", timeout, ctx.Err())
+	}
+	if err != nil {
+		return string(output), fmt.Errorf("执行命令失败: %w", err)
+	}
+	return string(output), nil
+}
+
+// ReadFile 读取工作目录内的文件
+// 参数:
+//   - path: 文件路径，相对路径基于工作目录
+// 返回:
+//   - string: 文件内容
+//   - error: 工具未启用、路径超出工作目录或读取失败时返回错误
+func (t *ToolExecutor) ReadFile(path string) (string, error) {
+	if !t.whitelist[ToolReadFile] {
+		return "", fmt.Errorf("%w: %s", ErrToolNotAllowed, ToolReadFile)
+	}
+	resolved, err := t.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// WriteFile 写入工作目录内的文件，目录不存在时自动创建
+// 参数:
+//   - path: 文件路径，相对路径基于工作目录
+//   - content: 写入的内容
+// 返回:
+//   - bool: 是否写入成功
+//   - error: 工具未启用、路径超出工作目录或写入失败时返回错误
+func (t *ToolExecutor) WriteFile(path string, content string) (bool, error) {
+	if !t.whitelist[ToolWriteFile] {
+		return false, fmt.Errorf("%w: %s", ErrToolNotAllowed, ToolWriteFile)
+	}
+	resolved, err := t.resolvePath(path)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
+		return false, fmt.Errorf("创建目录失败: %w", err)
+	}
+	// 创建目录后再次检查，避免通过符号链接目录写到工作目录之外
+	if resolved, err = t.resolvePath(resolved); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(resolved, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("写入文件失败: %w", err)
+	}
+	return true, nil
+}
+
+// resolvePath 将路径解析为工作目录内的绝对路径，已存在的部分会解析符号链接
+func (t *ToolExecutor) resolvePath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("路径不能为空")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.workDir, path)
+	}
+	path = filepath.Clean(path)
+
+	// 解析最长的已存在前缀中的符号链接，不存在的部分原样拼接
+	resolved, rest := path, ""
+	for {
+		real, err := filepath.EvalSymlinks(resolved)
+		if err == nil {
+			resolved = filepath.Join(real, rest)
+			break
+		}
+		parent := filepath.Dir(resolved)
+		if parent == resolved {
+			break
+		}
+		rest = filepath.Join(filepath.Base(resolved), rest)
+		resolved = parent
+	}
+
+	rel, err := filepath.Rel(t.workDir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("路径 %s 超出工具工作目录 %s", path, t.workDir)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// CorrelationIDHeader 发送给模型接口的请求关联ID请求头
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// RequestIDHeader HTTP 服务接收上游请求ID的请求头，见 Engine.WithRequestID
+const RequestIDHeader = "X-Request-ID"
+
+// NewCorrelationID 生成 UUID v4 格式的请求关联ID
+// 返回:
+//   - string: 关联ID
+func NewCorrelationID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand 在受支持的平台上不会失败
+		panic(fmt.Sprintf("生成关联ID失败: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // 版本 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 变体
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// WithCorrelationID 返回携带请求关联ID的上下文
+// DispatchAndHandle 会沿用上下文中已有的关联ID，没有时自动生成
+// 参数:
+//   - ctx: 父上下文
+//   - id: 关联ID
+// 返回:
+//   - context.Context: 新的上下文
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey, id)
+}
+
+// CorrelationIDFromContext 从上下文中获取请求关联ID
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - string: 关联ID，未设置时为空字符串
+func CorrelationIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDContextKey).(string)
+	return id
+}
+
+// WithRequestID 返回使用指定请求ID的 Engine 副本，适用于 HTTP 服务将 X-Request-ID 请求头传递给 Engine
+// 请求ID作为副本基础上下文的关联ID（见 WithCorrelationID），出现在所有日志和发送给模型接口的 X-Correlation-ID 请求头中，
+// 并以 request_id 字段出现在查询结果中；调用上下文中已有关联ID时日志和请求头使用调用上下文的关联ID
+// 参数:
+//   - id: 请求ID，为空时使用 crypto/rand 生成
+// 返回:
+//   - *Engine: Engine 副本指针
+func (engine *Engine) WithRequestID(id string) *Engine {
+	if id == "" {
+		id = NewCorrelationID()
+	}
+	ctx := WithCorrelationID(engine.baseContext(), id)
+	clone := engine.Clone()
+	clone.baseCtx = context.WithValue(ctx, requestIDContextKey, id)
+	return clone
+}
+
+// RequestIDFromContext 从上下文中获取 Engine.WithRequestID 设置的请求ID
+// 参数:
+//   - ctx: 上下文
+// 返回:
+//   - string: 请求ID，未设置时为空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// withCorrelation 确保上下文携带关联ID，并将其作为属性附加到上下文的 logger
+func withCorrelation(ctx context.Context) context.Context {
+	id := CorrelationIDFromContext(ctx)
+	if id == "" {
+		id = NewCorrelationID()
+		ctx = WithCorrelationID(ctx, id)
+	}
+	return ContextWithLogger(ctx, LoggerFromContext(ctx).With("correlation_id", id))
+}
+
+// requestOptions 根据上下文生成调用模型接口时附加的请求选项
+func (engine *Engine) requestOptions(ctx context.Context) []option.RequestOption {
+	var opts []option.RequestOption
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		opts = append(opts, option.WithHeader(CorrelationIDHeader, id))
+	}
+	return opts
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"agent_engine/conf"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// 链路追踪的默认设置
+const (
+	DefaultTelemetryServiceName = "agent_engine" // 未配置 telemetry.service_name 时上报的服务名称
+	tracerName                  = "agent_engine/agent"
+	defaultOTLPTracesPath       = "/v1/traces"
+)
+
+// 链路追踪的 span 属性
+const (
+	AttrProviderName       = attribute.Key("provider.name")        // 提供商名称
+	AttrModelID            = attribute.Key("model.id")             // 模型ID
+	AttrAttemptNumber      = attribute.Key("attempt.number")       // QueryHandler 中第几次尝试（跨提供商累计）
+	AttrResponseTokenCount = attribute.Key("response.token_count") // 成功调用消耗的 token 数
+	AttrEventType          = attribute.Key("event.type")           // 分发的事件类型
+)
+
+// newTracer 按 telemetry 配置创建 Tracer：配置了 otlp_endpoint 时通过 OTLP/HTTP 批量导出，并在 Engine 关闭时刷新；否则返回 no-op Tracer
+func (engine *Engine) newTracer(config conf.TelemetryConfig) (trace.Tracer, error) {
+	if config.OTLPEndpoint == "" {
+		return noop.NewTracerProvider().Tracer(tracerName), nil
+	}
+	endpoint, err := otlpTracesURL(config.OTLPEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("创建 OTLP 导出器失败: %w", err)
+	}
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultTelemetryServiceName
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	engine.OnShutdown(provider.Shutdown)
+	return provider.Tracer(tracerName), nil
+}
+
+// otlpTracesURL 补全 OTLP 接收地址：没有协议时使用 http，没有路径时使用 /v1/traces
+func otlpTracesURL(endpoint string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("telemetry.otlp_endpoint 无效: %s", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultOTLPTracesPath
+	}
+	return u.String(), nil
+}
+
+// startSpan 以当前提供商和模型为属性创建 span
+// 上下文中没有 span 但携带上游的 W3C Trace Context 时，以其为父 span；
+// span 在记录时，之后调用模型接口转发的 traceparent 改为该 span，使模型接口的调用出现在同一链路中
+func (engine *Engine) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := engine.tracer
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		headers := http.Header{}
+		InjectTraceContext(ctx, headers)
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(headers))
+	}
+	attrs = append(attrs, AttrProviderName.String(engine.GetCurrentProviderName()), AttrModelID.String(engine.ModelId))
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	if span.IsRecording() {
+		headers := http.Header{}
+		propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(headers))
+		ctx = WithTraceContext(ctx, headers.Get(TraceParentHeader), headers.Get(TraceStateHeader))
+	}
+	return ctx, span
+}
+
+// endSpan 记录错误（不为空时）并结束 span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDispatchWithNoopTracer(t *testing.T) {
+	server := newChatServer(t, func(int) (int, string) { return http.StatusOK, "ok" })
+	// 未配置 telemetry.otlp_endpoint，使用 no-op TracerProvider，不需要运行采集器
+	engine := newTestEngine(t, newTestConfig(t, testProvider("mock", server.URL, "m1")))
+
+	_, span := engine.startSpan(t.Context(), "test")
+	if span.IsRecording() || span.SpanContext().IsValid() {
+		t.Error("未配置 otlp_endpoint 时 span 不应记录数据")
+	}
+	span.End()
+
+	result, match, err := QueryAndHandle(engine, t.Context(), "你好")
+	if err != nil || !match {
+		t.Fatalf("分发 query 事件失败: match=%v, err=%v", match, err)
+	}
+	if result.Reply != "ok" || result.Attempts != 1 {
+		t.Errorf("结果为回复 %q、尝试 %d 次，期望回复 %q、尝试 1 次", result.Reply, result.Attempts, "ok")
+	}
+	// 没有上游的 Trace Context 时不转发 traceparent
+	if h := server.lastHeader(t).Get(TraceParentHeader); h != "" {
+		t.Errorf("请求携带了 traceparent: %s", h)
+	}
+}
+
+func TestDispatchWithNoopTracerForwardsTraceContext(t *testing.T) {
+	server := newChatServer(t, func(int) (int, string) { return http.StatusOK, "ok" })
+	engine := newTestEngine(t, newTestConfig(t, testProvider("mock", server.URL, "m1")))
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := WithTraceContext(t.Context(), traceparent, "vendor=value")
+	if _, _, err := engine.DispatchAndHandle(ctx, "你好", "query"); err != nil {
+		t.Fatalf("分发 query 事件失败: %v", err)
+	}
+	// no-op span 不改写上游的 Trace Context，原样转发给模型接口
+	header := server.lastHeader(t)
+	if got := header.Get(TraceParentHeader); got != traceparent {
+		t.Errorf("traceparent = %q，期望 %q", got, traceparent)
+	}
+	if got := header.Get(TraceStateHeader); got != "vendor=value" {
+		t.Errorf("tracestate = %q，期望 %q", got, "vendor=value")
+	}
+}
@@ -0,0 +1,32 @@
+package agent
+
+import "net/http"
+
+// roundTripFunc 将函数适配为 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip 实现 http.RoundTripper
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// SetCustomTransport 设置调用模型接口时发送 HTTP 请求的函数，用于录制、回放、修改或完全替换 HTTP 交互（如 go-vcr）
+// 影响 Engine 通过 OpenAI 客户端发出的所有请求（查询、嵌入向量、模型列表、自检等），不影响 ValidateProviderConfig 的连通性检查；
+// 之后创建的副本使用同一函数
+// 参数:
+//   - fn: 发送请求的函数，收到的请求已包含认证、API 版本和 Trace Context 请求头；为 nil 时恢复使用默认的 HTTP 传输
+func (engine *Engine) SetCustomTransport(fn func(*http.Request) (*http.Response, error)) {
+	if fn == nil {
+		engine.httpClient = nil
+		return
+	}
+	engine.httpClient = &http.Client{Transport: traceTransport{base: roundTripFunc(fn)}}
+}
+
+// modelHTTPClient 返回调用模型接口使用的 HTTP 客户端
+func (engine *Engine) modelHTTPClient() *http.Client {
+	if engine.httpClient != nil {
+		return engine.httpClient
+	}
+	return traceHTTPClient
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultUsageFile 默认的 token 用量记录文件
+const DefaultUsageFile = "./agent_engine_logs/usage.json"
+
+// ErrNoTokenBudget 提供商未配置每月 token 预算时返回的错误
+var ErrNoTokenBudget = errors.New("提供商未配置每月 token 预算")
+
+// usageData 用量文件的内容：提供商名称 -> 计费周期（YYYY-MM）-> 已使用的 token 数
+type usageData map[string]map[string]int
+
+// usageTracker 按提供商和计费周期累计 token 用量，由 Engine 及其所有副本共享
+// 每次读写都直接操作 JSON 文件，多个进程共用同一文件时用量可以累计
+type usageTracker struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newUsageTracker 创建用量记录器
+func newUsageTracker(path string) *usageTracker {
+	if path == "" {
+		path = DefaultUsageFile
+	}
+	return &usageTracker{path: path}
+}
+
+// billingPeriod 返回时间所在的计费周期（自然月）
+func billingPeriod(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// load 读取用量文件，文件不存在时返回空记录
+func (t *usageTracker) load() (usageData, error) {
+	data, err := os.ReadFile(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return usageData{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取用量文件失败: %w", err)
+	}
+	usage := usageData{}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("解析用量文件失败: %w", err)
+	}
+	return usage, nil
+}
+
+// add 累加提供商在 now 所在计费周期的用量
+func (t *usageTracker) add(provider string, tokens int, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage, err := t.load()
+	if err != nil {
+		return err
+	}
+	if usage[provider] == nil {
+		usage[provider] = make(map[string]int)
+	}
+	usage[provider][billingPeriod(now)] += tokens
+
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化用量数据失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("创建用量文件目录失败: %w", err)
+	}
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入用量文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("写入用量文件失败: %w", err)
+	}
+	return nil
+}
+
+// flush 等待进行中的写入完成后将用量文件同步到磁盘
+func (t *usageTracker) flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := syncFile(t.path); err != nil {
+		return fmt.Errorf("同步用量文件失败: %w", err)
+	}
+	return nil
+}
+
+// used 返回提供商在 now 所在计费周期已使用的 token 数
+func (t *usageTracker) used(provider string, now time.Time) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage, err := t.load()
+	if err != nil {
+		return 0, err
+	}
+	return usage[provider][billingPeriod(now)], nil
+}
+
+// recordUsage 记录当前提供商的一次 token 用量，失败时只记录日志
+func (engine *Engine) recordUsage(ctx context.Context, tokens int64) {
+	if engine.usage == nil || tokens <= 0 {
+		return
+	}
+	if err := engine.usage.add(engine.GetCurrentProviderName(), int(tokens), time.Now()); err != nil {
+		LoggerFromContext(ctx).Warn("记录 token 用量失败", "provider", engine.GetCurrentProviderName(), "error", err)
+	}
+}
+
+// TokenBudget 单个提供商在当前计费周期的预算使用情况
+type TokenBudget struct {
+	Provider  string `json:"provider"`  // 提供商名称
+	Period    string `json:"period"`    // 计费周期（YYYY-MM）
+	Used      int    `json:"used"`      // 已使用的 token 数
+	Budget    int    `json:"budget"`    // 每月 token 预算，0 表示未配置
+	Remaining *int   `json:"remaining"` // 剩余 token 数，未配置预算时为空
+}
+
+// GetTokenBudgetRemaining 获取提供商在当前计费周期（自然月）剩余的 token 预算
+// 参数:
+//   - provider: 提供商名称
+// 返回:
+//   - int: 剩余 token 数，超出预算时为 0
+//   - error: 提供商不存在、未配置预算（ErrNoTokenBudget）或读取用量文件失败时返回错误
+func (engine *Engine) GetTokenBudgetRemaining(provider string) (int, error) {
+	budget, err := engine.tokenBudget(provider, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if budget.Remaining == nil {
+		return 0, fmt.Errorf("%w: %s", ErrNoTokenBudget, provider)
+	}
+	return *budget.Remaining, nil
+}
+
+// GetTokenBudgets 获取所有提供商在当前计费周期的预算使用情况（按配置顺序）
+// 返回:
+//   - []TokenBudget: 预算使用情况列表
+//   - error: 读取用量文件失败时返回错误
+func (engine *Engine) GetTokenBudgets() ([]TokenBudget, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	now := time.Now()
+	budgets := make([]TokenBudget, 0, len(engine.config.Provider))
+	for _, p := range engine.config.Provider {
+		budget, err := engine.tokenBudget(p.Name, now)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, *budget)
+	}
+	return budgets, nil
+}
+
+// tokenBudget 计算提供商在 now 所在计费周期的预算使用情况
+func (engine *Engine) tokenBudget(provider string, now time.Time) (*TokenBudget, error) {
+	if engine.config == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	p, err := engine.config.GetProviderByName(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := &TokenBudget{Provider: p.Name, Period: billingPeriod(now), Budget: p.MonthlyTokenBudget}
+	if engine.usage != nil {
+		if budget.Used, err = engine.usage.used(p.Name, now); err != nil {
+			return nil, err
+		}
+	}
+	if p.MonthlyTokenBudget > 0 {
+		remaining := max(p.MonthlyTokenBudget-budget.Used, 0)
+		budget.Remaining = &remaining
+	}
+	return budget, nil
+}
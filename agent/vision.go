@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// 常用的模型能力名称（对应 ModelConfig.Capabilities 的键）
+const (
+	CapabilityVision          = "vision"           // 支持图片输入
+	CapabilityFunctionCalling = "function_calling" // 支持函数调用
+)
+
+// ErrVisionNotSupported 模型未声明支持图片输入时 QueryWithImages 返回的错误
+var ErrVisionNotSupported = errors.New("模型不支持图片输入")
+
+// ModelCapabilities 模型在配置文件中声明支持的能力
+type ModelCapabilities struct {
+	SupportsVision          bool     `json:"supports_vision"`           // 是否支持图片输入（vision）
+	SupportsFunctionCalling bool     `json:"supports_function_calling"` // 是否支持函数调用（function_calling）
+	Declared                []string `json:"declared"`                  // 声明支持的全部能力，按字典序排列
+}
+
+// GetModelCapabilities 获取当前提供商中模型声明支持的能力
+// 参数:
+//   - modelId: 模型ID或别名
+// 返回:
+//   - ModelCapabilities: 模型能力，配置未加载或当前提供商未配置该模型时为零值
+func (engine *Engine) GetModelCapabilities(modelId string) ModelCapabilities {
+	var caps ModelCapabilities
+	if engine.config == nil {
+		return caps
+	}
+	provider, err := engine.config.GetProviderByName(engine.providerName)
+	if err != nil {
+		return caps
+	}
+	model, ok := provider.GetModel(modelId)
+	if !ok {
+		return caps
+	}
+
+	declared := enabledCapabilities(model)
+	caps.SupportsVision = declared[CapabilityVision]
+	caps.SupportsFunctionCalling = declared[CapabilityFunctionCalling]
+	caps.Declared = slices.Sorted(maps.Keys(declared))
+	return caps
+}
+
+// QueryWithImages 发送带图片的查询，查询文本和每张图片作为同一条用户消息的多个内容部分发送
+// 以 / 或 ./ 开头的图片按本地文件读取并转换为 base64 data URI，其他值按图片 URL 原样发送
+// 参数:
+//   - ctx: 上下文
+//   - query: 查询内容
+//   - imageURLs: 图片 URL 或本地文件路径
+// 返回:
+//   - *QueryResult: 查询结果
+//   - error: 当前模型未声明 vision 能力（ErrVisionNotSupported）、读取图片失败或调用失败时返回错误
+func (engine *Engine) QueryWithImages(ctx context.Context, query string, imageURLs []string) (*QueryResult, error) {
+	if !engine.GetModelCapabilities(engine.ModelId).SupportsVision {
+		return nil, fmt.Errorf("%w: %s", ErrVisionNotSupported, engine.ModelId)
+	}
+	return engine.QueryRequest(ctx, &QueryRequest{Query: query, Images: imageURLs})
+}
+
+// userMessage 构建本次查询的用户消息，请求包含图片时使用多个内容部分
+func (req *QueryRequest) userMessage(query string) (openai.ChatCompletionMessageParamUnion, error) {
+	if len(req.Images) == 0 {
+		return openai.UserMessage(query), nil
+	}
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(req.Images)+1)
+	parts = append(parts, openai.TextContentPart(query))
+	for _, image := range req.Images {
+		url, err := imageURL(image)
+		if err != nil {
+			return openai.ChatCompletionMessageParamUnion{}, err
+		}
+		parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: url}))
+	}
+	return openai.UserMessage(parts), nil
+}
+
+// imageURL 本地文件路径转换为 base64 data URI，其他值原样返回
+func imageURL(image string) (string, error) {
+	if !strings.HasPrefix(image, "/") && !strings.HasPrefix(image, "./") {
+		return image, nil
+	}
+	data, err := os.ReadFile(image)
+	if err != nil {
+		return "", fmt.Errorf("读取图片 %s 失败: %w", image, err)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("文件 %s 不是图片（%s）", image, mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -3,43 +3,250 @@ package conf
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// ModelConfig 定义单个模型的配置
+// YAML 中既可以直接写模型ID字符串，也可以写成包含元数据的对象
+type ModelConfig struct {
+	ID               string          `yaml:"id" json:"id"`                                                     // 模型ID
+	Aliases          []string        `yaml:"aliases,omitempty" json:"aliases,omitempty"`                       // 模型别名
+	MaxContextTokens int             `yaml:"max_context_tokens,omitempty" json:"max_context_tokens,omitempty"` // 最大上下文 token 数
+	Weight           int             `yaml:"weight,omitempty" json:"weight,omitempty"`                         // 模型权重（用于选择与负载均衡）
+	Capabilities     map[string]bool `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`             // 模型能力，例如 vision、function_calling
+	DailyQuota       int             `yaml:"daily_quota,omitempty" json:"daily_quota,omitempty"`               // 每日调用配额，0 表示不限制
+	PricePerMTokens  float64         `yaml:"price_per_m_tokens,omitempty" json:"price_per_m_tokens,omitempty"` // 每百万 token 的价格（美元），用于估算费用
+}
+
+// UnmarshalYAML 支持字符串和对象两种写法
+func (m *ModelConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*m = ModelConfig{ID: value.Value}
+		return nil
+	}
+
+	type plain ModelConfig
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	*m = ModelConfig(p)
+	return nil
+}
+
+// MarshalYAML 只有模型ID时序列化为字符串，保持配置文件简洁
+func (m ModelConfig) MarshalYAML() (any, error) {
+	meta := m
+	meta.ID = ""
+	if reflect.ValueOf(meta).IsZero() {
+		return m.ID, nil
+	}
+	type plain ModelConfig
+	return plain(m), nil
+}
+
+// HasAlias 检查模型是否包含指定别名（不区分大小写）
+func (m *ModelConfig) HasAlias(alias string) bool {
+	for _, a := range m.Aliases {
+		if strings.EqualFold(a, alias) {
+			return true
+		}
+	}
+	return false
+}
+
 // ProviderConfig 定义单个 LLM 提供商的配置
 type ProviderConfig struct {
-	Name    string   `yaml:"name"`    // 提供商名称
-	ApiKey  string   `yaml:"api_key"` // API密钥
-	BaseUrl string   `yaml:"base_url"` // 基础URL
-	Model   []string `yaml:"model"`   // 支持的模型列表
+	Name    string        `yaml:"name"`     // 提供商名称
+	ApiKey  string        `yaml:"api_key"`  // API密钥
+	BaseUrl string        `yaml:"base_url"` // 基础URL
+	Models  []ModelConfig `yaml:"model"`    // 支持的模型列表
+
+	MonthlyTokenBudget int    `yaml:"monthly_token_budget,omitempty"` // 每月 token 预算，0 表示不限制
+	LoadBalancing      string `yaml:"load_balancing,omitempty"`       // 选择初始模型的负载均衡策略，为空时使用当前模型
+	APIVersion         string `yaml:"api_version,omitempty"`          // API 版本（如 Anthropic 的 2023-06-01、Azure 的 2024-10-21），为空时不发送
+	APIVersionLocation string `yaml:"api_version_location,omitempty"` // API 版本的传递方式: header（默认）或 query
+	TokenEncoding      string `yaml:"token_encoding,omitempty"`       // 计算 token 数使用的编码（如 cl100k_base、o200k_base），为空或不受支持时近似估算
+	SSEMode            bool   `yaml:"sse_mode,omitempty"`             // 是否以流式请求调用并自行解析 SSE 响应，用于只支持 SSE 流式输出的提供商
+	SystemPrompt       string `yaml:"system_prompt,omitempty"`        // 查询时在历史消息和用户消息之前发送的系统提示（如角色设定、行为约束），为空时不发送
+
+	ModelParams `yaml:",inline"` // 查询时发送的生成参数（temperature、max_tokens、top_p）
+
+	RateLimit       RateLimit         `yaml:"rate_limit,omitempty"`       // 调用频率限制，未配置时不限制
+	Image           *ImageConfig      `yaml:"image,omitempty"`            // 图片生成设置，未配置时提供商不支持 image 命令
+	Metadata        map[string]string `yaml:"metadata,omitempty"`         // 自定义标注（如 team、cost-center、tier），用于筛选和报表
+	CustomEndpoints map[string]string `yaml:"custom_endpoints,omitempty"` // 覆盖默认接口路径: completions、models、embeddings -> 路径，以 / 开头时相对于主机，否则相对于基础URL
+}
+
+// RateLimit 定义提供商的调用频率限制（令牌桶），字段为 0 时不限制对应项
+type RateLimit struct {
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty" json:"requests_per_minute,omitempty"` // 每分钟最多发送的请求数，请求之间均匀间隔
+	TokensPerMinute   int `yaml:"tokens_per_minute,omitempty" json:"tokens_per_minute,omitempty"`     // 每分钟最多使用的 token 数，请求前按预估的输入 token 数等待，成功后补记生成的 token 数
+}
+
+// ImageConfig 定义提供商的图片生成设置（image 命令），字段为空时使用接口的默认值
+type ImageConfig struct {
+	Model   string `yaml:"model,omitempty" json:"model,omitempty"`     // 图片模型，为空时使用 DefaultImageModel
+	Size    string `yaml:"size,omitempty" json:"size,omitempty"`       // 图片尺寸，如 1024x1024、1792x1024、auto
+	Quality string `yaml:"quality,omitempty" json:"quality,omitempty"` // 图片质量，如 standard、hd（dall-e-3），low、medium、high（gpt-image-1）
+	N       int    `yaml:"n,omitempty" json:"n,omitempty"`             // 每次生成的图片数，为 0 时生成 1 张
+}
+
+// DefaultImageModel image.model 未配置时使用的图片模型
+const DefaultImageModel = "dall-e-3"
+
+// ModelParams 定义查询时发送的生成参数
+// 字段为指针以区分未设置和零值：为 nil 时不发送，使用提供商的默认值
+type ModelParams struct {
+	Temperature *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"` // 采样温度，通常为 0~2
+	MaxTokens   *int64   `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`   // 最多生成的 token 数
+	TopP        *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`             // 核采样概率，0~1
+}
+
+// Merge 返回以 override 中已设置的字段覆盖 p 后的参数
+// 参数:
+//   - override: 优先使用的参数
+// 返回:
+//   - ModelParams: 合并后的参数
+func (p ModelParams) Merge(override ModelParams) ModelParams {
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.MaxTokens != nil {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	return p
+}
+
+// 可通过 custom_endpoints 覆盖路径的接口
+const (
+	EndpointCompletions = "completions" // 对话补全，默认 chat/completions
+	EndpointModels      = "models"      // 模型列表和模型详情，默认 models
+	EndpointEmbeddings  = "embeddings"  // 嵌入向量，默认 embeddings
+)
+
+// API 版本的传递方式
+const (
+	APIVersionHeader = "header" // 通过 anthropic-version 请求头传递
+	APIVersionQuery  = "query"  // 通过 api-version 查询参数传递
+)
+
+// 负载均衡策略
+const (
+	LoadBalancingFirst          = "first"           // 总是使用第一个模型
+	LoadBalancingRandom         = "random"          // 随机选择
+	LoadBalancingRoundRobin     = "round-robin"     // 依次轮流使用
+	LoadBalancingWeightedRandom = "weighted-random" // 按模型权重随机选择
+	LoadBalancingLeastLatency   = "least-latency"   // 选择最近平均延迟最低的模型
+)
+
+// 查询结果缓存的类型
+const (
+	CacheTypeSemantic = "semantic" // 按查询的嵌入向量余弦相似度匹配，需要设置 embedding_model
+)
+
+// ClassifierRule 定义单个查询类型的匹配规则
+type ClassifierRule struct {
+	Type     string   `yaml:"type"`     // 查询类型，例如: code_generation
+	Keywords []string `yaml:"keywords"` // 关键词列表（不区分大小写）
+	Patterns []string `yaml:"patterns"` // 正则表达式列表
+}
+
+// RouteConfig 定义某一查询类型的路由目标
+type RouteConfig struct {
+	Provider string `yaml:"provider"` // 目标提供商名称，为空则保持当前提供商
+	Model    string `yaml:"model"`    // 目标模型ID，为空则使用提供商的默认模型
+}
+
+// ClassifierConfig 定义查询分类器及基于内容路由的配置
+type ClassifierConfig struct {
+	Rules         []ClassifierRule       `yaml:"rules"`          // 自定义规则，同类型的规则会覆盖内置规则
+	FallbackModel string                 `yaml:"fallback_model"` // 规则无法确定类型时用于辅助分类的模型（可选）
+	Routes        map[string]RouteConfig `yaml:"routes"`         // 查询类型到路由目标的映射
+}
+
+// TelemetryConfig 定义 OpenTelemetry 链路追踪的配置
+type TelemetryConfig struct {
+	OTLPEndpoint string `yaml:"otlp_endpoint"` // OTLP/HTTP 追踪数据的接收地址，如 http://localhost:4318（未指定路径时使用 /v1/traces），为空时不导出追踪数据
+	ServiceName  string `yaml:"service_name"`  // 上报的服务名称，默认 agent_engine
 }
 
 // Config 定义整体配置结构
 type Config struct {
-	Provider []ProviderConfig `yaml:"provider"` // 提供商列表
+	Provider   []ProviderConfig `yaml:"provider"`   // 提供商列表
+	Classifier ClassifierConfig `yaml:"classifier"` // 查询分类器配置
+	Telemetry  TelemetryConfig  `yaml:"telemetry"`  // 链路追踪配置
+
+	ErrorHistorySize  int    `yaml:"error_history_size"` // 保留的错误记录数量，默认 100
+	EvalModel         string `yaml:"eval_model"`         // 评测套件中 llm 类型评测使用的评判模型
+	UsageFile         string `yaml:"usage_file"`         // token 用量记录文件，默认 ./agent_engine_logs/usage.json
+	EmbeddingModel    string `yaml:"embedding_model"`    // 获取嵌入向量使用的模型，不在任何提供商的模型列表中时使用当前提供商
+	RequestLogFile    string `yaml:"request_log_file"`   // 请求日志文件（JSONL），默认 ./agent_engine_logs/requests.jsonl
+	CompressionPrompt string `yaml:"compression_prompt"` // 压缩会话历史时让模型总结旧消息的指令，为空时使用内置指令
+	StatsFile         string `yaml:"stats_file"`         // 持久化统计数据（如响应长度直方图）的文件，默认 ./agent_engine_logs/stats.json
+	SessionDir        string `yaml:"session_dir"`        // 保存多轮对话（--session）的目录，默认 ./agent_engine_logs/sessions
+	MaxTurns          int    `yaml:"max_turns"`          // 多轮对话保留的最多轮数（一问一答为一轮），超出时丢弃最早的轮次，默认 20
+	PruneAfter        int    `yaml:"prune_after"`        // 会话历史超过该消息数时在每轮结束后删除较早的轮次，只保留最近 prune_after 条，为 0 时不自动删除
+
+	CacheType                string  `yaml:"cache_type"`                 // 查询结果缓存的类型: semantic，为空时不缓存
+	CacheSimilarityThreshold float64 `yaml:"cache_similarity_threshold"` // semantic 缓存命中所需的最低余弦相似度，默认 0.95
+
+	CacheTTL time.Duration `yaml:"cache_ttl"` // 提供商、模型、系统提示和查询内容都相同的查询结果在内存中缓存的时长（如 10m），为 0 时不缓存
+
+	ToolWhitelist []string `yaml:"tool_whitelist"` // 启用的内置工具: shell_exec、read_file、write_file，为空时不执行任何工具
+	ToolWorkDir   string   `yaml:"tool_work_dir"`  // 内置工具的工作目录，默认当前目录
+	ToolTimeoutS  int      `yaml:"tool_timeout_s"` // 内置工具执行的超时时间（秒），默认 30
 }
 
 // LoadConfig 从指定路径加载 YAML 配置文件
+// 加载后使用 DefaultSecretResolver 替换配置中的密钥地址（如 api_key: env://DEEPSEEK_API_KEY），
+// 从 GitHub 加载的配置使用 RemoteSecretResolver
 // 参数:
-//   - configPath: 配置文件路径
+//   - configPath: 配置文件路径，github://owner/repo/path 格式时从 GitHub 加载
 // 返回:
 //   - *Config: 配置对象指针
 //   - error: 错误信息
 func LoadConfig(configPath string) (*Config, error) {
+	if IsGitHubURI(configPath) {
+		return loadConfigFromGitHubURI(configPath)
+	}
+
 	// 读取配置文件
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
-	// 解析 YAML
+	return parseConfig(data, DefaultSecretResolver())
+}
+
+// parseConfig 解析 YAML 格式的配置内容，并使用 resolver 替换其中的密钥地址
+func parseConfig(data []byte, resolver SecretResolver) (*Config, error) {
 	var config Config
-	err = yaml.Unmarshal(data, &config)
+	err := yaml.Unmarshal(data, &config)
 	if err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
 
+	if err := config.InterpolateSecrets(resolver); err != nil {
+		return nil, err
+	}
+
+	for _, p := range config.Provider {
+		switch p.APIVersionLocation {
+		case "", APIVersionHeader, APIVersionQuery:
+		default:
+			return nil, fmt.Errorf("提供商 %s 的 api_version_location %s 无效，可选值: header、query", p.Name, p.APIVersionLocation)
+		}
+	}
+
 	return &config, nil
 }
 
@@ -74,22 +281,64 @@ func (c *Config) GetProviderByName(name string) (*ProviderConfig, error) {
 //   - string: 模型名称
 //   - error: 错误信息
 func (p *ProviderConfig) GetDefaultModel() (string, error) {
-	if len(p.Model) == 0 {
+	if len(p.Models) == 0 {
 		return "", fmt.Errorf("提供商 %s 没有配置模型", p.Name)
 	}
-	return p.Model[0], nil
+	return p.Models[0].ID, nil
+}
+
+// ModelIDs 获取提供商的所有模型ID（按配置顺序）
+// 返回:
+//   - []string: 模型ID列表
+func (p *ProviderConfig) ModelIDs() []string {
+	ids := make([]string, 0, len(p.Models))
+	for _, m := range p.Models {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// GetModel 根据模型ID或别名获取模型配置
+// 参数:
+//   - modelId: 模型ID或别名（别名不区分大小写）
+// 返回:
+//   - *ModelConfig: 模型配置指针
+//   - bool: 是否找到
+func (p *ProviderConfig) GetModel(modelId string) (*ModelConfig, bool) {
+	// 优先匹配模型ID，再匹配别名
+	for i := range p.Models {
+		if p.Models[i].ID == modelId {
+			return &p.Models[i], true
+		}
+	}
+	for i := range p.Models {
+		if p.Models[i].HasAlias(modelId) {
+			return &p.Models[i], true
+		}
+	}
+	return nil, false
 }
 
 // HasModel 检查提供商是否支持指定的模型
 // 参数:
-//   - modelId: 模型ID
+//   - modelId: 模型ID或别名
 // 返回:
 //   - bool: 是否支持该模型
 func (p *ProviderConfig) HasModel(modelId string) bool {
-	for _, m := range p.Model {
-		if m == modelId {
+	_, ok := p.GetModel(modelId)
+	return ok
+}
+
+// HasCapability 检查提供商是否至少有一个模型声明支持指定能力
+// 参数:
+//   - capability: 能力名称，例如 vision、function_calling
+// 返回:
+//   - bool: 是否支持该能力
+func (p *ProviderConfig) HasCapability(capability string) bool {
+	for _, m := range p.Models {
+		if m.Capabilities[capability] {
 			return true
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
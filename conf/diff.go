@@ -0,0 +1,95 @@
+package conf
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ConfigDiff 两份配置中的一处差异
+type ConfigDiff struct {
+	Path string `json:"path"` // 配置项路径（使用 YAML 字段名），如 provider[0].base_url
+	Old  any    `json:"old"`  // 在 Diff 接收者中的值，该项不存在时为 nil
+	New  any    `json:"new"`  // 在 Diff 参数中的值，该项不存在时为 nil
+}
+
+// Diff 比较两份配置，返回所有不同的配置项
+// 结构体逐字段比较；列表按下标比较，多出的元素整体作为一处差异；映射按键比较
+// 参数:
+//   - other: 比较的配置
+// 返回:
+//   - []ConfigDiff: 差异列表，按字段顺序排列，相同时为空
+func (c *Config) Diff(other *Config) []ConfigDiff {
+	var diffs []ConfigDiff
+	diffValue(reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem(), "", &diffs)
+	return diffs
+}
+
+// diffValue 递归比较 a 和 b，将差异追加到 diffs，path 为当前字段路径
+func diffValue(a, b reflect.Value, path string, diffs *[]ConfigDiff) {
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*diffs = append(*diffs, ConfigDiff{Path: path, Old: diffInterface(a), New: diffInterface(b)})
+			}
+			return
+		}
+		diffValue(a.Elem(), b.Elem(), path, diffs)
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < a.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() {
+				diffValue(a.Field(i), b.Field(i), joinFieldPath(path, field), diffs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < max(a.Len(), b.Len()); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= a.Len():
+				*diffs = append(*diffs, ConfigDiff{Path: elemPath, New: b.Index(i).Interface()})
+			case i >= b.Len():
+				*diffs = append(*diffs, ConfigDiff{Path: elemPath, Old: a.Index(i).Interface()})
+			default:
+				diffValue(a.Index(i), b.Index(i), elemPath, diffs)
+			}
+		}
+	case reflect.Map:
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		slices.SortFunc(keys, func(x, y reflect.Value) int {
+			return cmp.Compare(fmt.Sprint(x.Interface()), fmt.Sprint(y.Interface()))
+		})
+		for _, k := range keys {
+			elemPath := fmt.Sprintf("%s[%v]", path, k)
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !av.IsValid():
+				*diffs = append(*diffs, ConfigDiff{Path: elemPath, New: bv.Interface()})
+			case !bv.IsValid():
+				*diffs = append(*diffs, ConfigDiff{Path: elemPath, Old: av.Interface()})
+			default:
+				diffValue(av, bv, elemPath, diffs)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*diffs = append(*diffs, ConfigDiff{Path: path, Old: a.Interface(), New: b.Interface()})
+		}
+	}
+}
+
+// diffInterface 返回指针指向的值，空指针返回 nil
+func diffInterface(v reflect.Value) any {
+	if v.IsNil() {
+		return nil
+	}
+	return v.Elem().Interface()
+}
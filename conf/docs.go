@@ -0,0 +1,141 @@
+package conf
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// GenerateDocumentation 生成 Markdown 格式的配置参考文档
+// 按配置顺序列出每个提供商的基础URL、负载均衡和预算设置、标注，以及模型的别名、上下文长度、权重、能力、每日配额和价格；
+// 不包含 API 密钥
+// 返回:
+//   - string: Markdown 文档
+func (c *Config) GenerateDocumentation() string {
+	var sb strings.Builder
+	sb.WriteString("# LLM 提供商配置参考\n\n")
+	fmt.Fprintf(&sb, "共 %d 个提供商。\n", len(c.Provider))
+
+	for _, p := range c.Provider {
+		fmt.Fprintf(&sb, "\n## %s\n\n", p.Name)
+		fmt.Fprintf(&sb, "- 基础URL: `%s`\n", p.BaseUrl)
+		if p.LoadBalancing != "" {
+			fmt.Fprintf(&sb, "- 负载均衡策略: %s\n", p.LoadBalancing)
+		}
+		if p.MonthlyTokenBudget > 0 {
+			fmt.Fprintf(&sb, "- 每月 token 预算: %d\n", p.MonthlyTokenBudget)
+		} else {
+			sb.WriteString("- 每月 token 预算: 不限制\n")
+		}
+		if p.APIVersion != "" {
+			location := p.APIVersionLocation
+			if location == "" {
+				location = APIVersionHeader
+			}
+			fmt.Fprintf(&sb, "- API 版本: %s（%s）\n", p.APIVersion, location)
+		}
+		if p.TokenEncoding != "" {
+			fmt.Fprintf(&sb, "- token 编码: %s\n", p.TokenEncoding)
+		}
+		if p.Image != nil {
+			model := p.Image.Model
+			if model == "" {
+				model = DefaultImageModel
+			}
+			fmt.Fprintf(&sb, "- 图片生成: %s（尺寸 %s，质量 %s）\n", model, orDash(p.Image.Size), orDash(p.Image.Quality))
+		}
+		if len(p.Metadata) > 0 {
+			pairs := make([]string, 0, len(p.Metadata))
+			for _, k := range slices.Sorted(maps.Keys(p.Metadata)) {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", k, p.Metadata[k]))
+			}
+			fmt.Fprintf(&sb, "- 标注: %s\n", strings.Join(pairs, ", "))
+		}
+		if capabilities := providerCapabilities(p); len(capabilities) > 0 {
+			fmt.Fprintf(&sb, "- 声明的能力: %s\n", strings.Join(capabilities, ", "))
+		}
+
+		if len(p.Models) == 0 {
+			sb.WriteString("\n未配置模型。\n")
+			continue
+		}
+
+		priced := slices.ContainsFunc(p.Models, func(m ModelConfig) bool { return m.PricePerMTokens > 0 })
+		sb.WriteString("\n| 模型 | 别名 | 最大上下文 | 权重 | 能力 | 每日配额 |")
+		if priced {
+			sb.WriteString(" 价格（美元/百万 token） |")
+		}
+		sb.WriteString("\n|---|---|---|---|---|---|")
+		if priced {
+			sb.WriteString("---|")
+		}
+		sb.WriteString("\n")
+
+		for _, m := range p.Models {
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s |",
+				m.ID,
+				orDash(strings.Join(m.Aliases, ", ")),
+				orDash(positive(m.MaxContextTokens)),
+				orDash(positive(m.Weight)),
+				orDash(strings.Join(modelCapabilities(m), ", ")),
+				quota(m.DailyQuota))
+			if priced {
+				price := "-"
+				if m.PricePerMTokens > 0 {
+					price = fmt.Sprintf("%g", m.PricePerMTokens)
+				}
+				fmt.Fprintf(&sb, " %s |", price)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// providerCapabilities 返回提供商所有模型声明支持的能力（去重并排序）
+func providerCapabilities(p ProviderConfig) []string {
+	set := make(map[string]bool)
+	for _, m := range p.Models {
+		for _, c := range modelCapabilities(m) {
+			set[c] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(set))
+}
+
+// modelCapabilities 返回模型声明支持的能力（排序）
+func modelCapabilities(m ModelConfig) []string {
+	var capabilities []string
+	for c, ok := range m.Capabilities {
+		if ok {
+			capabilities = append(capabilities, c)
+		}
+	}
+	slices.Sort(capabilities)
+	return capabilities
+}
+
+// positive 正数转换为字符串，其他值返回空字符串
+func positive(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+// quota 格式化每日配额
+func quota(n int) string {
+	if n <= 0 {
+		return "不限制"
+	}
+	return fmt.Sprint(n)
+}
+
+// orDash 空字符串显示为 -
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
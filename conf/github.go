@@ -0,0 +1,117 @@
+package conf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// GitHubScheme GitHub 配置文件地址前缀，格式: github://owner/repo/path/to/conf.yaml
+	GitHubScheme = "github://"
+	// GitHubTokenEnv 读取 GitHub 访问令牌的环境变量（私有仓库需要）
+	GitHubTokenEnv = "GITHUB_TOKEN"
+	// GitHubLocalSecretsEnv 设置为 true 时，从 GitHub 加载的配置也解析 env://、file:// 等本地密钥地址
+	GitHubLocalSecretsEnv = "AGENT_ENGINE_GITHUB_LOCAL_SECRETS"
+
+	githubAPIBaseUrl = "https://api.github.com"
+	githubTimeout    = 10 * time.Second
+)
+
+// IsGitHubURI 判断配置路径是否为 github:// 地址
+func IsGitHubURI(configPath string) bool {
+	return strings.HasPrefix(configPath, GitHubScheme)
+}
+
+// ParseGitHubURI 解析 github://owner/repo/path 格式的地址
+// 参数:
+//   - uri: github:// 地址
+// 返回:
+//   - owner: 仓库所有者
+//   - repo: 仓库名称
+//   - path: 文件在仓库中的路径
+//   - err: 地址格式错误时返回错误
+func ParseGitHubURI(uri string) (owner string, repo string, path string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(uri, GitHubScheme), "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("GitHub 配置地址格式错误，应为 %sowner/repo/path: %s", GitHubScheme, uri)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// githubContent GitHub Contents API 的响应结构
+type githubContent struct {
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+// LoadConfigFromGitHub 通过 GitHub Contents API 加载配置文件
+// 远程配置中的密钥地址使用 RemoteSecretResolver 解析，默认不读取本机的环境变量和文件
+// 参数:
+//   - repoOwner: 仓库所有者
+//   - repoName: 仓库名称
+//   - path: 文件在仓库中的路径
+//   - token: GitHub 访问令牌，公开仓库可为空
+// 返回:
+//   - *Config: 配置对象指针
+//   - error: 错误信息
+func LoadConfigFromGitHub(repoOwner, repoName, path, token string) (*Config, error) {
+	apiUrl := fmt.Sprintf("%s/repos/%s/%s/contents/%s", githubAPIBaseUrl,
+		url.PathEscape(repoOwner), url.PathEscape(repoName), strings.TrimPrefix(path, "/"))
+
+	req, err := http.NewRequest(http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建 GitHub 请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: githubTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 GitHub 配置文件失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取 GitHub 响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 GitHub 配置文件失败，状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var content githubContent
+	if err := json.Unmarshal(body, &content); err != nil {
+		return nil, fmt.Errorf("解析 GitHub 响应失败: %w", err)
+	}
+	if content.Type != "file" || content.Encoding != "base64" {
+		return nil, fmt.Errorf("GitHub 路径 %s 不是可直接读取的文件（type=%s, encoding=%s）", path, content.Type, content.Encoding)
+	}
+
+	// GitHub 返回的 base64 内容带有换行
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("解码 GitHub 配置文件内容失败: %w", err)
+	}
+
+	return parseConfig(data, RemoteSecretResolver())
+}
+
+// loadConfigFromGitHubURI 加载 github:// 地址指向的配置文件，令牌从 GITHUB_TOKEN 环境变量读取
+func loadConfigFromGitHubURI(uri string) (*Config, error) {
+	owner, repo, path, err := ParseGitHubURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfigFromGitHub(owner, repo, path, os.Getenv(GitHubTokenEnv))
+}
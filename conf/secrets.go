@@ -0,0 +1,232 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnsupportedSecretScheme 解析器不支持该地址的 scheme 时返回的错误
+	// InterpolateSecrets 遇到该错误时保留原值，因此 https:// 等普通地址不会被误替换
+	ErrUnsupportedSecretScheme = errors.New("不支持的密钥地址 scheme")
+	// ErrLocalSecretNotAllowed 远程配置引用本地密钥（env://、file://）且未显式允许时返回的错误
+	ErrLocalSecretNotAllowed = errors.New("远程配置不允许引用本地密钥")
+)
+
+// secretURIPattern 匹配 {scheme}://... 格式的值
+var secretURIPattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*)://(.*)$`)
+
+// SecretResolver 将密钥地址解析为实际值
+type SecretResolver interface {
+	// Resolve 解析形如 {scheme}://... 的密钥地址
+	// 不支持该 scheme 时返回包装了 ErrUnsupportedSecretScheme 的错误
+	Resolve(uri string) (string, error)
+}
+
+// parseSecretURI 拆分密钥地址的 scheme 和其余部分
+func parseSecretURI(uri string) (scheme string, rest string, ok bool) {
+	m := secretURIPattern.FindStringSubmatch(uri)
+	if m == nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), m[2], true
+}
+
+// unsupportedScheme 生成不支持该 scheme 的错误
+func unsupportedScheme(uri string) error {
+	return fmt.Errorf("%w: %s", ErrUnsupportedSecretScheme, uri)
+}
+
+// EnvSecretResolver 从环境变量读取密钥，地址格式: env://VAR_NAME
+type EnvSecretResolver struct{}
+
+// Resolve 读取地址指定的环境变量，变量未设置时返回错误
+func (EnvSecretResolver) Resolve(uri string) (string, error) {
+	scheme, name, ok := parseSecretURI(uri)
+	if !ok || scheme != "env" {
+		return "", unsupportedScheme(uri)
+	}
+	value, found := os.LookupEnv(name)
+	if !found {
+		return "", fmt.Errorf("环境变量 %s 未设置", name)
+	}
+	return value, nil
+}
+
+// FileSecretResolver 从文件读取密钥，地址格式: file:///abs/path 或 file://relative/path
+// 文件内容末尾的空白字符（如换行）会被去除
+type FileSecretResolver struct {
+	BaseDir string // 相对路径的基准目录，为空时使用当前工作目录
+}
+
+// Resolve 读取地址指定的文件内容
+func (r FileSecretResolver) Resolve(uri string) (string, error) {
+	scheme, path, ok := parseSecretURI(uri)
+	if !ok || scheme != "file" {
+		return "", unsupportedScheme(uri)
+	}
+	if path == "" {
+		return "", fmt.Errorf("密钥文件路径为空: %s", uri)
+	}
+	if r.BaseDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(r.BaseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+// SecretResolverChain 依次尝试多个解析器，使用第一个支持该 scheme 的解析器的结果
+type SecretResolverChain []SecretResolver
+
+// Resolve 依次调用各解析器，所有解析器都不支持时返回 ErrUnsupportedSecretScheme
+func (c SecretResolverChain) Resolve(uri string) (string, error) {
+	for _, r := range c {
+		value, err := r.Resolve(uri)
+		if errors.Is(err, ErrUnsupportedSecretScheme) {
+			continue
+		}
+		return value, err
+	}
+	return "", unsupportedScheme(uri)
+}
+
+var (
+	registeredResolversMu sync.Mutex
+	registeredResolvers   []SecretResolver // 通过 RegisterSecretResolver 注册的解析器（如 aws 构建标签下的 AWSSecretsManagerResolver）
+)
+
+// RegisterSecretResolver 注册 LoadConfig 使用的额外密钥解析器
+// 参数:
+//   - r: 密钥解析器
+func RegisterSecretResolver(r SecretResolver) {
+	registeredResolversMu.Lock()
+	defer registeredResolversMu.Unlock()
+	registeredResolvers = append(registeredResolvers, r)
+}
+
+// DefaultSecretResolver 返回 LoadConfig 使用的解析器：内置的 env://、file:// 解析器和所有已注册的解析器
+// 返回:
+//   - SecretResolver: 解析器链
+func DefaultSecretResolver() SecretResolver {
+	registeredResolversMu.Lock()
+	defer registeredResolversMu.Unlock()
+	chain := SecretResolverChain{EnvSecretResolver{}, FileSecretResolver{}}
+	return append(chain, registeredResolvers...)
+}
+
+// RemoteSecretResolver 返回从 GitHub 等远程地址加载配置时使用的解析器
+// 远程配置的内容不受本机控制，默认拒绝 env://、file:// 地址，以免配置读取本机的环境变量或文件并发送给配置中的提供商；
+// 已注册的解析器（如 aws-sm://）仍然生效。环境变量 AGENT_ENGINE_GITHUB_LOCAL_SECRETS 为 true 时与 DefaultSecretResolver 相同
+// 返回:
+//   - SecretResolver: 解析器链
+func RemoteSecretResolver() SecretResolver {
+	if allowed, _ := strconv.ParseBool(os.Getenv(GitHubLocalSecretsEnv)); allowed {
+		return DefaultSecretResolver()
+	}
+	registeredResolversMu.Lock()
+	defer registeredResolversMu.Unlock()
+	chain := SecretResolverChain{localSecretRefusal{}}
+	return append(chain, registeredResolvers...)
+}
+
+// localSecretRefusal 对 env://、file:// 地址返回 ErrLocalSecretNotAllowed，其他地址交给后续解析器
+type localSecretRefusal struct{}
+
+// Resolve 拒绝解析本地密钥地址
+func (localSecretRefusal) Resolve(uri string) (string, error) {
+	scheme, _, ok := parseSecretURI(uri)
+	if !ok || (scheme != "env" && scheme != "file") {
+		return "", unsupportedScheme(uri)
+	}
+	return "", fmt.Errorf("%w: %s（如需解析请设置环境变量 %s=true）", ErrLocalSecretNotAllowed, uri, GitHubLocalSecretsEnv)
+}
+
+// InterpolateSecrets 遍历配置中的所有字符串字段，将 {scheme}://... 格式的值替换为解析结果
+// 解析器不支持的 scheme（如 https://）保持原值
+// 参数:
+//   - resolver: 密钥解析器
+// 返回:
+//   - error: 解析失败时返回错误，错误信息包含字段路径
+func (c *Config) InterpolateSecrets(resolver SecretResolver) error {
+	if resolver == nil {
+		return fmt.Errorf("密钥解析器不能为空")
+	}
+	return interpolateValue(reflect.ValueOf(c).Elem(), "", resolver)
+}
+
+// interpolateValue 递归替换 v 中的字符串，path 为当前字段路径（用于错误信息）
+func interpolateValue(v reflect.Value, path string, resolver SecretResolver) error {
+	switch v.Kind() {
+	case reflect.String:
+		if _, _, ok := parseSecretURI(v.String()); !ok {
+			return nil
+		}
+		value, err := resolver.Resolve(v.String())
+		if errors.Is(err, ErrUnsupportedSecretScheme) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("解析配置项 %s 的密钥失败: %w", path, err)
+		}
+		v.SetString(value)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return interpolateValue(v.Elem(), path, resolver)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if err := interpolateValue(v.Field(i), joinFieldPath(path, field), resolver); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := interpolateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), resolver); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map 的值不可寻址，复制后替换再写回
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := interpolateValue(elem, fmt.Sprintf("%s[%v]", path, iter.Key()), resolver); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
+
+// joinFieldPath 拼接字段路径，优先使用 yaml 标签中的名称；inline 字段不增加层级
+func joinFieldPath(parent string, field reflect.StructField) string {
+	name := field.Name
+	tag, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if tag == "" && opts == "inline" {
+		return parent
+	}
+	if tag != "" && tag != "-" {
+		name = tag
+	}
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
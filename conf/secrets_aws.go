@@ -0,0 +1,83 @@
+//go:build aws
+
+package conf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// awsSecretsManagerTimeout 读取单个 AWS Secrets Manager 密钥的超时时间
+const awsSecretsManagerTimeout = 10 * time.Second
+
+// AWSSecretsManagerResolver 从 AWS Secrets Manager 读取密钥（需使用 -tags aws 构建）
+// 地址格式: aws-sm://secret-id 或 aws-sm://secret-id#json_key（密钥值为 JSON 对象时取指定字段）
+// 凭证和区域按 AWS SDK 的默认方式获取（环境变量、~/.aws 配置、实例角色等）
+type AWSSecretsManagerResolver struct {
+	once   sync.Once
+	client *secretsmanager.Client
+	err    error
+}
+
+func init() {
+	RegisterSecretResolver(&AWSSecretsManagerResolver{})
+}
+
+// Resolve 读取地址指定的密钥值
+func (r *AWSSecretsManagerResolver) Resolve(uri string) (string, error) {
+	scheme, rest, ok := parseSecretURI(uri)
+	if !ok || scheme != "aws-sm" {
+		return "", unsupportedScheme(uri)
+	}
+	secretId, jsonKey, _ := strings.Cut(rest, "#")
+	if secretId == "" {
+		return "", fmt.Errorf("AWS Secrets Manager 密钥ID为空: %s", uri)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), awsSecretsManagerTimeout)
+	defer cancel()
+
+	// 客户端在第一次使用时创建，未使用 aws-sm:// 地址时不加载 AWS 配置
+	r.once.Do(func() {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			r.err = fmt.Errorf("加载 AWS 配置失败: %w", err)
+			return
+		}
+		r.client = secretsmanager.NewFromConfig(cfg)
+	})
+	if r.err != nil {
+		return "", r.err
+	}
+
+	out, err := r.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &secretId})
+	if err != nil {
+		return "", fmt.Errorf("读取 AWS 密钥 %s 失败: %w", secretId, err)
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("AWS 密钥 %s 不是字符串类型", secretId)
+	}
+	if jsonKey == "" {
+		return *out.SecretString, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(*out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("AWS 密钥 %s 不是 JSON 对象: %w", secretId, err)
+	}
+	value, ok := fields[jsonKey]
+	if !ok {
+		return "", fmt.Errorf("AWS 密钥 %s 中没有字段 %s", secretId, jsonKey)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
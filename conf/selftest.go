@@ -0,0 +1,91 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoProviderTester 未注册提供商自检函数时 SelfTest 返回的错误
+var ErrNoProviderTester = errors.New("未注册提供商自检函数（需要导入 agent 包）")
+
+// ProviderTester 创建对单个提供商执行端到端自检的函数 test，test 返回 nil 表示自检通过
+// test 会被并发调用（每个提供商一次），全部结束后调用 shutdown 释放创建时占用的资源
+// conf 包不能依赖创建 Engine 的 agent 包，由 agent 包在初始化时通过 RegisterProviderTester 注册
+type ProviderTester func(ctx context.Context, c *Config) (test func(ctx context.Context, providerName string) error, shutdown func(ctx context.Context) error, err error)
+
+var (
+	providerTesterMu sync.Mutex
+	providerTester   ProviderTester
+)
+
+// RegisterProviderTester 注册 SelfTest 使用的提供商自检函数，重复注册时替换之前的函数
+// 参数:
+//   - t: 提供商自检函数
+func RegisterProviderTester(t ProviderTester) {
+	providerTesterMu.Lock()
+	defer providerTesterMu.Unlock()
+	providerTester = t
+}
+
+// SelfTestResult 单个提供商的自检结果
+type SelfTestResult struct {
+	Provider string        `json:"provider"`        // 提供商名称
+	Passed   bool          `json:"passed"`          // 是否通过
+	Duration time.Duration `json:"duration"`        // 自检耗时
+	Error    string        `json:"error,omitempty"` // 未通过的原因
+}
+
+// SelfTest 对所有提供商执行端到端自检，所有提供商都通过时才返回 nil，见 RunSelfTest
+// 参数:
+//   - ctx: 上下文，用于限制自检时间
+// 返回:
+//   - error: 未通过的提供商的错误（多个错误会合并返回）
+func (c *Config) SelfTest(ctx context.Context) error {
+	_, err := c.RunSelfTest(ctx)
+	return err
+}
+
+// RunSelfTest 并发对所有提供商执行端到端自检并返回每个提供商的结果
+// agent 包注册的自检依次校验提供商配置、检查连通性和密钥，并发送一次最多生成 10 个 token 的测试查询
+// 参数:
+//   - ctx: 上下文，用于限制自检时间
+// 返回:
+//   - []SelfTestResult: 每个提供商的结果，按配置顺序排列
+//   - error: 未注册自检函数（ErrNoProviderTester）、没有提供商、创建自检失败或有提供商未通过时返回错误
+func (c *Config) RunSelfTest(ctx context.Context) ([]SelfTestResult, error) {
+	providerTesterMu.Lock()
+	tester := providerTester
+	providerTesterMu.Unlock()
+	if tester == nil {
+		return nil, ErrNoProviderTester
+	}
+	if len(c.Provider) == 0 {
+		return nil, fmt.Errorf("配置中没有提供商")
+	}
+	test, shutdown, err := tester(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("创建自检失败: %w", err)
+	}
+	// 自检超时后仍需释放资源并写入统计数据
+	defer shutdown(context.WithoutCancel(ctx))
+
+	results := make([]SelfTestResult, len(c.Provider))
+	errs := make([]error, len(c.Provider))
+	var wg sync.WaitGroup
+	for i, p := range c.Provider {
+		wg.Go(func() {
+			start := time.Now()
+			err := test(ctx, p.Name)
+			results[i] = SelfTestResult{Provider: p.Name, Passed: err == nil, Duration: time.Since(start)}
+			if err != nil {
+				results[i].Error = err.Error()
+				errs[i] = fmt.Errorf("提供商 %s 自检未通过: %w", p.Name, err)
+			}
+		})
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+package conf
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// tiktoken 支持的 token 编码
+const (
+	TokenEncodingO200K  = tiktoken.MODEL_O200K_BASE  // GPT-4o 系列
+	TokenEncodingCL100K = tiktoken.MODEL_CL100K_BASE // GPT-4、GPT-3.5 系列
+	TokenEncodingP50K   = tiktoken.MODEL_P50K_BASE
+	TokenEncodingR50K   = tiktoken.MODEL_R50K_BASE
+)
+
+var (
+	// tiktokenOnce 使用内置的 BPE 文件，避免运行时下载
+	tiktokenOnce sync.Once
+	// tiktokenEncoders 已加载的编码器: 编码名称 -> *tiktoken.Tiktoken
+	tiktokenEncoders sync.Map
+)
+
+// IsTiktokenEncoding 检查编码是否由 tiktoken 精确计算
+func IsTiktokenEncoding(encoding string) bool {
+	switch encoding {
+	case TokenEncodingO200K, TokenEncodingCL100K, TokenEncodingP50K, TokenEncodingR50K, tiktoken.MODEL_P50K_EDIT:
+		return true
+	}
+	return false
+}
+
+// CountTokens 计算文本的 token 数
+// tiktoken 支持的编码（o200k_base、cl100k_base、p50k_base、r50k_base）精确计算；
+// 其他编码（如 llama 系列）和空编码按字符近似估算，见 ApproximateTokens
+// 参数:
+//   - text: 文本内容
+//   - encoding: token 编码名称，对应 ProviderConfig.TokenEncoding
+// 返回:
+//   - int: token 数
+//   - error: 加载编码失败时返回错误
+func CountTokens(text, encoding string) (int, error) {
+	if !IsTiktokenEncoding(encoding) {
+		return ApproximateTokens(text), nil
+	}
+	enc, err := tiktokenEncoder(encoding)
+	if err != nil {
+		return 0, err
+	}
+	return len(enc.Encode(text, nil, nil)), nil
+}
+
+// ApproximateTokens 粗略估算文本的 token 数：中日韩文字每字按 1 个 token，其他非空白字符每 4 个按 1 个 token
+// 参数:
+//   - text: 文本内容
+// 返回:
+//   - int: 估算的 token 数
+func ApproximateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else if !unicode.IsSpace(r) {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// tiktokenEncoder 获取编码器，首次使用时加载并缓存
+func tiktokenEncoder(encoding string) (*tiktoken.Tiktoken, error) {
+	tiktokenOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+	if enc, ok := tiktokenEncoders.Load(encoding); ok {
+		return enc.(*tiktoken.Tiktoken), nil
+	}
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("加载 token 编码 %s 失败: %w", encoding, err)
+	}
+	actual, _ := tiktokenEncoders.LoadOrStore(encoding, enc)
+	return actual.(*tiktoken.Tiktoken), nil
+}
@@ -0,0 +1,4 @@
+package constant
+
+// Version 引擎版本，构建时通过 -ldflags "-X main.Version=v1.2.3" 设置（也可直接设置 agent_engine/constant.Version），未设置时为 dev
+var Version = "dev"
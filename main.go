@@ -2,19 +2,32 @@ package main
 
 import (
 	"agent_engine/agent"
+	"agent_engine/conf"
 	"agent_engine/constant"
+	"agent_engine/model"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"maps"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	markdown "github.com/MichaelMure/go-term-markdown"
+	"github.com/openai/openai-go/v3"
 	flag "github.com/spf13/pflag"
 	"github.com/tidwall/gjson"
 	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -27,6 +40,18 @@ const (
 	MaxIndent            = 8   // 最大缩进
 )
 
+// HistogramBarWidth --histogram 柱状图中最长柱子的字符数
+const HistogramBarWidth = 40
+
+// ShutdownTimeout 退出前等待 Engine 优雅关闭的最长时间
+const ShutdownTimeout = 5 * time.Second
+
+// 构建信息，通过 -ldflags "-X main.Version=v1.2.3 -X main.BuildTime=2025-01-01T00:00:00Z" 设置
+var (
+	Version   = "" // 版本号，设置后同步到 constant.Version
+	BuildTime = "" // 构建时间
+)
+
 // Response 定义标准响应结构
 type Response struct {
 	Code    int    `json:"code"`
@@ -35,6 +60,13 @@ type Response struct {
 }
 
 func main() {
+	// 需要以非零状态码退出时设置，在其他 defer（如关闭 Engine）执行完后退出
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
 	// 日志文件
 	logFile, err := os.OpenFile("./agent_engine_logs/log.txt", os.O_CREATE|os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
@@ -54,7 +86,10 @@ func main() {
 		fmt.Fprintf(os.Stderr, "命令说明:\n")
 		fmt.Fprintf(os.Stderr, "  query   - 向 AI 模型发送查询请求（支持自动模型轮换）\n")
 		fmt.Fprintf(os.Stderr, "  list    - 列出所有可用的提供商和模型信息\n")
-		fmt.Fprintf(os.Stderr, "  render  - 将 Markdown 文本渲染为终端友好格式\n\n")
+		fmt.Fprintf(os.Stderr, "  embed   - 获取文本的嵌入向量（紧凑 JSON 输出）\n")
+		fmt.Fprintf(os.Stderr, "  image   - 根据描述生成图片（需在提供商下配置 image）\n")
+		fmt.Fprintf(os.Stderr, "  render  - 将 Markdown 文本渲染为终端友好格式\n")
+		fmt.Fprintf(os.Stderr, "  wizard  - 交互式生成配置文件（-f 指定写入路径）\n\n")
 
 		fmt.Fprintf(os.Stderr, "选项:\n")
 		// 打印所有 flag 的帮助信息（pflag 自动生成）
@@ -68,24 +103,32 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  echo \"你好\" | %s -c query\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # 列出所有模型\n")
 		fmt.Fprintf(os.Stderr, "  %s -c list\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # 获取嵌入向量\n")
+		fmt.Fprintf(os.Stderr, "  %s -c embed -p '{\"text\":\"你好\",\"model\":\"text-embedding-3-small\"}'\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # 生成图片并保存\n")
+		fmt.Fprintf(os.Stderr, "  %s -c image -p \"a futuristic city\" --download --output city.png\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # 渲染 Markdown\n")
 		fmt.Fprintf(os.Stderr, "  cat README.md | %s -c render\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # 交互式生成配置文件\n")
+		fmt.Fprintf(os.Stderr, "  %s -c wizard\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # 以 gRPC 服务模式运行\n")
+		fmt.Fprintf(os.Stderr, "  %s --grpc-port 50051\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # 提取特定字段\n")
 		fmt.Fprintf(os.Stderr, "  %s -c query -p \"你好\" -e \"$.data.reply\"\n\n", os.Args[0])
 	}
 
 	// 定义命令行参数，使用更详细的描述信息（pflag 会自动格式化）
 	command := flag.StringP("command", "c", "query",
-		"命令类型: query(查询AI), list(列出模型), render(渲染Markdown)")
+		"命令类型: query(查询AI), list(列出模型), embed(获取嵌入向量), image(生成图片), render(渲染Markdown), wizard(交互式生成配置文件)")
 
 	configPath := flag.StringP("conf", "f", "./conf.yaml",
-		"配置文件路径（支持相对路径和绝对路径）")
+		"配置文件路径（支持相对路径、绝对路径和 github://owner/repo/path，私有仓库需设置 GITHUB_TOKEN）")
 
 	extra := flag.StringP("extract", "e", "$",
-		"提取 JSON 响应中指定路径的值，使用 JSONPath 语法（如: $.data.reply）")
+		"提取 JSON 响应中指定路径的值，使用 JSONPath 语法（如: $.data.reply，支持 [?(@.is_current==true)] 过滤表达式）")
 
 	modelId := flag.StringP("model", "m", "",
-		"指定使用的模型名称（不指定则使用配置文件中的第一个模型）")
+		"指定使用的模型名称或别名，别名不区分大小写（不指定则使用配置文件中的第一个模型）")
 
 	params := flag.StringP("params", "p", "",
 		"命令参数内容（不指定则从标准输入读取；list命令可选，其他命令必需）")
@@ -93,9 +136,158 @@ func main() {
 	providerName := flag.String("provider", "",
 		"指定提供商名称（不指定则使用配置文件中的第一个提供商）")
 
+	verbose := flag.Bool("verbose", false,
+		"list 命令输出每个模型的元数据（优先从提供商接口获取）")
+
+	docs := flag.Bool("docs", false,
+		"list 命令输出 Markdown 格式的配置参考文档（提供商、模型元数据、能力、价格和配额）")
+
+	benchmark := flag.Int("benchmark", 0,
+		"对所有提供商/模型组合运行基准测试，值为每个组合的请求次数（查询内容通过 -p 或标准输入提供）")
+
+	stream := flag.Bool("stream", false,
+		"query 命令在模型生成回复的同时将内容直接输出到标准输出（不包装为 JSON），不能与 --format 等输出选项一起使用")
+
+	loadTest := flag.Bool("load-test", false,
+		"以 --rps 的速率持续发送查询 --duration 时长进行负载测试，输出延迟百分位、成功率和错误类型分布")
+
+	rps := flag.Float64("rps", 10,
+		"负载测试的目标每秒请求数")
+
+	duration := flag.Duration("duration", 60*time.Second,
+		"负载测试发送请求的时长")
+
+	ramp := flag.Bool("ramp", false,
+		"负载测试从 1 RPS 线性增加到 --rps")
+
+	fuzz := flag.Int("fuzz", 0,
+		"向当前模型（可通过 -m 指定）发送 N 个随机生成的输入，输出引发调用失败、空回复或回复中包含错误信息的输入")
+
+	abTest := flag.StringSlice("ab-test", nil,
+		"对两个提示词文件运行 A/B 测试，由当前模型评判回复优劣并输出胜率，如 --ab-test a.txt,b.txt")
+
+	abRounds := flag.Int("ab-rounds", 1,
+		"与 --ab-test 一起使用，A/B 测试的轮数")
+
+	extractCode := flag.Bool("extract-code", false,
+		"query 命令只输出回复中第一个代码块的内容（不含围栏），适合在脚本中生成代码")
+
+	allCode := flag.Bool("all-code", false,
+		"与 --extract-code 配合使用，输出回复中所有代码块的内容（以空行分隔）")
+
+	format := flag.String("format", "",
+		"query 命令的输出格式: json、text、markdown、table 或包含 {{ 的 Go 模板（如 \"{{.ModelUsed}}: {{.Reply}}\"，不指定则输出完整 JSON 响应）")
+
+	explainQuery := flag.Bool("explain-query", false,
+		"query 命令在发送前将处理预览（估算 token、选择的提供商和模型、负载均衡策略等）以 JSON 输出到标准错误，然后继续查询")
+
+	debug := flag.Bool("debug", false,
+		"调试模式：以 debug 级别记录配置、模型选择、缓存、重试决策和完整请求 JSON（可能包含敏感信息）")
+
+	scoreResponse := flag.Bool("score-response", false,
+		"query 命令在结果的 score 字段中附加启发式的回复质量评分（相关性、连贯性、长度、是否包含代码和 Markdown）")
+
+	save := flag.String("save", "",
+		"query 命令成功后将结果保存到指定路径，格式由扩展名决定（.json、.md、.txt），路径为目录时自动生成文件名")
+
+	tps := flag.Bool("tps", false,
+		"query 命令完成后以表格输出所有模型的滚动平均生成速度（completion tokens/s）后退出，不输出查询结果")
+
+	checkpoint := flag.String("checkpoint", "",
+		"query 命令成功后将当前状态（提供商、模型、统计、缓存等）保存为指定名称的检查点，写入 stats_file")
+
+	smartFallback := flag.Bool("smart-fallback", false,
+		"模型调用失败时按名称和元数据的相似度选择替代模型（默认随机选择）")
+
+	crossProviderFallback := flag.Bool("cross-provider-fallback", false,
+		"当前提供商的模型都调用失败时，按配置顺序切换到其他提供商继续尝试")
+
+	similarity := flag.Bool("similarity", false,
+		"计算两个位置参数文本的嵌入向量余弦相似度后退出（需在配置文件中设置 embedding_model），如 --similarity \"文本1\" \"文本2\"")
+
+	budgetCheck := flag.Bool("budget-check", false,
+		"输出所有提供商本月的 token 预算使用情况后退出")
+
+	health := flag.Bool("health", false,
+		"输出所有提供商的健康状态（延迟、错误率、熔断器状态、预算使用百分比）后退出，不调用模型接口")
+
+	selfTest := flag.Bool("self-test", false,
+		"对所有提供商执行端到端自检（配置校验、连通性和密钥检查、测试查询），以表格输出每个提供商的结果后退出")
+
+	selfTestTimeout := flag.Duration("self-test-timeout", 30*time.Second,
+		"与 --self-test 一起使用，自检的超时时间")
+
+	checkDrift := flag.Bool("check-drift", false,
+		"重新读取配置文件并与加载的配置比较，输出差异；存在差异时以状态码 1 退出（用于 CI）")
+
+	testAll := flag.Bool("test-all", false,
+		"测试所有提供商的连通性、密钥有效性、延迟和模型列表，以表格输出后退出")
+
+	histogram := flag.Bool("histogram", false,
+		"以 ASCII 柱状图输出当前提供商和模型（可通过 --provider、-m 指定）的回复长度分布后退出")
+
+	dumpConfig := flag.Bool("dump-config", false,
+		"以 YAML 格式输出加载后的配置后退出，API 密钥已脱敏")
+
+	dumpLog := flag.Bool("dump-log", false,
+		"以 JSONL 格式将请求日志输出到标准输出后退出，可与 --since 一起使用")
+
+	since := flag.String("since", "",
+		"与 --dump-log 一起使用，只输出最近一段时间内的请求日志，如 30m、1h、24h（time.ParseDuration 格式）")
+
+	generateConfig := flag.Bool("generate-config", false,
+		"调用 --base-url 的 /v1/models 接口获取模型列表，生成配置文件写入 -f 指定的路径（文件已存在时不覆盖）")
+
+	baseURL := flag.String("base-url", "",
+		"与 --generate-config 一起使用，提供商地址，如 https://api.deepseek.com")
+
+	apiKey := flag.String("api-key", "",
+		"与 --generate-config 一起使用，提供商的 API 密钥")
+
+	grpcPort := flag.Int("grpc-port", 0,
+		"以 gRPC 服务模式运行并监听指定端口（0 表示不启用），收到 SIGINT/SIGTERM 后退出")
+
+	systemPrompt := flag.String("system", "",
+		"本次调用使用的系统提示，覆盖配置文件中提供商的 system_prompt")
+
+	session := flag.String("session", "",
+		"多轮对话的会话ID：查询时携带该会话的历史，成功后将本轮保存到 session_dir 下的 <会话ID>.json")
+
+	summarizeSession := flag.String("summarize-session", "",
+		"从 session_dir 加载指定ID的会话，输出 3-5 句话的摘要并保存到会话文件后退出")
+
+	summaryFile := flag.String("summary-file", "",
+		"与 --summarize-session 一起使用，将摘要追加到指定的 Markdown 文件")
+
+	temperature := flag.Float64("temperature", 0,
+		"本次调用的采样温度，覆盖配置文件中提供商的 temperature（不指定时使用配置或提供商默认值）")
+
+	topP := flag.Float64("top-p", 0,
+		"本次调用的核采样概率，覆盖配置文件中提供商的 top_p（不指定时使用配置或提供商默认值）")
+
+	maxTokens := flag.Int("max-tokens", 0,
+		"每次查询最多生成的 token 数，覆盖配置文件中提供商的 max_tokens（0 表示使用配置，未配置时不限制），模型不支持该参数时不限制回复长度")
+
+	noCache := flag.Bool("no-cache", false,
+		"不使用 cache_ttl 启用的内存查询结果缓存，每次查询都调用模型")
+
+	toolsDB := flag.String("tools-db", "",
+		"SQLite 数据库文件路径：查询时向模型声明其中 t_tool 表的工具和 tool_whitelist 中的内置工具，模型请求调用时执行内置工具后继续查询")
+
+	download := flag.Bool("download", false,
+		"image 命令下载生成的图片并保存到 --output 指定的路径，而不是输出图片地址")
+
+	output := flag.String("output", "image.png",
+		"--download 保存图片的路径，生成多张图片时依次添加 -1、-2 等后缀")
+
+	maxConcurrency := flag.Int("max-concurrency", 0,
+		"同时处理的最大请求数（0 表示不限制），超出时新请求排队等待，用于 gRPC 服务模式")
+
 	// 添加 help 标志
 	help := flag.BoolP("help", "h", false, "显示此帮助信息")
 
+	version := flag.BoolP("version", "v", false, "显示版本、构建时间和 Go 版本信息")
+
 	flag.Parse()
 
 	// 如果用户请求帮助信息，显示后退出
@@ -104,9 +296,44 @@ func main() {
 		return
 	}
 
+	if Version != "" {
+		constant.Version = Version
+	}
+	if *version {
+		printVersion()
+		return
+	}
+
+	// wizard 命令从标准输入交互读取配置，不需要加载配置文件
+	if *command == "wizard" {
+		if err := agent.RunConfigWizard(*configPath, os.Stdin, os.Stdout); err != nil {
+			log.Printf("配置向导失败: %v", err)
+			transportResponse(constant.InternalError, nil, "配置向导失败: "+err.Error())
+		}
+		return
+	}
+
+	// 根据提供商的模型列表生成配置文件，不需要加载配置文件
+	if *generateConfig {
+		config, err := new(agent.Engine).GenerateConfigFromAPI(context.Background(), *baseURL, *apiKey)
+		if err != nil {
+			log.Printf("生成配置失败: %v", err)
+			transportResponse(constant.InternalError, nil, "生成配置失败: "+err.Error())
+			return
+		}
+		if err := agent.WriteConfig(*configPath, config); err != nil {
+			log.Printf("写入配置文件失败: %v", err)
+			transportResponse(constant.InternalError, nil, "写入配置文件失败: "+err.Error())
+			return
+		}
+		provider := config.Provider[0]
+		transportResponse(constant.Success, map[string]any{"config_path": *configPath, "provider": provider.Name, "models": provider.ModelIDs()}, "success")
+		return
+	}
+
 	// 统一处理参数：如果 -p 参数为空，则从标准输入读取
 	var inputContent string
-	if *params == "" && *command != "list" {
+	if *params == "" && *command != "list" && *grpcPort == 0 && !*budgetCheck && !*health && !*selfTest && !*checkDrift && *fuzz == 0 && !*similarity && !*dumpLog && *summarizeSession == "" && !*testAll && !*histogram && !*dumpConfig && len(*abTest) == 0 {
 		// 从标准输入读取所有内容
 		inputBytes, err := io.ReadAll(os.Stdin)
 		if err != nil {
@@ -126,6 +353,12 @@ func main() {
 		inputContent = *params
 	}
 
+	// list 命令的 --verbose、--docs 参数通过 JSON 参数传递给处理器
+	if *command == "list" && (*verbose || *docs) && inputContent == "" {
+		listReq, _ := json.Marshal(agent.ListRequest{Verbose: *verbose, Docs: *docs})
+		inputContent = string(listReq)
+	}
+
 	// render 命令不需要加载配置文件，直接渲染输出
 	if *command == "render" {
 		// 直接使用 markdown 渲染并输出
@@ -141,21 +374,402 @@ func main() {
 		return
 	}
 	log.Printf("从配置文件加载: provider=%s, model=%s, baseUrl=%s", engine.GetCurrentProviderName(), engine.ModelId, engine.BaseUrl)
+	engine.SetSmartFallback(*smartFallback)
+	engine.SetCrossProviderFallback(*crossProviderFallback)
+	if *debug {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		engine = engine.WithDebugMode()
+	}
+	if *systemPrompt != "" {
+		engine = engine.WithSystemPrompt(*systemPrompt)
+	}
+	// 只有显式指定的参数才覆盖配置，以区分未指定和 0
+	var modelParams conf.ModelParams
+	if flag.CommandLine.Changed("temperature") {
+		modelParams.Temperature = temperature
+	}
+	if flag.CommandLine.Changed("top-p") {
+		modelParams.TopP = topP
+	}
+	engine = engine.WithModelParams(modelParams)
+	if *maxTokens > 0 {
+		engine = engine.WithMaxResponseTokens(*maxTokens)
+	}
+	if *noCache {
+		engine = engine.WithResponseCache(nil, 0)
+	}
+	if *maxConcurrency > 0 {
+		engine = engine.WithMaxConcurrency(*maxConcurrency)
+	}
+	if *session != "" {
+		if _, err := engine.ResumeConversation(*session); err != nil {
+			log.Printf("恢复对话失败: %v", err)
+			transportResponse(constant.InternalError, nil, "恢复对话失败: "+err.Error())
+			return
+		}
+	}
+
+	// 收到 SIGINT/SIGTERM 时取消进行中的请求，退出前优雅关闭 Engine
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := engine.Shutdown(shutdownCtx); err != nil {
+			log.Printf("关闭 Engine 失败: %v", err)
+		}
+	}()
+
+	// 输出配置：始终使用密钥已脱敏的副本
+	if *dumpConfig {
+		data, err := yaml.Marshal(engine.RedactAPIKeys().GetConfig())
+		if err != nil {
+			log.Printf("序列化配置失败: %v", err)
+			transportResponse(constant.InternalError, nil, "序列化配置失败: "+err.Error())
+			return
+		}
+		fmt.Print(string(data))
+		return
+	}
+
+	// 预算检查：输出各提供商本月的 token 用量和剩余预算
+	if *budgetCheck {
+		budgets, err := engine.GetTokenBudgets()
+		if err != nil {
+			log.Printf("获取 token 预算失败: %v", err)
+			transportResponse(constant.InternalError, nil, "获取 token 预算失败: "+err.Error())
+			return
+		}
+		transportResponse(constant.Success, budgets, "success")
+		return
+	}
+
+	// 健康状态：根据已有的统计数据评估，不调用模型接口
+	if *health {
+		transportResponse(constant.Success, engine.GetProviderHealth(ctx), "success")
+		return
+	}
+
+	// 端到端自检：输出每个提供商的自检结果表格
+	if *selfTest {
+		testCtx, cancel := context.WithTimeout(ctx, *selfTestTimeout)
+		defer cancel()
+		results, err := engine.GetConfig().RunSelfTest(testCtx)
+		if err != nil {
+			log.Printf("自检未通过: %v", err)
+		}
+		if len(results) == 0 {
+			transportResponse(constant.InternalError, nil, "自检失败: "+err.Error())
+			return
+		}
+		transport(selfTestTable(results), false)
+		return
+	}
+
+	// 配置漂移检查：存在差异时输出差异表格并以状态码 1 退出
+	if *checkDrift {
+		drift, err := engine.GetConfigDrift()
+		if err != nil {
+			log.Printf("检查配置漂移失败: %v", err)
+			transportResponse(constant.InternalError, nil, "检查配置漂移失败: "+err.Error())
+			exitCode = 1
+			return
+		}
+		if len(drift) > 0 {
+			transport(driftTable(drift), false)
+			exitCode = 1
+			return
+		}
+		fmt.Println("配置与配置文件一致")
+		return
+	}
+
+	// 测试所有提供商：输出连通性测试结果表格
+	if *testAll {
+		transport(providerTestTable(engine.TestAllProviders(ctx)), false)
+		return
+	}
+
+	// 回复长度分布：输出当前模型的响应长度直方图
+	if *histogram {
+		counts := engine.GetResponseHistogram(engine.GetCurrentProviderName(), engine.ModelId)
+		fmt.Print(histogramChart(engine.GetCurrentProviderName(), engine.ModelId, counts))
+		return
+	}
+
+	// 导出请求日志：--since 为相对当前时间的时长
+	if *dumpLog {
+		var sinceTime time.Time
+		if *since != "" {
+			d, err := time.ParseDuration(*since)
+			if err != nil {
+				log.Printf("解析 --since 失败: %v", err)
+				transportResponse(constant.InternalError, nil, "解析 --since 失败: "+err.Error())
+				return
+			}
+			sinceTime = time.Now().Add(-d)
+		}
+		if err := engine.DumpRequestLog(os.Stdout, sinceTime); err != nil {
+			log.Printf("导出请求日志失败: %v", err)
+			transportResponse(constant.InternalError, nil, "导出请求日志失败: "+err.Error())
+		}
+		return
+	}
+
+	// 会话摘要：摘要随会话文件保存，指定 --summary-file 时追加到 Markdown 文件
+	if *summarizeSession != "" {
+		if _, err := engine.ResumeConversation(*summarizeSession); err != nil {
+			log.Printf("加载会话失败: %v", err)
+			transportResponse(constant.InternalError, nil, "加载会话失败: "+err.Error())
+			return
+		}
+		summary, err := engine.SummarizeConversation(ctx, *summarizeSession)
+		if err != nil {
+			log.Printf("生成会话摘要失败: %v", err)
+			transportResponse(constant.InternalError, nil, "生成会话摘要失败: "+err.Error())
+			return
+		}
+		saveConversation(engine, *summarizeSession)
+		if *summaryFile != "" {
+			if err := appendSummary(*summaryFile, *summarizeSession, summary); err != nil {
+				log.Printf("写入摘要文件失败: %v", err)
+				transportResponse(constant.InternalError, nil, "写入摘要文件失败: "+err.Error())
+				return
+			}
+		}
+		fmt.Println(summary)
+		return
+	}
+
+	// 相似度计算：输出两段文本嵌入向量的余弦相似度
+	if *similarity {
+		if flag.NArg() != 2 {
+			log.Printf("--similarity 需要两个文本参数，实际为 %d 个", flag.NArg())
+			transportResponse(constant.InternalError, nil, fmt.Sprintf("--similarity 需要两个文本参数，实际为 %d 个", flag.NArg()))
+			return
+		}
+		score, err := engine.ComputeEmbeddingSimilarity(ctx, flag.Arg(0), flag.Arg(1))
+		if err != nil {
+			log.Printf("计算相似度失败: %v", err)
+			transportResponse(constant.InternalError, nil, "计算相似度失败: "+err.Error())
+			return
+		}
+		transportResponse(constant.Success, map[string]any{"similarity": score}, "success")
+		return
+	}
+
+	// gRPC 服务模式：阻塞直到收到退出信号
+	if *grpcPort > 0 {
+		if err := engine.ServeGRPC(ctx, fmt.Sprintf(":%d", *grpcPort)); err != nil {
+			log.Printf("gRPC 服务失败: %v", err)
+			transportResponse(constant.InternalError, nil, "gRPC 服务失败: "+err.Error())
+		}
+		return
+	}
+
+	// 基准测试：输出各组合的延迟、生成速度和失败率
+	if *benchmark > 0 {
+		report, err := agent.BenchmarkProviders(ctx, engine, inputContent, *benchmark)
+		if err != nil {
+			log.Printf("基准测试失败: %v", err)
+			transportResponse(constant.InternalError, nil, "基准测试失败: "+err.Error())
+			return
+		}
+		transport(benchmarkTable(report), false)
+		return
+	}
+
+	// 负载测试：输出延迟百分位、成功率和错误类型分布
+	if *loadTest {
+		report, err := agent.RunLoadTest(ctx, engine, agent.LoadTestConfig{RPS: *rps, Duration: *duration, Query: inputContent, Ramp: *ramp})
+		if err != nil {
+			log.Printf("负载测试失败: %v", err)
+			transportResponse(constant.InternalError, nil, "负载测试失败: "+err.Error())
+			return
+		}
+		transportResponse(constant.Success, report, "success")
+		return
+	}
+
+	// 模糊测试：输出引发问题的输入
+	if *fuzz > 0 {
+		report, err := engine.FuzzTest(ctx, engine.ModelId, *fuzz)
+		if err != nil {
+			log.Printf("模糊测试失败: %v", err)
+			transportResponse(constant.InternalError, nil, "模糊测试失败: "+err.Error())
+			return
+		}
+		transportResponse(constant.Success, report, "success")
+		return
+	}
+
+	// A/B 测试：运行多轮并输出两个提示词的胜率
+	if len(*abTest) > 0 {
+		report, err := runABTest(ctx, engine, *abTest, *abRounds)
+		if err != nil {
+			log.Printf("A/B 测试失败: %v", err)
+			transportResponse(constant.InternalError, nil, "A/B 测试失败: "+err.Error())
+			return
+		}
+		transport(report, false)
+		return
+	}
+
+	// 指定了工具数据库时，query 事件改用支持工具调用的处理器
+	if *toolsDB != "" && *command == "query" {
+		handler, err := toolCallHandler(ctx, engine, *toolsDB)
+		if err != nil {
+			log.Printf("加载工具失败: %v", err)
+			transportResponse(constant.InternalError, nil, "加载工具失败: "+err.Error())
+			return
+		}
+		if err := engine.HotSwapHandler("query", handler); err != nil {
+			log.Printf("设置工具调用处理器失败: %v", err)
+			transportResponse(constant.InternalError, nil, "设置工具调用处理器失败: "+err.Error())
+			return
+		}
+	}
+
+	// 查询前输出处理预览，输出到标准错误以免影响标准输出的响应
+	if *explainQuery && *command == "query" {
+		if err := explain(ctx, engine, inputContent); err != nil {
+			log.Printf("预览查询失败: %v", err)
+			transportResponse(constant.InternalError, nil, "预览查询失败: "+err.Error())
+			return
+		}
+	}
+
+	// 流式输出：回复内容边生成边写入标准输出
+	if *stream && *command == "query" {
+		out := &countingWriter{w: os.Stdout}
+		_, err := engine.StreamQuery(ctx, inputContent, out)
+		if err != nil {
+			log.Printf("流式查询失败: %v", err)
+			exitCode = 1
+			// 已输出部分回复时错误写入标准错误，避免 JSON 响应混在回复内容中
+			if out.n > 0 {
+				fmt.Println()
+				fmt.Fprintf(os.Stderr, "流式查询失败: %v\n", err)
+				return
+			}
+			transportResponse(constant.InternalError, nil, "流式查询失败: "+err.Error())
+			return
+		}
+		fmt.Println()
+		saveConversation(engine, *session)
+		return
+	}
 
 	// 分发处理，根据结果返回（使用统一处理后的 inputContent）
-	data, match, err := engine.DispatchAndHandle(context.Background(), inputContent, *command)
+	data, match, err := engine.DispatchAndHandle(ctx, inputContent, *command)
 	if err != nil {
 		// 如果没有匹配到事件，返回错误
 		if !match {
 			transportResponse(constant.EventNotFound, nil, "未找到对应事件")
 			return
 		}
+		// 当前提供商不支持图片生成，与未找到事件同样处理
+		if errors.Is(err, agent.ErrImageNotSupported) {
+			transportResponse(constant.EventNotFound, nil, err.Error())
+			return
+		}
 		// 发生错误
 		transportResponse(constant.InternalError, nil, "内部错误: "+err.Error())
 		return
 	}
 
-	if *command == "query" {
+	// 图片生成：逐行输出图片地址，指定 --download 时下载后逐行输出保存路径
+	if result, ok := data.(*agent.ImageResult); ok {
+		paths := imageOutputPaths(*output, len(result.Images))
+		for i, image := range result.Images {
+			if !*download {
+				fmt.Println(image.URL)
+				continue
+			}
+			if err := image.Save(ctx, paths[i]); err != nil {
+				log.Printf("保存图片失败: %v", err)
+				transportResponse(constant.InternalError, nil, "保存图片失败: "+err.Error())
+				return
+			}
+			fmt.Println(paths[i])
+		}
+		return
+	}
+
+	// 保存多轮对话，失败时只记录日志，不影响输出
+	if _, ok := data.(*agent.QueryResult); ok {
+		saveConversation(engine, *session)
+	}
+
+	// 附加回复质量评分
+	if result, ok := data.(*agent.QueryResult); ok && *scoreResponse {
+		score := agent.ScoreResponse(result.Query, result.Reply)
+		result.Score = &score
+	}
+
+	// 保存查询结果，失败时只记录日志，不影响输出
+	if result, ok := data.(*agent.QueryResult); ok && *save != "" {
+		if err := engine.SaveResponse(ctx, result, *save); err != nil {
+			log.Printf("保存查询结果失败: %v", err)
+		}
+	}
+
+	// 保存检查点，失败时只记录日志，不影响输出
+	if _, ok := data.(*agent.QueryResult); ok && *checkpoint != "" {
+		if err := engine.CreateCheckpoint(*checkpoint); err != nil {
+			log.Printf("创建检查点失败: %v", err)
+		}
+	}
+
+	// 输出各模型的生成速度
+	if _, ok := data.(*agent.QueryResult); ok && *tps {
+		transport(tpsTable(engine), false)
+		return
+	}
+
+	// 配置参考文档通过 Markdown 渲染输出
+	if result, ok := data.(*agent.ListResult); ok && *docs {
+		transport(result.Documentation, false)
+		return
+	}
+
+	// 提取代码块时只输出代码内容
+	if result, ok := data.(*agent.QueryResult); ok && (*extractCode || *allCode) {
+		blocks := agent.ExtractCodeBlocks(result.Reply)
+		if len(blocks) == 0 {
+			log.Printf("回复中没有代码块")
+			transportResponse(constant.InternalError, nil, "回复中没有代码块")
+			return
+		}
+		if !*allCode {
+			blocks = blocks[:1]
+		}
+		codes := make([]string, 0, len(blocks))
+		for _, block := range blocks {
+			codes = append(codes, block.Code)
+		}
+		fmt.Println(strings.Join(codes, "\n\n"))
+		return
+	}
+
+	// 指定了输出格式时，按格式输出查询结果
+	if result, ok := data.(*agent.QueryResult); ok && *format != "" {
+		content, err := engine.FormatResponse(result, *format)
+		if err != nil {
+			log.Printf("格式化查询结果失败: %v", err)
+			transportResponse(constant.InternalError, nil, "格式化查询结果失败: "+err.Error())
+			return
+		}
+		// Markdown 和表格渲染后输出，其他格式原样输出
+		if strings.EqualFold(*format, agent.FormatMarkdown) || strings.EqualFold(*format, agent.FormatTable) {
+			transport(content, false)
+		} else {
+			fmt.Println(content)
+		}
+		return
+	}
+
+	if *command == "query" || *command == "list" {
 		// 如果指定了 extra 参数且不是默认值 "$"，则提取指定路径的值
 		if *extra != "" && *extra != "$" {
 			// 构建完整的响应结构
@@ -172,6 +786,23 @@ func main() {
 				return
 			}
 
+			// 包含过滤表达式时使用完整的 JSONPath 实现
+			if strings.Contains(*extra, "[?") {
+				results, err := agent.ExtractJSONPath(jsonData, *extra)
+				if err != nil {
+					log.Printf("JSONPath 提取失败: %v", err)
+					transportResponse(constant.InternalError, nil, "JSONPath 提取失败: "+err.Error())
+					return
+				}
+				if len(results) == 0 {
+					log.Printf("提取路径 %s 没有匹配的值", *extra)
+					transportResponse(constant.InternalError, nil, "提取路径不存在: "+*extra)
+					return
+				}
+				transport(results, false)
+				return
+			}
+
 			// 处理 JSONPath 语法：去掉开头的 "$." 前缀（gjson 不需要 $ 前缀）
 			extractPath := *extra
 			if strings.HasPrefix(extractPath, "$.") {
@@ -215,6 +846,301 @@ func getTerminalWidth() int {
 	return width
 }
 
+// saveConversation 指定了 --session 时保存多轮对话，失败时只记录日志
+func saveConversation(engine *agent.Engine, session string) {
+	if session == "" {
+		return
+	}
+	if err := engine.SaveConversation(); err != nil {
+		log.Printf("保存对话失败: %v", err)
+	}
+}
+
+// appendSummary 将会话摘要作为一节追加到 Markdown 文件，文件不存在时创建
+func appendSummary(path string, session string, summary string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "## %s（%s）\n\n%s\n\n", session, time.Now().Format(time.DateTime), summary)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// benchmarkTable 将基准测试报告格式化为 Markdown 表格
+func benchmarkTable(report *agent.BenchmarkReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# 基准测试（每个组合 %d 次）\n\n", report.Iterations)
+	sb.WriteString("| 提供商 | 模型 | p50 | p95 | p99 | tokens/s | 失败率 |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, r := range report.Results {
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %.1f | %.0f%% |\n",
+			r.Provider, r.Model,
+			r.P50.Round(time.Millisecond), r.P95.Round(time.Millisecond), r.P99.Round(time.Millisecond),
+			r.TokensPerSecond, r.ErrorRate*100)
+	}
+	return sb.String()
+}
+
+// runABTest 读取两个提示词文件运行多轮 A/B 测试，返回 Markdown 格式的胜率表格
+func runABTest(ctx context.Context, engine *agent.Engine, files []string, rounds int) (string, error) {
+	if len(files) != 2 {
+		return "", fmt.Errorf("--ab-test 需要两个提示词文件，实际为 %d 个", len(files))
+	}
+	if rounds <= 0 {
+		return "", fmt.Errorf("--ab-rounds 必须大于 0，当前为 %d", rounds)
+	}
+	var queries [2]string
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("读取提示词文件失败: %w", err)
+		}
+		queries[i] = strings.TrimSpace(string(data))
+	}
+
+	var (
+		wins      [2]int
+		ties      int
+		latencies [2]time.Duration
+	)
+	for round := 1; round <= rounds; round++ {
+		result, err := agent.ABTest(ctx, engine, queries[0], queries[1], nil)
+		if err != nil {
+			return "", fmt.Errorf("第 %d 轮: %w", round, err)
+		}
+		switch result.JudgeScore {
+		case -1:
+			wins[0]++
+		case 1:
+			wins[1]++
+		default:
+			ties++
+		}
+		latencies[0] += result.Latencies[0]
+		latencies[1] += result.Latencies[1]
+		log.Printf("A/B 测试第 %d/%d 轮完成: score=%d", round, rounds, result.JudgeScore)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# A/B 测试（%d 轮，评判模型 %s）\n\n", rounds, engine.ModelId)
+	sb.WriteString("| 提示词 | 文件 | 胜出 | 胜率 | 平均延迟 |\n")
+	sb.WriteString("|---|---|---|---|---|\n")
+	for i, name := range []string{"A", "B"} {
+		fmt.Fprintf(&sb, "| %s | %s | %d | %.0f%% | %s |\n",
+			name, files[i], wins[i], float64(wins[i])*100/float64(rounds),
+			(latencies[i] / time.Duration(rounds)).Round(time.Millisecond))
+	}
+	fmt.Fprintf(&sb, "| 平局 | - | %d | %.0f%% | - |\n", ties, float64(ties)*100/float64(rounds))
+	return sb.String(), nil
+}
+
+// providerTestTable 将提供商测试结果格式化为 Markdown 表格（按提供商名称排序）
+func providerTestTable(results map[string]agent.ProviderTestResult) string {
+	mark := func(ok bool) string {
+		if ok {
+			return "✓"
+		}
+		return "✗"
+	}
+	var sb strings.Builder
+	sb.WriteString("# 提供商测试\n\n")
+	sb.WriteString("| 提供商 | 可达 | 密钥有效 | 延迟 | 模型数 | 问题 |\n")
+	sb.WriteString("|---|---|---|---|---|---|\n")
+	for _, name := range slices.Sorted(maps.Keys(results)) {
+		r := results[name]
+		latency := "-"
+		if r.Reachable {
+			latency = r.Latency.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %d | %s |\n",
+			r.Provider, mark(r.Reachable), mark(r.AuthValid), latency, r.ModelCount, strings.ReplaceAll(r.Error, "|", "\\|"))
+	}
+	return sb.String()
+}
+
+// selfTestTable 将自检结果格式化为 Markdown 表格（按配置顺序）
+func selfTestTable(results []conf.SelfTestResult) string {
+	passed := 0
+	var sb strings.Builder
+	sb.WriteString("# 提供商自检\n\n")
+	sb.WriteString("| 提供商 | 结果 | 耗时 | 问题 |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, r := range results {
+		result := "✗"
+		if r.Passed {
+			result = "✓"
+			passed++
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n",
+			r.Provider, result, r.Duration.Round(time.Millisecond), strings.ReplaceAll(r.Error, "|", "\\|"))
+	}
+	fmt.Fprintf(&sb, "\n%d/%d 个提供商通过自检\n", passed, len(results))
+	return sb.String()
+}
+
+// driftTable 将配置差异格式化为 Markdown 表格
+func driftTable(drift []conf.ConfigDiff) string {
+	value := func(v any) string {
+		if v == nil {
+			return "-"
+		}
+		return strings.ReplaceAll(fmt.Sprintf("%v", v), "|", "\\|")
+	}
+	var sb strings.Builder
+	sb.WriteString("# 配置漂移\n\n")
+	sb.WriteString("| 配置项 | 内存中的值 | 配置文件中的值 |\n")
+	sb.WriteString("|---|---|---|\n")
+	for _, d := range drift {
+		fmt.Fprintf(&sb, "| %s | %s | %s |\n", d.Path, value(d.Old), value(d.New))
+	}
+	fmt.Fprintf(&sb, "\n%d 处差异\n", len(drift))
+	return sb.String()
+}
+
+// histogramChart 将响应长度直方图格式化为 ASCII 柱状图，最长的柱子宽度为 HistogramBarWidth
+func histogramChart(provider, model string, counts map[string]int) string {
+	total, peak := 0, 0
+	for _, n := range counts {
+		total += n
+		peak = max(peak, n)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "回复长度分布（%s/%s，共 %d 次，单位: completion token）\n", provider, model, total)
+	for _, bucket := range agent.ResponseHistogramBuckets {
+		n := counts[bucket]
+		width := 0
+		if peak > 0 {
+			width = n * HistogramBarWidth / peak
+		}
+		if n > 0 && width == 0 {
+			width = 1
+		}
+		fmt.Fprintf(&sb, "%9s | %-*s %d\n", bucket, HistogramBarWidth, strings.Repeat("#", width), n)
+	}
+	return sb.String()
+}
+
+// tpsTable 将所有提供商/模型的滚动平均生成速度格式化为 Markdown 表格，没有记录的模型显示为 -
+func tpsTable(engine *agent.Engine) string {
+	providers, _ := engine.GetAllProvidersInfo()
+	var sb strings.Builder
+	sb.WriteString("# 生成速度\n\n")
+	sb.WriteString("| 提供商 | 模型 | tokens/s |\n")
+	sb.WriteString("|---|---|---|\n")
+	for _, name := range engine.GetAllProviderNames() {
+		for _, m := range providers[name].Models {
+			value := "-"
+			if tps, err := engine.GetTokensPerSecond(m.ID); err == nil {
+				value = fmt.Sprintf("%.1f", tps)
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", name, m.ID, value)
+		}
+	}
+	return sb.String()
+}
+
+// explain 解析查询参数并将 ExplainQuery 的结果以 JSON 输出到标准错误
+func explain(ctx context.Context, engine *agent.Engine, params string) error {
+	req, err := agent.ParseQueryRequest(params)
+	if err != nil {
+		return err
+	}
+	query, err := req.RenderQuery()
+	if err != nil {
+		return err
+	}
+	result, err := engine.ExplainQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化处理预览失败: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+	return nil
+}
+
+// toolCallHandler 创建声明 tool_whitelist 中的内置工具和工具数据库中 t_tool 表的工具的 ToolCallHandler
+// 内置工具由 ToolExecutor 执行；t_tool 表只记录工具的说明，调用这些工具时向模型返回没有执行器的错误
+func toolCallHandler(ctx context.Context, engine *agent.Engine, path string) (*agent.ToolCallHandler, error) {
+	executor, err := agent.NewToolExecutor(engine)
+	if err != nil {
+		return nil, err
+	}
+	records, err := agent.LoadTableTools(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tools []openai.ChatCompletionToolUnionParam
+	schemas := executor.ToolSchemas()
+	for _, name := range slices.Sorted(maps.Keys(schemas)) {
+		tool, err := agent.NewFunctionTool(name, "", schemas[name])
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	// 与内置工具同名的记录不重复声明
+	records = slices.DeleteFunc(records, func(t model.TableTool) bool { return executor.Allowed(t.ToolID) })
+	tools = append(tools, agent.TableToolParams(records)...)
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("工具数据库 %s 中没有可用的工具，tool_whitelist 也未启用内置工具", path)
+	}
+	log.Printf("已加载工具: 内置 %d 个，t_tool 表 %d 个", len(schemas), len(records))
+
+	return &agent.ToolCallHandler{
+		Tools: tools,
+		Executor: func(name, args string) (string, error) {
+			if !executor.Allowed(name) {
+				return "", fmt.Errorf("工具 %s 没有可用的执行器", name)
+			}
+			return executor.Execute(ctx, name, args)
+		},
+	}, nil
+}
+
+// imageOutputPaths 返回保存 n 张图片的路径：只有一张时为 output，否则在扩展名前依次添加 -1、-2 等后缀
+func imageOutputPaths(output string, n int) []string {
+	if n == 1 {
+		return []string{output}
+	}
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("%s-%d%s", base, i+1, ext)
+	}
+	return paths
+}
+
+// printVersion 输出版本、构建时间和 Go 版本信息
+func printVersion() {
+	buildTime := BuildTime
+	if buildTime == "" {
+		buildTime = "unknown"
+	}
+	fmt.Printf("agent_engine %s\n", constant.Version)
+	fmt.Printf("构建时间: %s\n", buildTime)
+	fmt.Printf("Go 版本: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// countingWriter 记录已写入字节数的 io.Writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // transportResponse 返回数据到stdio
 func transportResponse(code int, data any, message string) {
 	rsp := Response{
@@ -0,0 +1,1274 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v5.28.3
+// source: proto/agent.proto
+
+// AgentService 的 gRPC 接口定义
+// 修改后在仓库根目录执行以下命令重新生成 Go 代码：
+// protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/agent.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ServingStatus 服务状态
+type HealthCheckResponse_ServingStatus int32
+
+const (
+	HealthCheckResponse_UNKNOWN     HealthCheckResponse_ServingStatus = 0
+	HealthCheckResponse_SERVING     HealthCheckResponse_ServingStatus = 1
+	HealthCheckResponse_NOT_SERVING HealthCheckResponse_ServingStatus = 2
+)
+
+// Enum value maps for HealthCheckResponse_ServingStatus.
+var (
+	HealthCheckResponse_ServingStatus_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "SERVING",
+		2: "NOT_SERVING",
+	}
+	HealthCheckResponse_ServingStatus_value = map[string]int32{
+		"UNKNOWN":     0,
+		"SERVING":     1,
+		"NOT_SERVING": 2,
+	}
+)
+
+func (x HealthCheckResponse_ServingStatus) Enum() *HealthCheckResponse_ServingStatus {
+	p := new(HealthCheckResponse_ServingStatus)
+	*p = x
+	return p
+}
+
+func (x HealthCheckResponse_ServingStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (HealthCheckResponse_ServingStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_agent_proto_enumTypes[0].Descriptor()
+}
+
+func (HealthCheckResponse_ServingStatus) Type() protoreflect.EnumType {
+	return &file_proto_agent_proto_enumTypes[0]
+}
+
+func (x HealthCheckResponse_ServingStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use HealthCheckResponse_ServingStatus.Descriptor instead.
+func (HealthCheckResponse_ServingStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{11, 0}
+}
+
+// QueryRequest 查询请求，对应 agent.QueryRequest
+type QueryRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Query            string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`                                                                                                             // 查询内容，可包含 Go 模板占位符
+	TemplateVars     map[string]string      `protobuf:"bytes,2,rep,name=template_vars,json=templateVars,proto3" json:"template_vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 模板变量，非空时使用其渲染 query
+	OverrideModel    string                 `protobuf:"bytes,3,opt,name=override_model,json=overrideModel,proto3" json:"override_model,omitempty"`                                                                        // 本次请求使用的模型
+	OverrideProvider string                 `protobuf:"bytes,4,opt,name=override_provider,json=overrideProvider,proto3" json:"override_provider,omitempty"`                                                               // 本次请求使用的提供商
+	N                int32                  `protobuf:"varint,5,opt,name=n,proto3" json:"n,omitempty"`                                                                                                                    // 生成的回复数量
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_proto_agent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetTemplateVars() map[string]string {
+	if x != nil {
+		return x.TemplateVars
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetOverrideModel() string {
+	if x != nil {
+		return x.OverrideModel
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetOverrideProvider() string {
+	if x != nil {
+		return x.OverrideProvider
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetN() int32 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+// QueryResponse 查询结果，对应 agent.QueryResult
+// Query 流的中间消息只设置 delta，最后一条消息设置 done 和其余字段；Batch 中的结果不使用 delta 和 done
+type QueryResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Query              string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`                                                         // 实际发送的查询内容
+	Reply              string                 `protobuf:"bytes,2,opt,name=reply,proto3" json:"reply,omitempty"`                                                         // 模型回复
+	Think              string                 `protobuf:"bytes,3,opt,name=think,proto3" json:"think,omitempty"`                                                         // 推理过程（部分模型提供）
+	Replies            []string               `protobuf:"bytes,4,rep,name=replies,proto3" json:"replies,omitempty"`                                                     // 请求多个回复（n > 1）时的全部回复
+	ModelUsed          string                 `protobuf:"bytes,5,opt,name=model_used,json=modelUsed,proto3" json:"model_used,omitempty"`                                // 实际使用的模型
+	ProviderUsed       string                 `protobuf:"bytes,6,opt,name=provider_used,json=providerUsed,proto3" json:"provider_used,omitempty"`                       // 实际使用的提供商
+	Attempts           int32                  `protobuf:"varint,7,opt,name=attempts,proto3" json:"attempts,omitempty"`                                                  // 尝试次数
+	CorrelationId      string                 `protobuf:"bytes,8,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`                    // 请求关联ID
+	RequestId          string                 `protobuf:"bytes,9,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`                                // Engine.WithRequestID 设置的请求ID
+	ApiKeyUsed         string                 `protobuf:"bytes,10,opt,name=api_key_used,json=apiKeyUsed,proto3" json:"api_key_used,omitempty"`                          // 设置了密钥轮换时成功调用所用的密钥（脱敏）
+	TotalTokens        int64                  `protobuf:"varint,11,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`                        // 成功调用消耗的 token 数，提供商未返回用量时为 0
+	Fallback           bool                   `protobuf:"varint,12,opt,name=fallback,proto3" json:"fallback,omitempty"`                                                 // reply 是否为兜底内容
+	EffectiveMaxTokens int64                  `protobuf:"varint,13,opt,name=effective_max_tokens,json=effectiveMaxTokens,proto3" json:"effective_max_tokens,omitempty"` // 成功调用实际使用的 max_tokens，未限制时为 0
+	ToolCalls          []*ToolCallRecord      `protobuf:"bytes,14,rep,name=tool_calls,json=toolCalls,proto3" json:"tool_calls,omitempty"`                               // 执行过的工具调用
+	CachedQuery        string                 `protobuf:"bytes,15,opt,name=cached_query,json=cachedQuery,proto3" json:"cached_query,omitempty"`                         // 结果来自查询结果缓存时，命中的缓存查询
+	CacheSimilarity    float64                `protobuf:"fixed64,16,opt,name=cache_similarity,json=cacheSimilarity,proto3" json:"cache_similarity,omitempty"`           // 结果来自查询结果缓存时，与缓存查询的余弦相似度
+	Score              *ResponseScore         `protobuf:"bytes,17,opt,name=score,proto3" json:"score,omitempty"`                                                        // 回复质量评分
+	PostHookErrors     []string               `protobuf:"bytes,18,rep,name=post_hook_errors,json=postHookErrors,proto3" json:"post_hook_errors,omitempty"`              // 查询后置钩子返回的错误
+	Delta              string                 `protobuf:"bytes,19,opt,name=delta,proto3" json:"delta,omitempty"`                                                        // Query 流的中间消息：回复内容分片
+	Done               bool                   `protobuf:"varint,20,opt,name=done,proto3" json:"done,omitempty"`                                                         // Query 流的最后一条消息：完整的查询结果
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_proto_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *QueryResponse) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetReply() string {
+	if x != nil {
+		return x.Reply
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetThink() string {
+	if x != nil {
+		return x.Think
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetReplies() []string {
+	if x != nil {
+		return x.Replies
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetModelUsed() string {
+	if x != nil {
+		return x.ModelUsed
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetProviderUsed() string {
+	if x != nil {
+		return x.ProviderUsed
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetApiKeyUsed() string {
+	if x != nil {
+		return x.ApiKeyUsed
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetTotalTokens() int64 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetFallback() bool {
+	if x != nil {
+		return x.Fallback
+	}
+	return false
+}
+
+func (x *QueryResponse) GetEffectiveMaxTokens() int64 {
+	if x != nil {
+		return x.EffectiveMaxTokens
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetToolCalls() []*ToolCallRecord {
+	if x != nil {
+		return x.ToolCalls
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetCachedQuery() string {
+	if x != nil {
+		return x.CachedQuery
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetCacheSimilarity() float64 {
+	if x != nil {
+		return x.CacheSimilarity
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetScore() *ResponseScore {
+	if x != nil {
+		return x.Score
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetPostHookErrors() []string {
+	if x != nil {
+		return x.PostHookErrors
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetDelta() string {
+	if x != nil {
+		return x.Delta
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+// ToolCallRecord 查询过程中执行的一次工具调用，对应 agent.ToolCallRecord
+type ToolCallRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`           // 工具名称
+	Arguments     string                 `protobuf:"bytes,2,opt,name=arguments,proto3" json:"arguments,omitempty"` // 模型生成的 JSON 参数
+	Output        string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`       // 工具输出
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`         // 执行失败时的错误信息
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCallRecord) Reset() {
+	*x = ToolCallRecord{}
+	mi := &file_proto_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCallRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCallRecord) ProtoMessage() {}
+
+func (x *ToolCallRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCallRecord.ProtoReflect.Descriptor instead.
+func (*ToolCallRecord) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ToolCallRecord) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolCallRecord) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+func (x *ToolCallRecord) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *ToolCallRecord) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ResponseScore 回复质量评分，对应 agent.ResponseScore
+type ResponseScore struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Relevance     float64                `protobuf:"fixed64,1,opt,name=relevance,proto3" json:"relevance,omitempty"`                           // 查询与回复词集合的 Jaccard 相似度
+	Coherence     float64                `protobuf:"fixed64,2,opt,name=coherence,proto3" json:"coherence,omitempty"`                           // 根据句子数和平均句子长度估算的连贯性
+	Length        int32                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`                                  // 回复的字符数
+	HasCode       bool                   `protobuf:"varint,4,opt,name=has_code,json=hasCode,proto3" json:"has_code,omitempty"`                 // 回复是否包含围栏代码块
+	HasMarkdown   bool                   `protobuf:"varint,5,opt,name=has_markdown,json=hasMarkdown,proto3" json:"has_markdown,omitempty"`     // 回复是否包含 Markdown 语法
+	OverallScore  float64                `protobuf:"fixed64,6,opt,name=overall_score,json=overallScore,proto3" json:"overall_score,omitempty"` // relevance 和 coherence 的平均值
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseScore) Reset() {
+	*x = ResponseScore{}
+	mi := &file_proto_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseScore) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseScore) ProtoMessage() {}
+
+func (x *ResponseScore) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseScore.ProtoReflect.Descriptor instead.
+func (*ResponseScore) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResponseScore) GetRelevance() float64 {
+	if x != nil {
+		return x.Relevance
+	}
+	return 0
+}
+
+func (x *ResponseScore) GetCoherence() float64 {
+	if x != nil {
+		return x.Coherence
+	}
+	return 0
+}
+
+func (x *ResponseScore) GetLength() int32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ResponseScore) GetHasCode() bool {
+	if x != nil {
+		return x.HasCode
+	}
+	return false
+}
+
+func (x *ResponseScore) GetHasMarkdown() bool {
+	if x != nil {
+		return x.HasMarkdown
+	}
+	return false
+}
+
+func (x *ResponseScore) GetOverallScore() float64 {
+	if x != nil {
+		return x.OverallScore
+	}
+	return 0
+}
+
+// ListRequest 列表请求，对应 agent.ListRequest
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Verbose       bool                   `protobuf:"varint,1,opt,name=verbose,proto3" json:"verbose,omitempty"` // 是否包含每个模型的元数据
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_proto_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListRequest) GetVerbose() bool {
+	if x != nil {
+		return x.Verbose
+	}
+	return false
+}
+
+// ProviderInfo 单个提供商的详细信息，对应 agent.ProviderInfo
+type ProviderInfo struct {
+	state         protoimpl.MessageState      `protogen:"open.v1"`
+	Name          string                      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                                                                                  // 提供商名称
+	BaseUrl       string                      `protobuf:"bytes,2,opt,name=base_url,json=baseUrl,proto3" json:"base_url,omitempty"`                                                                                             // 提供商的 base_url
+	Models        []string                    `protobuf:"bytes,3,rep,name=models,proto3" json:"models,omitempty"`                                                                                                              // 该提供商支持的模型列表
+	IsCurrent     bool                        `protobuf:"varint,4,opt,name=is_current,json=isCurrent,proto3" json:"is_current,omitempty"`                                                                                      // 是否为当前使用的提供商
+	ModelMetadata map[string]*structpb.Struct `protobuf:"bytes,5,rep,name=model_metadata,json=modelMetadata,proto3" json:"model_metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 每个模型的元数据（verbose 模式）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProviderInfo) Reset() {
+	*x = ProviderInfo{}
+	mi := &file_proto_agent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProviderInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProviderInfo) ProtoMessage() {}
+
+func (x *ProviderInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProviderInfo.ProtoReflect.Descriptor instead.
+func (*ProviderInfo) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ProviderInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetBaseUrl() string {
+	if x != nil {
+		return x.BaseUrl
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetModels() []string {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+func (x *ProviderInfo) GetIsCurrent() bool {
+	if x != nil {
+		return x.IsCurrent
+	}
+	return false
+}
+
+func (x *ProviderInfo) GetModelMetadata() map[string]*structpb.Struct {
+	if x != nil {
+		return x.ModelMetadata
+	}
+	return nil
+}
+
+// ListResponse 列表结果，对应 agent.ListResult
+type ListResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ConfigPath      string                 `protobuf:"bytes,1,opt,name=config_path,json=configPath,proto3" json:"config_path,omitempty"`                // 配置文件绝对路径
+	CurrentProvider string                 `protobuf:"bytes,2,opt,name=current_provider,json=currentProvider,proto3" json:"current_provider,omitempty"` // 当前提供商名称
+	CurrentModel    string                 `protobuf:"bytes,3,opt,name=current_model,json=currentModel,proto3" json:"current_model,omitempty"`          // 当前模型ID
+	CurrentBaseUrl  string                 `protobuf:"bytes,4,opt,name=current_base_url,json=currentBaseUrl,proto3" json:"current_base_url,omitempty"`  // 当前使用的 base_url
+	Providers       []*ProviderInfo        `protobuf:"bytes,5,rep,name=providers,proto3" json:"providers,omitempty"`                                    // 所有提供商的详细信息
+	TotalProviders  int32                  `protobuf:"varint,6,opt,name=total_providers,json=totalProviders,proto3" json:"total_providers,omitempty"`   // 提供商总数
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_proto_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListResponse) GetConfigPath() string {
+	if x != nil {
+		return x.ConfigPath
+	}
+	return ""
+}
+
+func (x *ListResponse) GetCurrentProvider() string {
+	if x != nil {
+		return x.CurrentProvider
+	}
+	return ""
+}
+
+func (x *ListResponse) GetCurrentModel() string {
+	if x != nil {
+		return x.CurrentModel
+	}
+	return ""
+}
+
+func (x *ListResponse) GetCurrentBaseUrl() string {
+	if x != nil {
+		return x.CurrentBaseUrl
+	}
+	return ""
+}
+
+func (x *ListResponse) GetProviders() []*ProviderInfo {
+	if x != nil {
+		return x.Providers
+	}
+	return nil
+}
+
+func (x *ListResponse) GetTotalProviders() int32 {
+	if x != nil {
+		return x.TotalProviders
+	}
+	return 0
+}
+
+// BatchRequest 批量查询请求
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*QueryRequest        `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`        // 查询请求列表
+	Concurrency   int32                  `protobuf:"varint,2,opt,name=concurrency,proto3" json:"concurrency,omitempty"` // 最大并发数，小于等于 0 时使用服务端默认值
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_proto_agent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchRequest) GetRequests() []*QueryRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+func (x *BatchRequest) GetConcurrency() int32 {
+	if x != nil {
+		return x.Concurrency
+	}
+	return 0
+}
+
+// BatchItem 单个查询的结果
+type BatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *QueryResponse         `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"` // 查询结果，失败时为空
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`   // 错误信息，成功时为空
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_proto_agent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchItem) GetResult() *QueryResponse {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *BatchItem) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// BatchResponse 批量查询结果
+type BatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BatchItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"` // 与请求顺序一致的结果列表
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_proto_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BatchResponse) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// HealthCheckRequest 健康检查请求
+type HealthCheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthCheckRequest) Reset() {
+	*x = HealthCheckRequest{}
+	mi := &file_proto_agent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthCheckRequest) ProtoMessage() {}
+
+func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
+func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{10}
+}
+
+// HealthCheckResponse 健康检查结果
+type HealthCheckResponse struct {
+	state            protoimpl.MessageState            `protogen:"open.v1"`
+	Status           HealthCheckResponse_ServingStatus `protobuf:"varint,1,opt,name=status,proto3,enum=agent.HealthCheckResponse_ServingStatus" json:"status,omitempty"`                                   // 服务状态
+	Provider         string                            `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`                                                                             // 当前提供商
+	Model            string                            `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`                                                                                   // 当前模型
+	QueriesPerSecond float64                           `protobuf:"fixed64,4,opt,name=queries_per_second,json=queriesPerSecond,proto3" json:"queries_per_second,omitempty"`                                 // 最近 60 秒的每秒查询数
+	Providers        map[string]*ProviderHealth        `protobuf:"bytes,5,rep,name=providers,proto3" json:"providers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 提供商名称 -> 健康状态，对应 agent.Engine.GetProviderHealth
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *HealthCheckResponse) Reset() {
+	*x = HealthCheckResponse{}
+	mi := &file_proto_agent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthCheckResponse) ProtoMessage() {}
+
+func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
+func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *HealthCheckResponse) GetStatus() HealthCheckResponse_ServingStatus {
+	if x != nil {
+		return x.Status
+	}
+	return HealthCheckResponse_UNKNOWN
+}
+
+func (x *HealthCheckResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *HealthCheckResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *HealthCheckResponse) GetQueriesPerSecond() float64 {
+	if x != nil {
+		return x.QueriesPerSecond
+	}
+	return 0
+}
+
+func (x *HealthCheckResponse) GetProviders() map[string]*ProviderHealth {
+	if x != nil {
+		return x.Providers
+	}
+	return nil
+}
+
+// ProviderHealth 单个提供商的健康状态，对应 agent.HealthStatus
+type ProviderHealth struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Status              string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                                                        // healthy、degraded 或 unhealthy
+	Latency             *durationpb.Duration   `protobuf:"bytes,2,opt,name=latency,proto3" json:"latency,omitempty"`                                                      // 所有模型最近成功调用的平均延迟，没有记录时为 0
+	ErrorRate           float64                `protobuf:"fixed64,3,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`                               // 最近 20 次模型调用的错误率，没有记录时为 0
+	CircuitBreakerState string                 `protobuf:"bytes,4,opt,name=circuit_breaker_state,json=circuitBreakerState,proto3" json:"circuit_breaker_state,omitempty"` // 熔断器状态: closed 或 open
+	TokenBudgetPct      float64                `protobuf:"fixed64,5,opt,name=token_budget_pct,json=tokenBudgetPct,proto3" json:"token_budget_pct,omitempty"`              // 当前计费周期已使用的 token 预算百分比，未配置预算时为 0
+	LastError           string                 `protobuf:"bytes,6,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`                                 // 最近一次调用失败的错误信息
+	LastSuccess         *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_success,json=lastSuccess,proto3" json:"last_success,omitempty"`                           // 最近一次成功调用的时间，没有记录时为空
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *ProviderHealth) Reset() {
+	*x = ProviderHealth{}
+	mi := &file_proto_agent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProviderHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProviderHealth) ProtoMessage() {}
+
+func (x *ProviderHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProviderHealth.ProtoReflect.Descriptor instead.
+func (*ProviderHealth) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ProviderHealth) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ProviderHealth) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *ProviderHealth) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *ProviderHealth) GetCircuitBreakerState() string {
+	if x != nil {
+		return x.CircuitBreakerState
+	}
+	return ""
+}
+
+func (x *ProviderHealth) GetTokenBudgetPct() float64 {
+	if x != nil {
+		return x.TokenBudgetPct
+	}
+	return 0
+}
+
+func (x *ProviderHealth) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *ProviderHealth) GetLastSuccess() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSuccess
+	}
+	return nil
+}
+
+var File_proto_agent_proto protoreflect.FileDescriptor
+
+const file_proto_agent_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/agent.proto\x12\x05agent\x1a\x1egoogle/protobuf/duration.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x93\x02\n" +
+	"\fQueryRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12J\n" +
+	"\rtemplate_vars\x18\x02 \x03(\v2%.agent.QueryRequest.TemplateVarsEntryR\ftemplateVars\x12%\n" +
+	"\x0eoverride_model\x18\x03 \x01(\tR\roverrideModel\x12+\n" +
+	"\x11override_provider\x18\x04 \x01(\tR\x10overrideProvider\x12\f\n" +
+	"\x01n\x18\x05 \x01(\x05R\x01n\x1a?\n" +
+	"\x11TemplateVarsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa8\x05\n" +
+	"\rQueryResponse\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05reply\x18\x02 \x01(\tR\x05reply\x12\x14\n" +
+	"\x05think\x18\x03 \x01(\tR\x05think\x12\x18\n" +
+	"\areplies\x18\x04 \x03(\tR\areplies\x12\x1d\n" +
+	"\n" +
+	"model_used\x18\x05 \x01(\tR\tmodelUsed\x12#\n" +
+	"\rprovider_used\x18\x06 \x01(\tR\fproviderUsed\x12\x1a\n" +
+	"\battempts\x18\a \x01(\x05R\battempts\x12%\n" +
+	"\x0ecorrelation_id\x18\b \x01(\tR\rcorrelationId\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\t \x01(\tR\trequestId\x12 \n" +
+	"\fapi_key_used\x18\n" +
+	" \x01(\tR\n" +
+	"apiKeyUsed\x12!\n" +
+	"\ftotal_tokens\x18\v \x01(\x03R\vtotalTokens\x12\x1a\n" +
+	"\bfallback\x18\f \x01(\bR\bfallback\x120\n" +
+	"\x14effective_max_tokens\x18\r \x01(\x03R\x12effectiveMaxTokens\x124\n" +
+	"\n" +
+	"tool_calls\x18\x0e \x03(\v2\x15.agent.ToolCallRecordR\ttoolCalls\x12!\n" +
+	"\fcached_query\x18\x0f \x01(\tR\vcachedQuery\x12)\n" +
+	"\x10cache_similarity\x18\x10 \x01(\x01R\x0fcacheSimilarity\x12*\n" +
+	"\x05score\x18\x11 \x01(\v2\x14.agent.ResponseScoreR\x05score\x12(\n" +
+	"\x10post_hook_errors\x18\x12 \x03(\tR\x0epostHookErrors\x12\x14\n" +
+	"\x05delta\x18\x13 \x01(\tR\x05delta\x12\x12\n" +
+	"\x04done\x18\x14 \x01(\bR\x04done\"p\n" +
+	"\x0eToolCallRecord\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\targuments\x18\x02 \x01(\tR\targuments\x12\x16\n" +
+	"\x06output\x18\x03 \x01(\tR\x06output\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xc6\x01\n" +
+	"\rResponseScore\x12\x1c\n" +
+	"\trelevance\x18\x01 \x01(\x01R\trelevance\x12\x1c\n" +
+	"\tcoherence\x18\x02 \x01(\x01R\tcoherence\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x05R\x06length\x12\x19\n" +
+	"\bhas_code\x18\x04 \x01(\bR\ahasCode\x12!\n" +
+	"\fhas_markdown\x18\x05 \x01(\bR\vhasMarkdown\x12#\n" +
+	"\roverall_score\x18\x06 \x01(\x01R\foverallScore\"'\n" +
+	"\vListRequest\x12\x18\n" +
+	"\averbose\x18\x01 \x01(\bR\averbose\"\x9e\x02\n" +
+	"\fProviderInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x19\n" +
+	"\bbase_url\x18\x02 \x01(\tR\abaseUrl\x12\x16\n" +
+	"\x06models\x18\x03 \x03(\tR\x06models\x12\x1d\n" +
+	"\n" +
+	"is_current\x18\x04 \x01(\bR\tisCurrent\x12M\n" +
+	"\x0emodel_metadata\x18\x05 \x03(\v2&.agent.ProviderInfo.ModelMetadataEntryR\rmodelMetadata\x1aY\n" +
+	"\x12ModelMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x05value:\x028\x01\"\x85\x02\n" +
+	"\fListResponse\x12\x1f\n" +
+	"\vconfig_path\x18\x01 \x01(\tR\n" +
+	"configPath\x12)\n" +
+	"\x10current_provider\x18\x02 \x01(\tR\x0fcurrentProvider\x12#\n" +
+	"\rcurrent_model\x18\x03 \x01(\tR\fcurrentModel\x12(\n" +
+	"\x10current_base_url\x18\x04 \x01(\tR\x0ecurrentBaseUrl\x121\n" +
+	"\tproviders\x18\x05 \x03(\v2\x13.agent.ProviderInfoR\tproviders\x12'\n" +
+	"\x0ftotal_providers\x18\x06 \x01(\x05R\x0etotalProviders\"a\n" +
+	"\fBatchRequest\x12/\n" +
+	"\brequests\x18\x01 \x03(\v2\x13.agent.QueryRequestR\brequests\x12 \n" +
+	"\vconcurrency\x18\x02 \x01(\x05R\vconcurrency\"O\n" +
+	"\tBatchItem\x12,\n" +
+	"\x06result\x18\x01 \x01(\v2\x14.agent.QueryResponseR\x06result\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"7\n" +
+	"\rBatchResponse\x12&\n" +
+	"\x05items\x18\x01 \x03(\v2\x10.agent.BatchItemR\x05items\"\x14\n" +
+	"\x12HealthCheckRequest\"\x91\x03\n" +
+	"\x13HealthCheckResponse\x12@\n" +
+	"\x06status\x18\x01 \x01(\x0e2(.agent.HealthCheckResponse.ServingStatusR\x06status\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12,\n" +
+	"\x12queries_per_second\x18\x04 \x01(\x01R\x10queriesPerSecond\x12G\n" +
+	"\tproviders\x18\x05 \x03(\v2).agent.HealthCheckResponse.ProvidersEntryR\tproviders\x1aS\n" +
+	"\x0eProvidersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12+\n" +
+	"\x05value\x18\x02 \x01(\v2\x15.agent.ProviderHealthR\x05value:\x028\x01\":\n" +
+	"\rServingStatus\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\v\n" +
+	"\aSERVING\x10\x01\x12\x0f\n" +
+	"\vNOT_SERVING\x10\x02\"\xb8\x02\n" +
+	"\x0eProviderHealth\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x123\n" +
+	"\alatency\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\alatency\x12\x1d\n" +
+	"\n" +
+	"error_rate\x18\x03 \x01(\x01R\terrorRate\x122\n" +
+	"\x15circuit_breaker_state\x18\x04 \x01(\tR\x13circuitBreakerState\x12(\n" +
+	"\x10token_budget_pct\x18\x05 \x01(\x01R\x0etokenBudgetPct\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x06 \x01(\tR\tlastError\x12=\n" +
+	"\flast_success\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\vlastSuccess2\xef\x01\n" +
+	"\fAgentService\x124\n" +
+	"\x05Query\x12\x13.agent.QueryRequest\x1a\x14.agent.QueryResponse0\x01\x12/\n" +
+	"\x04List\x12\x12.agent.ListRequest\x1a\x13.agent.ListResponse\x122\n" +
+	"\x05Batch\x12\x13.agent.BatchRequest\x1a\x14.agent.BatchResponse\x12D\n" +
+	"\vHealthCheck\x12\x19.agent.HealthCheckRequest\x1a\x1a.agent.HealthCheckResponseB\x14Z\x12agent_engine/protob\x06proto3"
+
+var (
+	file_proto_agent_proto_rawDescOnce sync.Once
+	file_proto_agent_proto_rawDescData []byte
+)
+
+func file_proto_agent_proto_rawDescGZIP() []byte {
+	file_proto_agent_proto_rawDescOnce.Do(func() {
+		file_proto_agent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_agent_proto_rawDesc), len(file_proto_agent_proto_rawDesc)))
+	})
+	return file_proto_agent_proto_rawDescData
+}
+
+var file_proto_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_proto_agent_proto_goTypes = []any{
+	(HealthCheckResponse_ServingStatus)(0), // 0: agent.HealthCheckResponse.ServingStatus
+	(*QueryRequest)(nil),                   // 1: agent.QueryRequest
+	(*QueryResponse)(nil),                  // 2: agent.QueryResponse
+	(*ToolCallRecord)(nil),                 // 3: agent.ToolCallRecord
+	(*ResponseScore)(nil),                  // 4: agent.ResponseScore
+	(*ListRequest)(nil),                    // 5: agent.ListRequest
+	(*ProviderInfo)(nil),                   // 6: agent.ProviderInfo
+	(*ListResponse)(nil),                   // 7: agent.ListResponse
+	(*BatchRequest)(nil),                   // 8: agent.BatchRequest
+	(*BatchItem)(nil),                      // 9: agent.BatchItem
+	(*BatchResponse)(nil),                  // 10: agent.BatchResponse
+	(*HealthCheckRequest)(nil),             // 11: agent.HealthCheckRequest
+	(*HealthCheckResponse)(nil),            // 12: agent.HealthCheckResponse
+	(*ProviderHealth)(nil),                 // 13: agent.ProviderHealth
+	nil,                                    // 14: agent.QueryRequest.TemplateVarsEntry
+	nil,                                    // 15: agent.ProviderInfo.ModelMetadataEntry
+	nil,                                    // 16: agent.HealthCheckResponse.ProvidersEntry
+	(*durationpb.Duration)(nil),            // 17: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),          // 18: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),                // 19: google.protobuf.Struct
+}
+var file_proto_agent_proto_depIdxs = []int32{
+	14, // 0: agent.QueryRequest.template_vars:type_name -> agent.QueryRequest.TemplateVarsEntry
+	3,  // 1: agent.QueryResponse.tool_calls:type_name -> agent.ToolCallRecord
+	4,  // 2: agent.QueryResponse.score:type_name -> agent.ResponseScore
+	15, // 3: agent.ProviderInfo.model_metadata:type_name -> agent.ProviderInfo.ModelMetadataEntry
+	6,  // 4: agent.ListResponse.providers:type_name -> agent.ProviderInfo
+	1,  // 5: agent.BatchRequest.requests:type_name -> agent.QueryRequest
+	2,  // 6: agent.BatchItem.result:type_name -> agent.QueryResponse
+	9,  // 7: agent.BatchResponse.items:type_name -> agent.BatchItem
+	0,  // 8: agent.HealthCheckResponse.status:type_name -> agent.HealthCheckResponse.ServingStatus
+	16, // 9: agent.HealthCheckResponse.providers:type_name -> agent.HealthCheckResponse.ProvidersEntry
+	17, // 10: agent.ProviderHealth.latency:type_name -> google.protobuf.Duration
+	18, // 11: agent.ProviderHealth.last_success:type_name -> google.protobuf.Timestamp
+	19, // 12: agent.ProviderInfo.ModelMetadataEntry.value:type_name -> google.protobuf.Struct
+	13, // 13: agent.HealthCheckResponse.ProvidersEntry.value:type_name -> agent.ProviderHealth
+	1,  // 14: agent.AgentService.Query:input_type -> agent.QueryRequest
+	5,  // 15: agent.AgentService.List:input_type -> agent.ListRequest
+	8,  // 16: agent.AgentService.Batch:input_type -> agent.BatchRequest
+	11, // 17: agent.AgentService.HealthCheck:input_type -> agent.HealthCheckRequest
+	2,  // 18: agent.AgentService.Query:output_type -> agent.QueryResponse
+	7,  // 19: agent.AgentService.List:output_type -> agent.ListResponse
+	10, // 20: agent.AgentService.Batch:output_type -> agent.BatchResponse
+	12, // 21: agent.AgentService.HealthCheck:output_type -> agent.HealthCheckResponse
+	18, // [18:22] is the sub-list for method output_type
+	14, // [14:18] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_proto_agent_proto_init() }
+func file_proto_agent_proto_init() {
+	if File_proto_agent_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_agent_proto_rawDesc), len(file_proto_agent_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_agent_proto_goTypes,
+		DependencyIndexes: file_proto_agent_proto_depIdxs,
+		EnumInfos:         file_proto_agent_proto_enumTypes,
+		MessageInfos:      file_proto_agent_proto_msgTypes,
+	}.Build()
+	File_proto_agent_proto = out.File
+	file_proto_agent_proto_goTypes = nil
+	file_proto_agent_proto_depIdxs = nil
+}